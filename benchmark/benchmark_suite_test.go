@@ -0,0 +1,151 @@
+package benchmark_test
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"testing"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/garden"
+	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/world"
+	"code.cloudfoundry.org/lager"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gexec"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+)
+
+var (
+	// componentMaker is suiteComponentMaker switched to the database driver
+	// of the running spec; see world.DescribeForEachDBDriver.
+	componentMaker, suiteComponentMaker world.ComponentMaker
+
+	plumbing, bbsProcess, gardenProcess ifrit.Process
+	gardenClient                        garden.Client
+	bbsClient                           bbs.InternalClient
+	lgr                                 lager.Logger
+	suiteTempDir                        string
+
+	numCells   int
+	numLRPs    int
+	numTasks   int
+	reportPath string
+)
+
+var _ = SynchronizedBeforeSuite(func() []byte {
+	payload, err := json.Marshal(world.BuiltArtifacts{
+		Executables: CompileTestedExecutables(),
+	})
+	Expect(err).NotTo(HaveOccurred())
+
+	return payload
+}, func(encodedBuiltArtifacts []byte) {
+	var builtArtifacts world.BuiltArtifacts
+
+	err := json.Unmarshal(encodedBuiltArtifacts, &builtArtifacts)
+	Expect(err).NotTo(HaveOccurred())
+
+	suiteTempDir = world.TempDir("before-suite")
+
+	suiteComponentMaker = world.MakeNodeComponentMaker(builtArtifacts, suiteTempDir)
+	suiteComponentMaker.Setup()
+	componentMaker = suiteComponentMaker
+
+	numCells = intFromEnv("BENCHMARK_CELLS", 3)
+	numLRPs = intFromEnv("BENCHMARK_LRPS", 300)
+	numTasks = intFromEnv("BENCHMARK_TASKS", 300)
+	reportPath = os.Getenv("BENCHMARK_REPORT_PATH")
+})
+
+var _ = AfterSuite(func() {
+	if suiteComponentMaker != nil {
+		suiteComponentMaker.Teardown()
+	}
+
+	deleteSuiteTempDir := func() error { return os.RemoveAll(suiteTempDir) }
+	Eventually(deleteSuiteTempDir).Should(Succeed())
+})
+
+var _ = BeforeEach(func() {
+	componentMaker = world.SpecComponentMaker(suiteComponentMaker)
+
+	plumbing = ginkgomon.Invoke(world.Plumbing(componentMaker))
+	gardenProcess = ginkgomon.Invoke(componentMaker.Garden())
+	bbsProcess = ginkgomon.Invoke(componentMaker.BBS())
+
+	helpers.ConsulWaitUntilReady(componentMaker.Addresses())
+	lgr = lager.NewLogger("benchmark")
+	lgr.RegisterSink(lager.NewWriterSink(GinkgoWriter, lager.INFO))
+
+	gardenClient = componentMaker.GardenClient()
+	bbsClient = componentMaker.BBSClient()
+})
+
+var _ = AfterEach(func() {
+	destroyContainerErrors := helpers.CleanupGarden(gardenClient)
+
+	helpers.StopProcesses(bbsProcess)
+	helpers.StopProcesses(gardenProcess)
+	helpers.StopProcesses(plumbing)
+
+	Expect(destroyContainerErrors).To(
+		BeEmpty(),
+		"%d containers failed to be destroyed!",
+		len(destroyContainerErrors),
+	)
+})
+
+func TestBenchmark(t *testing.T) {
+	if os.Getenv("INIGO_RUN_BENCHMARKS") == "" {
+		t.Skip("set INIGO_RUN_BENCHMARKS to run the scale benchmarks")
+	}
+
+	helpers.RegisterDefaultTimeouts()
+
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Benchmark Suite")
+}
+
+func intFromEnv(name string, defaultValue int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+
+	n, err := strconv.Atoi(value)
+	Expect(err).NotTo(HaveOccurred(), "%s must be an integer", name)
+	return n
+}
+
+// CompileTestedExecutables builds the components without -race, which
+// would otherwise dominate the timings being measured.
+func CompileTestedExecutables() world.BuiltExecutables {
+	var err error
+
+	builtExecutables := world.BuiltExecutables{}
+
+	cwd, err := os.Getwd()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(os.Chdir(os.Getenv("GARDEN_GOPATH"))).To(Succeed())
+	builtExecutables["garden"], err = gexec.Build("./cmd/gdn", "-a", "-tags", "daemon")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(os.Chdir(cwd)).To(Succeed())
+
+	builtExecutables["auctioneer"], err = gexec.Build("code.cloudfoundry.org/auctioneer/cmd/auctioneer")
+	Expect(err).NotTo(HaveOccurred())
+
+	builtExecutables["rep"], err = gexec.Build("code.cloudfoundry.org/rep/cmd/rep")
+	Expect(err).NotTo(HaveOccurred())
+
+	builtExecutables["bbs"], err = gexec.Build("code.cloudfoundry.org/bbs/cmd/bbs")
+	Expect(err).NotTo(HaveOccurred())
+
+	builtExecutables["locket"], err = gexec.Build("code.cloudfoundry.org/locket/cmd/locket")
+	Expect(err).NotTo(HaveOccurred())
+
+	return builtExecutables
+}
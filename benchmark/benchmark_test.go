@@ -0,0 +1,223 @@
+package benchmark_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	bbsconfig "code.cloudfoundry.org/bbs/cmd/bbs/config"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/durationjson"
+	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/world"
+	repconfig "code.cloudfoundry.org/rep/cmd/rep/config"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// desireConcurrency bounds how many desire requests are in flight at once,
+// roughly what a busy cloud controller sync produces.
+const desireConcurrency = 20
+
+// workloadMB is the memory and disk every LRP and task asks for. It is kept
+// small so that the cells' capacity isn't what is being measured.
+const workloadMB = 1
+
+var _ = world.DescribeForEachDBDriver("Scale", func() {
+	var (
+		cells      []ifrit.Process
+		auctioneer ifrit.Process
+
+		report *helpers.BenchmarkReport
+
+		instanceEvents *helpers.EventRecorder
+		taskEvents     *helpers.EventRecorder
+
+		bbsLatency *helpers.DurationSamples
+	)
+
+	// inParallel calls f with every index in [0, n) from desireConcurrency
+	// goroutines.
+	inParallel := func(n int, f func(i int)) {
+		work := make(chan int)
+		wg := sync.WaitGroup{}
+		for w := 0; w < desireConcurrency; w++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				for i := range work {
+					f(i)
+				}
+			}()
+		}
+		for i := 0; i < n; i++ {
+			work <- i
+		}
+		close(work)
+		wg.Wait()
+	}
+
+	runningCount := func(processGuids map[string]time.Time) func() int {
+		return func() int {
+			var lrps []*models.ActualLRP
+			bbsLatency.Time(func() {
+				var err error
+				lrps, err = bbsClient.ActualLRPs(lgr, models.ActualLRPFilter{})
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			running := 0
+			for _, lrp := range lrps {
+				if _, ok := processGuids[lrp.ProcessGuid]; ok && lrp.State == models.ActualLRPStateRunning {
+					running++
+				}
+			}
+			return running
+		}
+	}
+
+	BeforeEach(func() {
+		bbsLatency = &helpers.DurationSamples{}
+
+		By("restarting the bbs with a short convergence interval")
+		helpers.StopProcesses(bbsProcess)
+		bbsProcess = ginkgomon.Invoke(componentMaker.BBS(func(cfg *bbsconfig.BBSConfig) {
+			cfg.ConvergeRepeatInterval = durationjson.Duration(time.Second)
+		}))
+
+		By(fmt.Sprintf("starting %d cells", numCells))
+		cells = nil
+		for i := 0; i < numCells; i++ {
+			cells = append(cells, ginkgomon.Invoke(componentMaker.RepN(i, func(cfg *repconfig.RepConfig) {
+				// this lets any one cell hold the whole workload after the
+				// others are gone
+				capacity := strconv.Itoa((numLRPs + numTasks) * workloadMB)
+				cfg.MemoryMB = capacity
+				cfg.DiskMB = capacity
+			})))
+		}
+		auctioneer = ginkgomon.Invoke(componentMaker.Auctioneer())
+
+		helpers.UpsertInigoDomain(lgr, bbsClient)
+
+		instanceEvents = helpers.NewEventRecorder(lgr, bbsClient, helpers.InstanceEventStream)
+		taskEvents = helpers.NewEventRecorder(lgr, bbsClient, helpers.TaskEventStream)
+
+		report = helpers.NewBenchmarkReport(CurrentGinkgoTestDescription().TestText, numCells, numLRPs, numTasks)
+		report.DBDriver = world.SpecDBDriver()
+	})
+
+	AfterEach(func() {
+		instanceEvents.Stop()
+		taskEvents.Stop()
+
+		report.Latencies["bbs-api"] = bbsLatency.Stats()
+
+		fmt.Fprintf(GinkgoWriter, "benchmark report: %+v\n", report)
+		if reportPath != "" {
+			// each driver's run gets a report of its own
+			ext := filepath.Ext(reportPath)
+			report.WriteJSON(strings.TrimSuffix(reportPath, ext) + "-" + report.DBDriver + ext)
+		}
+
+		helpers.StopProcesses(auctioneer)
+		helpers.StopProcesses(cells...)
+	})
+
+	It("places, runs and converges lightweight LRPs and tasks", func() {
+		desiredAt := map[string]time.Time{}
+		desiredAtLock := sync.Mutex{}
+
+		By(fmt.Sprintf("desiring %d LRPs", numLRPs))
+		desireStart := time.Now()
+		inParallel(numLRPs, func(i int) {
+			processGuid := helpers.GenerateGuid()
+			lrp := helpers.NewLRP(componentMaker.Addresses(), processGuid,
+				// nothing to download, so no file server is needed and the
+				// timings are the scheduler's alone
+				helpers.LRPSetup(nil),
+				helpers.LRPAction(&models.RunAction{
+					User: "vcap",
+					Path: "sh",
+					Args: []string{"-c", "while true; do sleep 1; done"},
+				}),
+				helpers.LRPMonitor(&models.RunAction{
+					User: "vcap",
+					Path: "sh",
+					Args: []string{"-c", "echo all good"},
+				}),
+				helpers.LRPMemoryMB(workloadMB),
+				helpers.LRPDiskMB(workloadMB),
+			)
+
+			desiredAtLock.Lock()
+			desiredAt[processGuid] = time.Now()
+			desiredAtLock.Unlock()
+
+			bbsLatency.Time(func() {
+				Expect(bbsClient.DesireLRP(lgr, lrp)).To(Succeed())
+			})
+		})
+
+		Eventually(runningCount(desiredAt), 10*time.Minute, time.Second).Should(Equal(numLRPs))
+		allRunning := time.Since(desireStart)
+
+		recorded := instanceEvents.Recorded()
+		claimed := helpers.InstanceStateLatencies(recorded, desiredAt, models.ActualLRPStateClaimed)
+		running := helpers.InstanceStateLatencies(recorded, desiredAt, models.ActualLRPStateRunning)
+		report.Latencies["lrp-desire-to-claimed"] = helpers.NewDurationStats(claimed)
+		report.Latencies["lrp-desire-to-running"] = helpers.NewDurationStats(running)
+		report.Durations["lrp-all-running"] = allRunning
+
+		claimedStats := report.Latencies["lrp-desire-to-claimed"]
+		if claimedStats.Max > 0 {
+			report.Throughput["auction-lrps"] = float64(claimedStats.Count) / claimedStats.Max.Seconds()
+		}
+
+		By(fmt.Sprintf("desiring %d tasks", numTasks))
+		taskDesiredAt := map[string]time.Time{}
+		taskStart := time.Now()
+		inParallel(numTasks, func(i int) {
+			taskGuid := helpers.GenerateGuid()
+			task := helpers.NewTask(taskGuid, &models.RunAction{
+				User: "vcap",
+				Path: "sh",
+				Args: []string{"-c", "exit 0"},
+			}, helpers.TaskMemoryMB(workloadMB), helpers.TaskDiskMB(workloadMB))
+
+			desiredAtLock.Lock()
+			taskDesiredAt[taskGuid] = time.Now()
+			desiredAtLock.Unlock()
+
+			bbsLatency.Time(func() {
+				Expect(bbsClient.DesireTask(lgr, task.TaskGuid, task.Domain, task.TaskDefinition)).To(Succeed())
+			})
+		})
+
+		Eventually(func() int {
+			return len(helpers.TaskStateLatencies(taskEvents.Recorded(), taskDesiredAt, models.Task_Completed))
+		}, 10*time.Minute, time.Second).Should(Equal(numTasks))
+
+		completed := helpers.TaskStateLatencies(taskEvents.Recorded(), taskDesiredAt, models.Task_Completed)
+		report.Latencies["task-desire-to-completed"] = helpers.NewDurationStats(completed)
+		report.Durations["task-all-completed"] = time.Since(taskStart)
+		report.Throughput["tasks-completed"] = float64(numTasks) / report.Durations["task-all-completed"].Seconds()
+
+		if numCells > 1 {
+			By("killing a cell and waiting for its LRPs to converge elsewhere")
+			ginkgomon.Kill(cells[0])
+			lost := time.Now()
+
+			Eventually(runningCount(desiredAt), 10*time.Minute, time.Second).ShouldNot(Equal(numLRPs))
+			Eventually(runningCount(desiredAt), 10*time.Minute, time.Second).Should(Equal(numLRPs))
+			report.Durations["convergence"] = time.Since(lost)
+		}
+	})
+})
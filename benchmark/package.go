@@ -0,0 +1 @@
+package benchmark // import "code.cloudfoundry.org/inigo/benchmark"
@@ -0,0 +1,134 @@
+package cell_test
+
+import (
+	"os"
+	"runtime"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/world"
+	repconfig "code.cloudfoundry.org/rep/cmd/rep/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+	"github.com/tedsuo/ifrit/grouper"
+)
+
+var _ = world.DescribeForEachDBDriver("Action trees", func() {
+	var (
+		cellProcess ifrit.Process
+		guid        string
+		tracer      *helpers.ActionTracer
+		tree        *helpers.TracedAction
+	)
+
+	BeforeEach(func() {
+		if runtime.GOOS == "windows" {
+			Skip(" not yet working on windows")
+		}
+
+		cellProcess = ginkgomon.Invoke(grouper.NewParallel(os.Interrupt, grouper.Members{
+			{"rep", componentMaker.Rep(func(config *repconfig.RepConfig) { config.MemoryMB = "1024" })},
+			{"auctioneer", componentMaker.Auctioneer()},
+		}))
+
+		guid = helpers.GenerateGuid()
+		tracer = helpers.NewActionTracer(guid, announcementServer.AnnounceURL)
+	})
+
+	JustBeforeEach(func() {
+		task := helpers.TaskCreateRequest(guid, tree.Action())
+		err := bbsClient.DesireTask(lgr, task.TaskGuid, task.Domain, task.TaskDefinition)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		helpers.StopProcesses(cellProcess)
+	})
+
+	Context("with a serial action", func() {
+		BeforeEach(func() {
+			tree = tracer.Serial("root",
+				tracer.Run("first", "sleep 1"),
+				tracer.Run("second", "true"),
+			)
+		})
+
+		It("runs the children one after the other", func() {
+			Eventually(announcementServer.Names).Should(tracer.HaveCompleted("root"))
+			Expect(announcementServer.Names()).To(tracer.HaveExecutedSerially("first", "second"))
+		})
+	})
+
+	Context("with a parallel action", func() {
+		BeforeEach(func() {
+			tree = tracer.Parallel("root",
+				tracer.Run("left", "sleep 2"),
+				tracer.Run("right", "sleep 2"),
+			)
+		})
+
+		It("runs the children at the same time", func() {
+			Eventually(announcementServer.Names).Should(tracer.HaveCompleted("root"))
+			Expect(announcementServer.Names()).To(tracer.HaveExecutedInParallel("left", "right"))
+		})
+	})
+
+	Context("with a codependent action where one child exits", func() {
+		BeforeEach(func() {
+			tree = tracer.Codependent("root",
+				tracer.Run("short-lived", "sleep 1"),
+				tracer.Run("long-lived", "sleep 1000"),
+			)
+		})
+
+		It("cancels the remaining children", func() {
+			Eventually(announcementServer.Names).Should(tracer.HaveBeenCancelled("long-lived"))
+			Expect(announcementServer.Names()).To(tracer.HaveCompleted("short-lived"))
+			Expect(announcementServer.Names()).NotTo(tracer.HaveCompleted("root"))
+		})
+	})
+
+	Context("when the task is cancelled while a branch runs", func() {
+		BeforeEach(func() {
+			tree = tracer.Serial("root",
+				tracer.Parallel("branch",
+					tracer.Run("left", "sleep 1000"),
+					tracer.Run("right", "sleep 1000"),
+				),
+				tracer.Run("after", "true"),
+			)
+		})
+
+		It("records the cancel for the branch and everything above it", func() {
+			Eventually(announcementServer.Names).Should(tracer.HaveExecutedInParallel("left", "right"))
+			Expect(bbsClient.CancelTask(lgr, guid)).To(Succeed())
+
+			Eventually(announcementServer.Names).Should(tracer.HaveBeenCancelled("root", "branch", "left", "right"))
+			Expect(announcementServer.Names()).To(tracer.HaveNotStarted("after"))
+		})
+	})
+
+	Context("with a timeout action that is exceeded", func() {
+		BeforeEach(func() {
+			tree = tracer.Serial("root",
+				tracer.Try("try",
+					tracer.Timeout("timeout", 2*time.Second, tracer.Run("slow", "sleep 1000")),
+				),
+				tracer.Run("after", "true"),
+			)
+		})
+
+		It("cancels the timed out action and carries on after the try", func() {
+			Eventually(announcementServer.Names).Should(tracer.HaveCompleted("root"))
+			Expect(announcementServer.Names()).To(tracer.HaveBeenCancelled("slow"))
+			Expect(announcementServer.Names()).To(tracer.HaveExecutedSerially("try", "after"))
+
+			var task models.Task
+			Eventually(helpers.TaskStatePoller(lgr, bbsClient, guid, &task)).Should(Equal(models.Task_Completed))
+			Expect(task.Failed).To(BeFalse())
+		})
+	})
+})
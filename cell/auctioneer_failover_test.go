@@ -0,0 +1,208 @@
+package cell_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	archive_helper "code.cloudfoundry.org/archiver/extractor/test_helper"
+	bbsconfig "code.cloudfoundry.org/bbs/cmd/bbs/config"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/durationjson"
+	"code.cloudfoundry.org/inigo/fixtures"
+	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/world"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+	"github.com/tedsuo/ifrit/grouper"
+)
+
+var _ = world.DescribeForEachDBDriver("Auctioneer failover", func() {
+	var (
+		auctioneers  *helpers.AuctioneerClusterProcess
+		ifritRuntime ifrit.Process
+
+		taskGuids []string
+	)
+
+	desireTasks := func(n int) {
+		for i := 0; i < n; i++ {
+			guid := helpers.GenerateGuid()
+			task := helpers.NewTask(guid, &models.RunAction{
+				User: "vcap",
+				Path: "sh",
+				Args: []string{"-c", "curl " + announcementServer.AnnounceURL(guid)},
+			})
+			Expect(bbsClient.DesireTask(lgr, task.TaskGuid, task.Domain, task.TaskDefinition)).To(Succeed())
+			taskGuids = append(taskGuids, guid)
+		}
+	}
+
+	completedTasks := func() []string {
+		completed := []string{}
+		for _, guid := range taskGuids {
+			task, err := bbsClient.TaskByGuid(lgr, guid)
+			Expect(err).NotTo(HaveOccurred())
+			if task.State == models.Task_Completed {
+				Expect(task.Failed).To(BeFalse(), "task %s failed: %s", guid, task.FailureReason)
+				completed = append(completed, guid)
+			}
+		}
+		return completed
+	}
+
+	timesRun := func() map[string]int {
+		counts := map[string]int{}
+		for _, name := range announcementServer.Names() {
+			counts[name]++
+		}
+		return counts
+	}
+
+	expectEveryTaskRunOnce := func() {
+		Eventually(completedTasks, 2*time.Minute).Should(ConsistOf(taskGuids))
+
+		once := map[string]int{}
+		for _, guid := range taskGuids {
+			once[guid] = 1
+		}
+		Consistently(timesRun).Should(Equal(once))
+	}
+
+	expectLRPPlacedOnce := func(instances int) {
+		processGuid := helpers.GenerateGuid()
+		lrp := helpers.NewLRP(componentMaker.Addresses(), processGuid, helpers.LRPInstances(instances))
+		Expect(bbsClient.DesireLRP(lgr, lrp)).To(Succeed())
+
+		running := func() int {
+			lrps, err := bbsClient.ActualLRPs(lgr, models.ActualLRPFilter{ProcessGuid: processGuid})
+			Expect(err).NotTo(HaveOccurred())
+			count := 0
+			for _, lrp := range lrps {
+				if lrp.State == models.ActualLRPStateRunning {
+					count++
+				}
+			}
+			return count
+		}
+		Eventually(running, 2*time.Minute).Should(Equal(instances))
+
+		containers, err := gardenClient.Containers(nil)
+		Expect(err).NotTo(HaveOccurred())
+		lrps, err := bbsClient.ActualLRPs(lgr, models.ActualLRPFilter{ProcessGuid: processGuid})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lrps).To(HaveLen(instances))
+
+		placed := map[string]bool{}
+		for _, lrp := range lrps {
+			placed[lrp.InstanceGuid] = true
+		}
+		found := 0
+		for _, container := range containers {
+			if placed[container.Handle()] {
+				found++
+			}
+		}
+		Expect(found).To(Equal(instances))
+	}
+
+	BeforeEach(func() {
+		if runtime.GOOS == "windows" {
+			Skip(" not yet working on windows")
+		}
+		taskGuids = []string{}
+
+		By("restarting the bbs so that it retries lost auctions quickly")
+		ginkgomon.Interrupt(bbsProcess)
+		bbsProcess = ginkgomon.Invoke(componentMaker.BBS(
+			overrideConvergenceRepeatInterval,
+			func(cfg *bbsconfig.BBSConfig) {
+				cfg.KickTaskDuration = durationjson.Duration(5 * time.Second)
+			},
+		))
+
+		fileServer, fileServerStaticDir := componentMaker.FileServer()
+		ifritRuntime = ginkgomon.Invoke(grouper.NewParallel(os.Kill, grouper.Members{
+			{"file-server", fileServer},
+			{"rep-0", componentMaker.RepN(0)},
+			{"rep-1", componentMaker.RepN(1)},
+		}))
+
+		archive_helper.CreateZipArchive(
+			filepath.Join(fileServerStaticDir, "lrp.zip"),
+			fixtures.GoServerApp(),
+		)
+
+		auctioneers = helpers.StartAuctioneerCluster(lgr, world.MakeAuctioneerCluster(componentMaker, 2), componentMaker.LocketClient(lgr))
+	})
+
+	AfterEach(func() {
+		if auctioneers != nil {
+			auctioneers.Stop()
+		}
+		helpers.StopProcesses(ifritRuntime)
+	})
+
+	It("has exactly one active auctioneer, which runs every auction once", func() {
+		active := auctioneers.Active()
+		Consistently(auctioneers.Active, 3*time.Second).Should(Equal(active))
+
+		desireTasks(5)
+		expectEveryTaskRunOnce()
+	})
+
+	Context("when the active auctioneer is partitioned from locket", func() {
+		var active int
+
+		BeforeEach(func() {
+			desireTasks(5)
+
+			active = auctioneers.Active()
+			auctioneers.Partition(active)
+
+			desireTasks(5)
+		})
+
+		It("hands over to the standby without losing or repeating any auction", func() {
+			Eventually(auctioneers.Active, helpers.AuctioneerFailoverTimeout).Should(SatisfyAll(
+				Not(Equal(-1)),
+				Not(Equal(active)),
+			))
+			Eventually(auctioneers.Exited(active), helpers.AuctioneerFailoverTimeout).Should(Receive())
+
+			desireTasks(5)
+			expectEveryTaskRunOnce()
+			expectLRPPlacedOnce(4)
+		})
+	})
+
+	Context("when the active auctioneer is paused past its lock TTL", func() {
+		var active int
+
+		BeforeEach(func() {
+			desireTasks(5)
+
+			active = auctioneers.Active()
+			auctioneers.Pause(active)
+
+			desireTasks(5)
+		})
+
+		It("hands over to the standby, and the old one gives up once resumed", func() {
+			Eventually(auctioneers.Active, helpers.AuctioneerFailoverTimeout).Should(SatisfyAll(
+				Not(Equal(-1)),
+				Not(Equal(active)),
+			))
+
+			auctioneers.Resume(active)
+			Eventually(auctioneers.Exited(active), helpers.AuctioneerFailoverTimeout).Should(Receive())
+
+			desireTasks(5)
+			expectEveryTaskRunOnce()
+			expectLRPPlacedOnce(4)
+		})
+	})
+})
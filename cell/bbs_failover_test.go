@@ -0,0 +1,146 @@
+package cell_test
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	archive_helper "code.cloudfoundry.org/archiver/extractor/test_helper"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/inigo/fixtures"
+	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/world"
+	"code.cloudfoundry.org/routing-info/cfroutes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+	"github.com/tedsuo/ifrit/grouper"
+)
+
+var _ = world.DescribeForEachDBDriver("BBS failover", func() {
+	var (
+		cluster      *helpers.BBSClusterProcess
+		ifritRuntime ifrit.Process
+	)
+
+	routableLRP := func(host string) *models.DesiredLRP {
+		routes := cfroutes.CFRoutes{{Hostnames: []string{host}, Port: 8080}}.RoutingInfo()
+		return helpers.NewLRP(componentMaker.Addresses(), helpers.GenerateGuid(), helpers.LRPRoutes(&routes))
+	}
+
+	routeResponds := func(host string) func() (int, error) {
+		return helpers.ResponseCodeFromHostPoller(componentMaker.Addresses().Router, host)
+	}
+
+	BeforeEach(func() {
+		if runtime.GOOS == "windows" {
+			Skip(" not yet working on windows")
+		}
+
+		By("replacing the bbs with a cluster of two")
+		ginkgomon.Interrupt(bbsProcess)
+		bbsProcess = nil
+		cluster = helpers.StartBBSCluster(lgr, world.MakeBBSCluster(componentMaker, 2), componentMaker.LocketClient(lgr))
+
+		fileServer, fileServerStaticDir := componentMaker.FileServer()
+		ifritRuntime = ginkgomon.Invoke(grouper.NewParallel(os.Kill, grouper.Members{
+			{"router", componentMaker.Router()},
+			{"file-server", fileServer},
+			{"rep", componentMaker.Rep()},
+			{"auctioneer", componentMaker.Auctioneer()},
+			{"route-emitter", componentMaker.RouteEmitter()},
+		}))
+
+		archive_helper.CreateZipArchive(
+			filepath.Join(fileServerStaticDir, "lrp.zip"),
+			fixtures.GoServerApp(),
+		)
+	})
+
+	AfterEach(func() {
+		helpers.StopProcesses(ifritRuntime)
+		if cluster != nil {
+			cluster.Stop()
+		}
+	})
+
+	It("serves from exactly the instance holding the lock", func() {
+		leader := cluster.Leader()
+		Consistently(cluster.Leader, 3*time.Second).Should(Equal(leader))
+
+		By("finding the leader through the address list")
+		Expect(cluster.ServingInstance()).To(Equal(leader))
+
+		By("refusing requests on the standby")
+		for i, instance := range cluster.Cluster.Instances {
+			if i == leader {
+				continue
+			}
+			Expect(cluster.Cluster.Client(i).Ping(lgr)).To(BeFalse(), "standby %s answered a ping", instance.UUID)
+			_, err := net.DialTimeout("tcp", instance.ListenAddress, time.Second)
+			Expect(err).To(HaveOccurred(), "standby %s accepted a connection", instance.UUID)
+		}
+
+		By("serving components through the world's BBS address")
+		Expect(bbsClient.Ping(lgr)).To(BeTrue())
+	})
+
+	Context("when the leader dies", func() {
+		var (
+			before     *models.DesiredLRP
+			oldLeader  int
+			deadline   time.Time
+			afterHost  = "lrp-route-after-failover"
+			beforeHost = "lrp-route-before-failover"
+		)
+
+		BeforeEach(func() {
+			before = routableLRP(beforeHost)
+			Expect(bbsClient.DesireLRP(lgr, before)).To(Succeed())
+			Eventually(routeResponds(beforeHost)).Should(Equal(http.StatusOK))
+
+			deadline = time.Now().Add(helpers.BBSFailoverTimeout)
+			oldLeader = cluster.KillLeader()
+		})
+
+		It("hands the lock to the standby, which starts serving", func() {
+			Eventually(cluster.Leader, helpers.BBSFailoverTimeout).Should(SatisfyAll(
+				Not(Equal(-1)),
+				Not(Equal(oldLeader)),
+			))
+			newLeader := cluster.Leader()
+
+			By("failing clients with the address list over to it")
+			Eventually(cluster.ServingInstance, helpers.BBSFailoverTimeout).Should(Equal(newLeader))
+			client, _ := cluster.ServingClient()
+			Expect(client).NotTo(BeNil())
+
+			lrps, err := client.ActualLRPs(lgr, models.ActualLRPFilter{ProcessGuid: before.ProcessGuid})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lrps).To(HaveLen(1))
+
+			By("serving components through the world's BBS address")
+			Eventually(func() bool { return bbsClient.Ping(lgr) }, helpers.BBSFailoverTimeout).Should(BeTrue())
+		})
+
+		It("has the rep, auctioneer and route-emitter working against the new leader within bounded time", func() {
+			Eventually(func() bool { return bbsClient.Ping(lgr) }, time.Until(deadline)).Should(BeTrue())
+
+			after := routableLRP(afterHost)
+			Expect(bbsClient.DesireLRP(lgr, after)).To(Succeed())
+
+			By("auctioning and starting new work")
+			Eventually(helpers.LRPStatePoller(lgr, bbsClient, after.ProcessGuid, nil), time.Until(deadline)).Should(Equal(models.ActualLRPStateRunning))
+
+			By("routing to it")
+			Eventually(routeResponds(afterHost), time.Until(deadline)).Should(Equal(http.StatusOK))
+
+			By("keeping the routes registered before the failover")
+			Consistently(routeResponds(beforeHost)).Should(Equal(http.StatusOK))
+		})
+	})
+})
@@ -0,0 +1,214 @@
+package cell_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+
+	archive_helper "code.cloudfoundry.org/archiver/extractor/test_helper"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/inigo/fixtures"
+	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/world"
+	"code.cloudfoundry.org/rep"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+	"github.com/tedsuo/ifrit/grouper"
+)
+
+var _ = world.DescribeForEachDBDriver("Cell capacity", func() {
+	var (
+		processGuid  string
+		ifritRuntime ifrit.Process
+		cells        ifrit.Process
+
+		distribution func() helpers.InstanceDistribution
+	)
+
+	cellStates := func() map[string]rep.CellState {
+		return helpers.CellStates(lgr, bbsClient, componentMaker.RepClientFactory())
+	}
+
+	cellWithMemory := func(memoryMB int32) string {
+		for cellID, state := range cellStates() {
+			if state.TotalResources.MemoryMB == memoryMB {
+				return cellID
+			}
+		}
+		Fail("no cell advertises the expected memory")
+		return ""
+	}
+
+	BeforeEach(func() {
+		cells = nil
+		if runtime.GOOS == "windows" {
+			Skip(" not yet working on windows")
+		}
+
+		processGuid = helpers.GenerateGuid()
+		distribution = helpers.InstanceDistributionPoller(lgr, bbsClient, processGuid)
+
+		fileServer, fileServerStaticDir := componentMaker.FileServer()
+		ifritRuntime = ginkgomon.Invoke(grouper.NewParallel(os.Kill, grouper.Members{
+			{"file-server", fileServer},
+			{"auctioneer", componentMaker.Auctioneer()},
+		}))
+
+		archive_helper.CreateZipArchive(
+			filepath.Join(fileServerStaticDir, "lrp.zip"),
+			fixtures.GoServerApp(),
+		)
+	})
+
+	AfterEach(func() {
+		helpers.StopProcesses(cells, ifritRuntime)
+	})
+
+	Context("with a small and a large cell", func() {
+		var smallCell, largeCell string
+
+		BeforeEach(func() {
+			cells = ginkgomon.Invoke(grouper.NewParallel(os.Kill, grouper.Members{
+				{"small-rep", componentMaker.RepN(0, world.RepCapacity(256, 1024))},
+				{"large-rep", componentMaker.RepN(1, world.RepCapacity(1024, 4096))},
+			}))
+			Eventually(func() ([]*models.CellPresence, error) { return bbsClient.Cells(lgr) }).Should(HaveLen(2))
+
+			smallCell = cellWithMemory(256)
+			largeCell = cellWithMemory(1024)
+		})
+
+		It("advertises each cell's capacity through the rep's /state and the cell presence", func() {
+			states := cellStates()
+			Expect(states[smallCell].TotalResources.MemoryMB).To(BeEquivalentTo(256))
+			Expect(states[smallCell].TotalResources.DiskMB).To(BeEquivalentTo(1024))
+			Expect(states[largeCell].TotalResources.MemoryMB).To(BeEquivalentTo(1024))
+			Expect(states[largeCell].TotalResources.DiskMB).To(BeEquivalentTo(4096))
+
+			presences, err := bbsClient.Cells(lgr)
+			Expect(err).NotTo(HaveOccurred())
+			capacities := map[string]*models.CellCapacity{}
+			for _, presence := range presences {
+				capacities[presence.CellId] = presence.Capacity
+			}
+			Expect(capacities[smallCell].MemoryMb).To(BeEquivalentTo(256))
+			Expect(capacities[largeCell].MemoryMb).To(BeEquivalentTo(1024))
+		})
+
+		Context("when an LRP only fits on the large cell", func() {
+			var lrp *models.DesiredLRP
+
+			BeforeEach(func() {
+				lrp = helpers.NewLRP(componentMaker.Addresses(), processGuid, helpers.LRPMemoryMB(512), helpers.LRPDiskMB(512))
+			})
+
+			It("places it there, as predicted", func() {
+				predicted, err := helpers.PredictPlacement(cellStates(), helpers.LRPResource(lrp), helpers.DefaultStartingContainerWeight)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(predicted).To(Equal(largeCell))
+
+				Expect(bbsClient.DesireLRP(lgr, lrp)).To(Succeed())
+				Eventually(helpers.LRPStatePoller(lgr, bbsClient, processGuid, nil)).Should(Equal(models.ActualLRPStateRunning))
+				Expect(distribution().ByCell).To(Equal(map[string]int{largeCell: 1}))
+			})
+		})
+
+		Context("when the instances exactly fill both cells", func() {
+			var lrp *models.DesiredLRP
+
+			BeforeEach(func() {
+				lrp = helpers.NewLRP(componentMaker.Addresses(), processGuid,
+					helpers.LRPInstances(5),
+					helpers.LRPMemoryMB(256),
+					helpers.LRPDiskMB(128),
+				)
+			})
+
+			It("packs them into the cells as predicted", func() {
+				resources := []rep.Resource{}
+				for i := 0; i < 5; i++ {
+					resources = append(resources, helpers.LRPResource(lrp))
+				}
+				placements, err := helpers.PredictPlacements(cellStates(), resources, helpers.DefaultStartingContainerWeight)
+				Expect(err).NotTo(HaveOccurred())
+
+				predicted := map[string]int{}
+				for _, cellID := range placements {
+					predicted[cellID]++
+				}
+				Expect(predicted).To(Equal(map[string]int{smallCell: 1, largeCell: 4}))
+
+				Expect(bbsClient.DesireLRP(lgr, lrp)).To(Succeed())
+				Eventually(func() map[string]int { return distribution().ByCell }).Should(Equal(predicted))
+			})
+
+			It("fails to place one more instance for lack of memory", func() {
+				Expect(bbsClient.DesireLRP(lgr, lrp)).To(Succeed())
+				Eventually(func() int { return distribution().Total() }).Should(Equal(5))
+
+				_, err := helpers.PredictPlacement(cellStates(), helpers.LRPResource(lrp), helpers.DefaultStartingContainerWeight)
+				Expect(err).To(MatchError("insufficient resources: memory"))
+
+				update := &models.DesiredLRPUpdate{}
+				update.SetInstances(6)
+				Expect(bbsClient.UpdateDesiredLRP(lgr, processGuid, update)).To(Succeed())
+
+				Eventually(helpers.PlacementErrorPoller(lgr, bbsClient, processGuid, 5)).Should(Equal(err.Error()))
+				Consistently(func() int { return distribution().Total() }).Should(Equal(5))
+			})
+		})
+
+		Context("when a task fits on no cell", func() {
+			It("fails the task with the predicted error", func() {
+				task := helpers.NewTask(helpers.GenerateGuid(), &models.RunAction{User: "vcap", Path: "true"},
+					helpers.TaskMemoryMB(2048),
+					helpers.TaskDiskMB(8192),
+				)
+
+				_, predictedErr := helpers.PredictPlacement(cellStates(), helpers.TaskResource(task), helpers.DefaultStartingContainerWeight)
+				Expect(predictedErr).To(MatchError("insufficient resources: disk, memory"))
+
+				Expect(bbsClient.DesireTask(lgr, task.TaskGuid, task.Domain, task.TaskDefinition)).To(Succeed())
+
+				Eventually(func() string {
+					t, err := bbsClient.TaskByGuid(lgr, task.TaskGuid)
+					Expect(err).NotTo(HaveOccurred())
+					return t.FailureReason
+				}).Should(Equal(predictedErr.Error()))
+			})
+		})
+	})
+
+	Context("when a cell's own garden limits its containers", func() {
+		BeforeEach(func() {
+			garden, useGarden := world.CellGarden(componentMaker, 0, 2)
+			cells = ginkgomon.Invoke(grouper.NewOrdered(os.Kill, grouper.Members{
+				{"cell-garden", garden},
+				{"rep", componentMaker.RepN(0, useGarden)},
+			}))
+			Eventually(func() ([]*models.CellPresence, error) { return bbsClient.Cells(lgr) }).Should(HaveLen(1))
+		})
+
+		It("advertises the garden's limit and places no more than it allows", func() {
+			for _, state := range cellStates() {
+				Expect(state.TotalResources.Containers).To(Equal(2))
+			}
+
+			lrp := helpers.NewLRP(componentMaker.Addresses(), processGuid, helpers.LRPInstances(3))
+			Expect(bbsClient.DesireLRP(lgr, lrp)).To(Succeed())
+
+			Eventually(func() int { return distribution().Total() }).Should(Equal(2))
+			Eventually(func() []string {
+				errs := []string{}
+				for i := 0; i < 3; i++ {
+					if err := helpers.PlacementErrorPoller(lgr, bbsClient, processGuid, i)(); err != "" {
+						errs = append(errs, err)
+					}
+				}
+				return errs
+			}).Should(ConsistOf("insufficient resources: containers"))
+		})
+	})
+})
@@ -2,19 +2,15 @@ package cell_test
 
 import (
 	"encoding/json"
-	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
 	"testing"
 	"time"
 
-	"code.cloudfoundry.org/consuladapter/consulrunner"
 	"code.cloudfoundry.org/durationjson"
 	"code.cloudfoundry.org/lager"
-	"code.cloudfoundry.org/localip"
 	. "github.com/onsi/ginkgo"
-	ginkgoconfig "github.com/onsi/ginkgo/config"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gexec"
 	"github.com/tedsuo/ifrit"
@@ -26,14 +22,15 @@ import (
 	"code.cloudfoundry.org/bbs/serviceclient"
 	"code.cloudfoundry.org/garden"
 	"code.cloudfoundry.org/inigo/helpers"
-	"code.cloudfoundry.org/inigo/helpers/certauthority"
-	"code.cloudfoundry.org/inigo/helpers/portauthority"
+	"code.cloudfoundry.org/inigo/helpers/perfbaseline"
 	"code.cloudfoundry.org/inigo/inigo_announcement_server"
 	"code.cloudfoundry.org/inigo/world"
 )
 
 var (
-	componentMaker world.ComponentMaker
+	// componentMaker is suiteComponentMaker switched to the database driver
+	// of the running spec; see world.DescribeForEachDBDriver.
+	componentMaker, suiteComponentMaker world.ComponentMaker
 
 	plumbing, bbsProcess, gardenProcess ifrit.Process
 	gardenClient                        garden.Client
@@ -41,6 +38,14 @@ var (
 	bbsServiceClient                    serviceclient.ServiceClient
 	lgr                                 lager.Logger
 	suiteTempDir                        string
+
+	// announcementServer records the announcements of the running spec's
+	// containers
+	announcementServer *inigo_announcement_server.Server
+
+	// durations collects timings for comparison against the performance
+	// baseline; see perfbaseline.ConfigFromEnv.
+	durations *perfbaseline.Recorder
 )
 
 func overrideConvergenceRepeatInterval(conf *bbsconfig.BBSConfig) {
@@ -67,48 +72,22 @@ var _ = SynchronizedBeforeSuite(func() []byte {
 	err := json.Unmarshal(encodedBuiltArtifacts, &builtArtifacts)
 	Expect(err).NotTo(HaveOccurred())
 
-	_, dbBaseConnectionString := world.DBInfo()
-
-	localIP, err := localip.LocalIP()
-	Expect(err).NotTo(HaveOccurred())
-
-	addresses := world.ComponentAddresses{
-		Garden:              fmt.Sprintf("127.0.0.1:%d", 10000+ginkgoconfig.GinkgoConfig.ParallelNode),
-		NATS:                fmt.Sprintf("127.0.0.1:%d", 11000+ginkgoconfig.GinkgoConfig.ParallelNode),
-		Consul:              fmt.Sprintf("127.0.0.1:%d", 12750+ginkgoconfig.GinkgoConfig.ParallelNode*consulrunner.PortOffsetLength),
-		Rep:                 fmt.Sprintf("127.0.0.1:%d", 14000+ginkgoconfig.GinkgoConfig.ParallelNode),
-		FileServer:          fmt.Sprintf("%s:%d", localIP, 17000+ginkgoconfig.GinkgoConfig.ParallelNode),
-		Router:              fmt.Sprintf("127.0.0.1:%d", 18000+ginkgoconfig.GinkgoConfig.ParallelNode),
-		BBS:                 fmt.Sprintf("127.0.0.1:%d", 20500+ginkgoconfig.GinkgoConfig.ParallelNode*2),
-		Health:              fmt.Sprintf("127.0.0.1:%d", 20500+ginkgoconfig.GinkgoConfig.ParallelNode*2+1),
-		Auctioneer:          fmt.Sprintf("127.0.0.1:%d", 23000+ginkgoconfig.GinkgoConfig.ParallelNode),
-		SSHProxy:            fmt.Sprintf("127.0.0.1:%d", 23500+ginkgoconfig.GinkgoConfig.ParallelNode),
-		SSHProxyHealthCheck: fmt.Sprintf("127.0.0.1:%d", 24500+ginkgoconfig.GinkgoConfig.ParallelNode),
-		FakeVolmanDriver:    fmt.Sprintf("127.0.0.1:%d", 25500+ginkgoconfig.GinkgoConfig.ParallelNode),
-		Locket:              fmt.Sprintf("127.0.0.1:%d", 26500+ginkgoconfig.GinkgoConfig.ParallelNode),
-		SQL:                 fmt.Sprintf("%sdiego_%d", dbBaseConnectionString, ginkgoconfig.GinkgoConfig.ParallelNode),
-	}
-
-	node := GinkgoParallelNode()
-	startPort := 1000 * node
-	portRange := 950
-	endPort := startPort + portRange
-
-	allocator, err := portauthority.New(startPort, endPort)
-	Expect(err).NotTo(HaveOccurred())
-
-	certDepot := world.TempDirWithParent(suiteTempDir, "cert-depot")
-
-	certAuthority, err := certauthority.NewCertAuthority(certDepot, "ca")
-	Expect(err).NotTo(HaveOccurred())
+	suiteComponentMaker = world.MakeNodeComponentMaker(builtArtifacts, suiteTempDir)
+	suiteComponentMaker.Setup()
+	componentMaker = suiteComponentMaker
 
-	componentMaker = world.MakeComponentMaker(builtArtifacts, addresses, allocator, certAuthority)
-	componentMaker.Setup()
+	durations = perfbaseline.NewRecorder()
 })
 
 var _ = AfterSuite(func() {
-	if componentMaker != nil {
-		componentMaker.Teardown()
+	if durations != nil {
+		perfConfig, err := perfbaseline.ConfigFromEnv()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(perfConfig.Apply(durations.Baseline(), GinkgoWriter)).To(Succeed())
+	}
+
+	if suiteComponentMaker != nil {
+		suiteComponentMaker.Teardown()
 	}
 
 	deleteSuiteTempDir := func() error { return os.RemoveAll(suiteTempDir) }
@@ -116,16 +95,11 @@ var _ = AfterSuite(func() {
 })
 
 var _ = BeforeEach(func() {
-	plumbing = ginkgomon.Invoke(grouper.NewOrdered(os.Kill, grouper.Members{
-		{"initial-services", grouper.NewParallel(os.Kill, grouper.Members{
-			{"sql", componentMaker.SQL()},
-			{"nats", componentMaker.NATS()},
-			{"consul", componentMaker.Consul()},
-		})},
-		{"locket", componentMaker.Locket()},
-	}))
-	gardenProcess = ginkgomon.Invoke(componentMaker.Garden())
-	bbsProcess = ginkgomon.Invoke(componentMaker.BBS())
+	componentMaker = world.SpecComponentMaker(suiteComponentMaker)
+
+	plumbing = ginkgomon.Invoke(world.Plumbing(componentMaker, grouper.Member{Name: "nats", Runner: componentMaker.NATS()}))
+	gardenProcess = ginkgomon.Invoke(perfbaseline.TimedRunner(durations, "garden-start", componentMaker.Garden()))
+	bbsProcess = ginkgomon.Invoke(perfbaseline.TimedRunner(durations, "bbs-start", componentMaker.BBS()))
 
 	helpers.ConsulWaitUntilReady(componentMaker.Addresses())
 	lgr = lager.NewLogger("test")
@@ -135,11 +109,11 @@ var _ = BeforeEach(func() {
 	bbsClient = componentMaker.BBSClient()
 	bbsServiceClient = componentMaker.BBSServiceClient(lgr)
 
-	inigo_announcement_server.Start(os.Getenv("EXTERNAL_ADDRESS"))
+	announcementServer = inigo_announcement_server.New(os.Getenv("EXTERNAL_ADDRESS"))
 })
 
 var _ = AfterEach(func() {
-	inigo_announcement_server.Stop()
+	announcementServer.Stop()
 
 	destroyContainerErrors := helpers.CleanupGarden(gardenClient)
 
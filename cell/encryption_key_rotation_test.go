@@ -0,0 +1,85 @@
+package cell_test
+
+import (
+	"database/sql"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/world"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit/ginkgomon"
+)
+
+var _ = world.DescribeForEachDBDriver("Encryption key rotation", func() {
+	const (
+		newKeyLabel      = "secure-key-2"
+		newKeyPassphrase = "another-secure-passphrase"
+	)
+
+	var (
+		db    *sql.DB
+		blobs int
+	)
+
+	restartBBS := func(keys map[string]string) {
+		ginkgomon.Interrupt(bbsProcess)
+		bbsProcess = ginkgomon.Invoke(componentMaker.BBS(world.BBSEncryptionKeys(newKeyLabel, keys)))
+	}
+
+	BeforeEach(func() {
+		db = helpers.SQLConnection(lgr, componentMaker)
+
+		for i := 0; i < 3; i++ {
+			lrp := helpers.NewLRP(componentMaker.Addresses(), helpers.GenerateGuid(), helpers.LRPInstances(2))
+			Expect(bbsClient.DesireLRP(lgr, lrp)).To(Succeed())
+
+			task := helpers.NewTask(helpers.GenerateGuid(), &models.RunAction{User: "vcap", Path: "true"})
+			Expect(bbsClient.DesireTask(lgr, task.TaskGuid, task.Domain, task.TaskDefinition)).To(Succeed())
+		}
+
+		labels := helpers.EncryptionKeyLabels(db)
+		Expect(labels).To(HaveLen(1))
+		Expect(labels).To(HaveKey(world.DefaultEncryptionKeyLabel))
+		blobs = labels[world.DefaultEncryptionKeyLabel]
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).To(Succeed())
+	})
+
+	Context("when the BBS restarts with a new active key", func() {
+		BeforeEach(func() {
+			restartBBS(map[string]string{
+				world.DefaultEncryptionKeyLabel: "secure-passphrase",
+				newKeyLabel:                     newKeyPassphrase,
+			})
+			Eventually(helpers.EncryptionKeyLabelPoller(db)).Should(Equal(newKeyLabel))
+		})
+
+		It("re-encrypts every record with it", func() {
+			Expect(helpers.EncryptionKeyLabels(db)).To(Equal(map[string]int{
+				newKeyLabel: blobs,
+			}))
+			helpers.ExpectAllRecordsReadable(lgr, bbsClient)
+		})
+
+		Context("and then without the old key", func() {
+			BeforeEach(func() {
+				restartBBS(map[string]string{newKeyLabel: newKeyPassphrase})
+			})
+
+			It("still reads every record", func() {
+				helpers.ExpectAllRecordsReadable(lgr, bbsClient)
+
+				desiredLRPs, err := bbsClient.DesiredLRPs(lgr, models.DesiredLRPFilter{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(desiredLRPs).To(HaveLen(3))
+
+				tasks, err := bbsClient.Tasks(lgr)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(tasks).To(HaveLen(3))
+			})
+		})
+	})
+})
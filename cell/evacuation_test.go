@@ -15,6 +15,8 @@ import (
 	"code.cloudfoundry.org/guardian/gqt/runner"
 	"code.cloudfoundry.org/inigo/fixtures"
 	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/helpers/perfbaseline"
+	"code.cloudfoundry.org/inigo/world"
 	repconfig "code.cloudfoundry.org/rep/cmd/rep/config"
 	"code.cloudfoundry.org/tlsconfig"
 	"github.com/tedsuo/ifrit"
@@ -25,7 +27,7 @@ import (
 	. "github.com/onsi/gomega"
 )
 
-var _ = Describe("Evacuation", func() {
+var _ = world.DescribeForEachDBDriver("Evacuation", func() {
 	var (
 		ifritRuntime ifrit.Process
 
@@ -124,8 +126,8 @@ var _ = Describe("Evacuation", func() {
 	})
 
 	JustBeforeEach(func() {
-		cellA = ginkgomon.Invoke(cellARepRunner)
-		cellB = ginkgomon.Invoke(cellBRepRunner)
+		cellA = ginkgomon.Invoke(perfbaseline.TimedRunner(durations, "rep-start", cellARepRunner))
+		cellB = ginkgomon.Invoke(perfbaseline.TimedRunner(durations, "rep-start", cellBRepRunner))
 	})
 
 	AfterEach(func() {
@@ -147,6 +149,9 @@ var _ = Describe("Evacuation", func() {
 		Expect(len(lrps)).To(Equal(1))
 		Expect(lrps[0].Presence).NotTo(Equal(models.ActualLRP_Evacuating))
 
+		traffic := helpers.StartTrafficGenerator(componentMaker.Addresses().Router, helpers.DefaultHost)
+		defer traffic.Stop()
+
 		var evacuatingRepPort uint16
 		var evacuatingRepRunner *ginkgomon.Runner
 
@@ -162,6 +167,7 @@ var _ = Describe("Evacuation", func() {
 		}
 
 		By("posting the evacuation endpoint")
+		evacuationStarted := time.Now()
 		// Rep admin endpoint verifies and validate 127.0.0.1 for IP SAN
 		resp, err := httpClient.Post(fmt.Sprintf("https://127.0.0.1:%d/evacuate", evacuatingRepPort), "text/html", nil)
 		Expect(err).NotTo(HaveOccurred())
@@ -174,9 +180,19 @@ var _ = Describe("Evacuation", func() {
 			return evacuatingRepRunner.ExitCode()
 		}).Should(Equal(0))
 
+		durations.Since("evacuation", evacuationStarted)
+
 		By("running immediately after the rep exits and is routable")
 		Expect(helpers.LRPStatePoller(lgr, bbsClient, processGuid, nil)()).To(Equal(models.ActualLRPStateRunning))
 		Consistently(helpers.ResponseCodeFromHostPoller(componentMaker.Addresses().Router, helpers.DefaultHost)).Should(Equal(http.StatusOK))
+
+		By("never failing a request while the cell evacuated")
+		traffic.Stop()
+		report := traffic.ReportBetween(evacuationStarted, time.Now())
+		Expect(report.Total).To(BeNumerically(">", 0))
+		Expect(report.StatusCodes).NotTo(HaveKey(http.StatusBadGateway))
+		Expect(report.ErrorWindows).To(BeEmpty())
+		Expect(report.Availability()).To(Equal(100.0))
 	})
 
 	Context("when garden Destroy hangs", func() {
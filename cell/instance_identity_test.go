@@ -50,7 +50,7 @@ import (
 
 const GraceBusyboxImageURL = "docker:///cfdiegodocker/grace"
 
-var _ = Describe("InstanceIdentity", func() {
+var _ = world.DescribeForEachDBDriver("InstanceIdentity", func() {
 	var (
 		validityPeriod                              time.Duration
 		cellProcess                                 ifrit.Process
@@ -999,18 +999,18 @@ func runTaskAndGetCommandOutput(command string, organizationalUnits []string) st
 		resultFile = "/home/vcap/thingy"
 	}
 
-	expectedTask := helpers.TaskCreateRequestWithCertificateProperties(
+	expectedTask := helpers.NewTask(
 		guid,
 		&models.RunAction{
 			User: "vcap",
 			Path: shell,
 			Args: args,
 		},
-		&models.CertificateProperties{
+		helpers.TaskCertificateProperties(&models.CertificateProperties{
 			OrganizationalUnit: organizationalUnits,
-		},
+		}),
+		helpers.TaskResultFile(resultFile),
 	)
-	expectedTask.ResultFile = resultFile
 
 	err := bbsClient.DesireTask(lgr, expectedTask.TaskGuid, expectedTask.Domain, expectedTask.TaskDefinition)
 	Expect(err).NotTo(HaveOccurred())
@@ -15,6 +15,7 @@ import (
 	"code.cloudfoundry.org/durationjson"
 	"code.cloudfoundry.org/inigo/fixtures"
 	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/world"
 	"code.cloudfoundry.org/lager"
 	repconfig "code.cloudfoundry.org/rep/cmd/rep/config"
 	routeemitterconfig "code.cloudfoundry.org/route-emitter/cmd/route-emitter/config"
@@ -30,7 +31,7 @@ import (
 	"github.com/tedsuo/ifrit/grouper"
 )
 
-var _ = Describe("LocalRouteEmitter", func() {
+var _ = world.DescribeForEachDBDriver("LocalRouteEmitter", func() {
 	var (
 		processGuid                                  string
 		ifritRuntime, cellAProcess, cellBProcess     ifrit.Process
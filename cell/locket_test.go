@@ -0,0 +1,108 @@
+package cell_test
+
+import (
+	"database/sql"
+	"time"
+
+	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/world"
+	"code.cloudfoundry.org/locket"
+	locketmodels "code.cloudfoundry.org/locket/models"
+	repconfig "code.cloudfoundry.org/rep/cmd/rep/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+)
+
+var _ = world.DescribeForEachDBDriver("Locket", func() {
+	var (
+		db        *sql.DB
+		inspector *helpers.LocketInspector
+	)
+
+	BeforeEach(func() {
+		db = helpers.SQLConnection(lgr, componentMaker)
+		inspector = helpers.NewLocketInspector(lgr, componentMaker.LocketClient(lgr), db)
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).To(Succeed())
+	})
+
+	Describe("locks", func() {
+		It("shows the BBS holding its lock with the default TTL", func() {
+			Eventually(inspector.LockOwner("bbs")).Should(Equal("bbs-inigo-lock-owner"))
+
+			lock, ok := inspector.Resource("bbs")
+			Expect(ok).To(BeTrue())
+			Expect(lock.Type).To(Equal(locketmodels.LOCK))
+			Expect(lock.TTL).To(Equal(locket.DefaultSessionTTL))
+
+			Expect(inspector.Locks()).To(ContainElement(lock))
+		})
+
+		Context("when the lock is released", func() {
+			It("is taken back by the BBS", func() {
+				Eventually(inspector.LockOwner("bbs")).Should(Equal("bbs-inigo-lock-owner"))
+
+				inspector.Release("bbs")
+
+				Eventually(inspector.LockOwner("bbs")).Should(Equal("bbs-inigo-lock-owner"))
+				Consistently(bbsProcess.Wait()).ShouldNot(Receive())
+			})
+		})
+
+		Context("when the lock is stolen", func() {
+			It("hands it to the thief and makes the BBS give up", func() {
+				Eventually(inspector.LockOwner("bbs")).Should(Equal("bbs-inigo-lock-owner"))
+
+				inspector.Steal("bbs", "thief", time.Minute)
+
+				Eventually(bbsProcess.Wait()).Should(Receive())
+				Expect(inspector.LockOwner("bbs")()).To(Equal("thief"))
+				Expect(inspector.LockTTL("bbs")()).To(Equal(time.Minute))
+			})
+		})
+	})
+
+	Describe("cell presences", func() {
+		var (
+			cellID string
+			rep    ifrit.Process
+		)
+
+		BeforeEach(func() {
+			rep = ginkgomon.Invoke(componentMaker.Rep(func(cfg *repconfig.RepConfig) {
+				cellID = cfg.CellID
+			}))
+		})
+
+		AfterEach(func() {
+			helpers.StopProcesses(rep)
+		})
+
+		It("registers the rep with its lock TTL", func() {
+			Eventually(inspector.PresenceKeys()).Should(ConsistOf(cellID))
+
+			presence, ok := inspector.Resource(cellID)
+			Expect(ok).To(BeTrue())
+			Expect(presence.Type).To(Equal(locketmodels.PRESENCE))
+			Expect(presence.TTL).To(Equal(10 * time.Second))
+		})
+
+		Context("when the rep dies without releasing its presence", func() {
+			It("expires the presence after its TTL", func() {
+				Eventually(inspector.PresenceKeys()).Should(ConsistOf(cellID))
+				presence, _ := inspector.Resource(cellID)
+
+				killedAt := time.Now()
+				ginkgomon.Kill(rep)
+
+				Eventually(inspector.PresenceKeys(), 3*presence.TTL).Should(BeEmpty())
+				// the rep renews its presence every retry interval
+				Expect(time.Since(killedAt)).To(BeNumerically(">=", presence.TTL-locket.RetryInterval))
+			})
+		})
+	})
+})
@@ -16,6 +16,7 @@ import (
 	"code.cloudfoundry.org/durationjson"
 	"code.cloudfoundry.org/inigo/fixtures"
 	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/world"
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/lager/lagertest"
 	"code.cloudfoundry.org/rep/cmd/rep/config"
@@ -27,7 +28,7 @@ import (
 	. "github.com/onsi/gomega"
 )
 
-var _ = Context("when declarative healthchecks is turned on", func() {
+var _ = world.DescribeForEachDBDriver("when declarative healthchecks is turned on", func() {
 	var (
 		processGuid         string
 		archiveFiles        []archive_helper.ArchiveFile
@@ -7,18 +7,16 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
-	"sync"
 	"time"
 
 	archive_helper "code.cloudfoundry.org/archiver/extractor/test_helper"
-	"code.cloudfoundry.org/bbs/events"
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/inigo/fixtures"
 	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/world"
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/lager/lagertest"
 	"code.cloudfoundry.org/routing-info/cfroutes"
-	. "code.cloudfoundry.org/vizzini/matchers"
 	"github.com/tedsuo/ifrit"
 	"github.com/tedsuo/ifrit/ginkgomon"
 	"github.com/tedsuo/ifrit/grouper"
@@ -27,7 +25,7 @@ import (
 	. "github.com/onsi/gomega"
 )
 
-var _ = Describe("LRP", func() {
+var _ = world.DescribeForEachDBDriver("LRP", func() {
 	var (
 		processGuid         string
 		archiveFiles        []archive_helper.ArchiveFile
@@ -36,17 +34,10 @@ var _ = Describe("LRP", func() {
 		ifritRuntime    ifrit.Process
 		archiveFilePath string
 
-		lock        *sync.Mutex
-		eventSource events.EventSource
-		events      []models.Event
+		eventRecorder         *helpers.EventRecorder
+		instanceEventRecorder *helpers.EventRecorder
 	)
 
-	getEvents := func() []models.Event {
-		lock.Lock()
-		defer lock.Unlock()
-		return events
-	}
-
 	BeforeEach(func() {
 		if runtime.GOOS == "windows" {
 			Skip(" not yet working on windows")
@@ -68,30 +59,16 @@ var _ = Describe("LRP", func() {
 			filepath.Join(fileServerStaticDir, "lrp.zip"),
 			archiveFiles,
 		)
-
-		lock = &sync.Mutex{}
 	})
 
 	JustBeforeEach(func() {
-		var err error
-		eventSource, err = bbsClient.SubscribeToEvents(lgr)
-		Expect(err).NotTo(HaveOccurred())
-		go func() {
-			defer GinkgoRecover()
-
-			for {
-				event, err := eventSource.Next()
-				if err != nil {
-					return
-				}
-				lock.Lock()
-				events = append(events, event)
-				lock.Unlock()
-			}
-		}()
+		eventRecorder = helpers.NewEventRecorder(lgr, bbsClient, helpers.LegacyEventStream)
+		instanceEventRecorder = helpers.NewEventRecorder(lgr, bbsClient, helpers.InstanceEventStream)
 	})
 
 	AfterEach(func() {
+		eventRecorder.Stop()
+		instanceEventRecorder.Stop()
 		helpers.StopProcesses(ifritRuntime)
 	})
 
@@ -111,21 +88,57 @@ var _ = Describe("LRP", func() {
 			lrp.Privileged = true
 		})
 
+		var desiredAt time.Time
+
 		JustBeforeEach(func() {
+			desiredAt = time.Now()
 			err := bbsClient.DesireLRP(lgr, lrp)
 			Expect(err).NotTo(HaveOccurred())
 		})
 
 		It("eventually runs", func() {
 			Eventually(helpers.LRPStatePoller(lgr, bbsClient, processGuid, nil)).Should(Equal(models.ActualLRPStateRunning))
+			durations.Since("lrp-time-to-running", desiredAt)
 			Eventually(helpers.HelloWorldInstancePoller(componentMaker.Addresses().Router, helpers.DefaultHost)).Should(ConsistOf([]string{"0"}))
 		})
 
 		It("should send events as the LRP goes through its lifecycle ", func() {
-			Eventually(getEvents).Should(ContainElement(MatchDesiredLRPCreatedEvent(processGuid)))
-			Eventually(getEvents).Should(ContainElement(MatchActualLRPCreatedEvent(processGuid, 0)))
-			Eventually(getEvents).Should(ContainElement(MatchActualLRPChangedEvent(processGuid, 0, models.ActualLRPStateClaimed)))
-			Eventually(getEvents).Should(ContainElement(MatchActualLRPChangedEvent(processGuid, 0, models.ActualLRPStateRunning)))
+			Eventually(eventRecorder).Should(helpers.ReceiveInOrder(
+				helpers.MatchDesiredLRPCreatedEvent(processGuid),
+				helpers.MatchActualLRPCreatedEvent(processGuid, 0),
+				helpers.MatchActualLRPChangedEvent(processGuid, 0, models.ActualLRPStateClaimed),
+				helpers.MatchActualLRPChangedEvent(processGuid, 0, models.ActualLRPStateRunning),
+			))
+		})
+
+		It("should send instance events as the LRP goes through its lifecycle", func() {
+			Eventually(instanceEventRecorder).Should(helpers.ReceiveInOrder(
+				helpers.MatchActualLRPInstanceCreatedEvent(processGuid, 0),
+				helpers.MatchActualLRPInstanceChangedEvent(processGuid, 0, models.ActualLRPStateClaimed),
+				helpers.MatchActualLRPInstanceChangedEvent(processGuid, 0, models.ActualLRPStateRunning),
+			))
+			instanceEventRecorder.NeverReceive(helpers.MatchActualLRPInstanceRemovedEvent(processGuid, 0), 2*time.Second)
+			Expect(instanceEventRecorder).To(helpers.ReceiveExactly(1, helpers.MatchActualLRPInstanceChangedEvent(processGuid, 0, models.ActualLRPStateRunning)))
+		})
+
+		Context("when the BBS restarts", func() {
+			It("keeps recording events across the restart", func() {
+				Eventually(helpers.LRPStatePoller(lgr, bbsClient, processGuid, nil)).Should(Equal(models.ActualLRPStateRunning))
+
+				mark := eventRecorder.Mark()
+				helpers.StopProcesses(bbsProcess)
+				bbsProcess = ginkgomon.Invoke(componentMaker.BBS())
+				Eventually(eventRecorder.Reconnects).Should(BeNumerically(">", 0))
+
+				update := &models.DesiredLRPUpdate{}
+				update.SetInstances(2)
+				err := bbsClient.UpdateDesiredLRP(lgr, processGuid, update)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(func() []models.Event {
+					return eventRecorder.EventsSince(mark)
+				}).Should(ContainElement(helpers.MatchActualLRPCreatedEvent(processGuid, 1)))
+			})
 		})
 
 		Context("when using a private image", func() {
@@ -540,7 +553,7 @@ var _ = Describe("LRP", func() {
 
 		Context("Unsupported preloaded rootfs is requested", func() {
 			BeforeEach(func() {
-				lrp = helpers.LRPCreateRequestWithRootFS(componentMaker.Addresses(), processGuid, helpers.BogusPreloadedRootFS)
+				lrp = helpers.NewLRP(componentMaker.Addresses(), processGuid, helpers.LRPRootFS(helpers.BogusPreloadedRootFS))
 			})
 
 			It("fails and sets a placement error", func() {
@@ -559,7 +572,7 @@ var _ = Describe("LRP", func() {
 
 		Context("Unsupported arbitrary rootfs is requested", func() {
 			BeforeEach(func() {
-				lrp = helpers.LRPCreateRequestWithRootFS(componentMaker.Addresses(), processGuid, "socker://hello")
+				lrp = helpers.NewLRP(componentMaker.Addresses(), processGuid, helpers.LRPRootFS("socker://hello"))
 			})
 
 			It("fails and sets a placement error", func() {
@@ -685,7 +698,7 @@ var _ = Describe("LRP", func() {
 				})
 
 				It("contains the instance guid and cell id", func() {
-					Eventually(getEvents).Should(ContainElement(helpers.MatchActualLRPCrashedEvent(
+					Eventually(eventRecorder.Events).Should(ContainElement(helpers.MatchActualLRPCrashedEvent(
 						processGuid,
 						lrps[0].InstanceGuid,
 						lrps[0].CellId,
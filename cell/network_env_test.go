@@ -14,6 +14,7 @@ import (
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/inigo/fixtures"
 	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/world"
 	repconfig "code.cloudfoundry.org/rep/cmd/rep/config"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -22,7 +23,7 @@ import (
 	"github.com/tedsuo/ifrit/grouper"
 )
 
-var _ = Describe("Network Environment Variables", func() {
+var _ = world.DescribeForEachDBDriver("Network Environment Variables", func() {
 	var (
 		guid                string
 		modifyRepConfig     func(*repconfig.RepConfig)
@@ -58,15 +59,15 @@ var _ = Describe("Network Environment Variables", func() {
 		var task *models.Task
 
 		JustBeforeEach(func() {
-			taskToDesire := helpers.TaskCreateRequest(
+			taskToDesire := helpers.NewTask(
 				guid,
 				&models.RunAction{
 					User: "vcap",
 					Path: "sh",
 					Args: []string{"-c", "/usr/bin/env | grep 'CF_INSTANCE' > /home/vcap/env"},
 				},
+				helpers.TaskResultFile("/home/vcap/env"),
 			)
-			taskToDesire.ResultFile = "/home/vcap/env"
 
 			err := bbsClient.DesireTask(lgr, taskToDesire.TaskGuid, taskToDesire.Domain, taskToDesire.TaskDefinition)
 			Expect(err).NotTo(HaveOccurred())
@@ -9,6 +9,7 @@ import (
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/inigo/fixtures"
 	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/world"
 	"code.cloudfoundry.org/lager"
 
 	repconfig "code.cloudfoundry.org/rep/cmd/rep/config"
@@ -19,7 +20,7 @@ import (
 	"github.com/tedsuo/ifrit/grouper"
 )
 
-var _ = Describe("Placement Tags", func() {
+var _ = world.DescribeForEachDBDriver("Placement Tags", func() {
 	var (
 		guid         string
 		ifritRuntime ifrit.Process
@@ -79,7 +80,7 @@ var _ = Describe("Placement Tags", func() {
 
 		Context("when the desired LRP matches the required tags", func() {
 			BeforeEach(func() {
-				lrp = helpers.LRPCreateRequestWithPlacementTag(componentMaker.Addresses(), guid, []string{"inigo-tag"})
+				lrp = helpers.NewLRP(componentMaker.Addresses(), guid, helpers.LRPPlacementTags("inigo-tag"))
 			})
 
 			It("succeeds and is running on correct cell", func() {
@@ -98,7 +99,7 @@ var _ = Describe("Placement Tags", func() {
 
 		Context("when the desired LRP matches the required and optional tags", func() {
 			BeforeEach(func() {
-				lrp = helpers.LRPCreateRequestWithPlacementTag(componentMaker.Addresses(), guid, []string{"inigo-tag", "inigo-optional-tag"})
+				lrp = helpers.NewLRP(componentMaker.Addresses(), guid, helpers.LRPPlacementTags("inigo-tag", "inigo-optional-tag"))
 			})
 
 			It("succeeds and is running on correct cell", func() {
@@ -117,7 +118,7 @@ var _ = Describe("Placement Tags", func() {
 
 		Context("when no cells are advertising the placement tags", func() {
 			BeforeEach(func() {
-				lrp = helpers.LRPCreateRequestWithPlacementTag(componentMaker.Addresses(), guid, []string{""})
+				lrp = helpers.NewLRP(componentMaker.Addresses(), guid, helpers.LRPPlacementTags(""))
 			})
 
 			It("fails and sets a placement error", func() {
@@ -192,7 +193,7 @@ var _ = Describe("Placement Tags", func() {
 
 		Context("when the task matches the required tags", func() {
 			BeforeEach(func() {
-				task = helpers.TaskCreateRequestWithTags(guid, action, []string{"inigo-tag"})
+				task = helpers.NewTask(guid, action, helpers.TaskPlacementTags("inigo-tag"))
 			})
 
 			taskShouldRunSuccessfully()
@@ -200,7 +201,7 @@ var _ = Describe("Placement Tags", func() {
 
 		Context("when the task matches the required and optional tags", func() {
 			BeforeEach(func() {
-				task = helpers.TaskCreateRequestWithTags(guid, action, []string{"inigo-tag", "inigo-optional-tag"})
+				task = helpers.NewTask(guid, action, helpers.TaskPlacementTags("inigo-tag", "inigo-optional-tag"))
 			})
 
 			taskShouldRunSuccessfully()
@@ -208,7 +209,7 @@ var _ = Describe("Placement Tags", func() {
 
 		Context("when no cells are advertising the placement tags", func() {
 			BeforeEach(func() {
-				task = helpers.TaskCreateRequestWithTags(guid, action, []string{""})
+				task = helpers.NewTask(guid, action, helpers.TaskPlacementTags(""))
 			})
 
 			It("fails and sets a placement error", func() {
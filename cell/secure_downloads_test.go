@@ -3,7 +3,6 @@ package cell_test
 import (
 	"crypto/tls"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/http/httputil"
@@ -16,6 +15,7 @@ import (
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/inigo/fixtures"
 	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/world"
 	"code.cloudfoundry.org/rep/cmd/rep/config"
 	"code.cloudfoundry.org/tlsconfig"
 	"github.com/tedsuo/ifrit"
@@ -24,10 +24,9 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
-	"github.com/onsi/gomega/ghttp"
 )
 
-var _ = Describe("Secure Downloading and Uploading", func() {
+var _ = world.DescribeForEachDBDriver("Secure Downloading and Uploading", func() {
 	var (
 		processGuid         string
 		archiveFiles        []archive_helper.ArchiveFile
@@ -136,49 +135,25 @@ var _ = Describe("Secure Downloading and Uploading", func() {
 	Describe("uploading", func() {
 		var (
 			guid       string
-			server     *httptest.Server
-			gotRequest chan struct{}
+			sink       *helpers.UploadSink
+			sinkConfig []func(*helpers.UploadSinkConfig)
 		)
 
 		BeforeEach(func() {
 			guid = helpers.GenerateGuid()
 
-			gotRequest = make(chan struct{})
-
-			server, _ = helpers.Callback(os.Getenv("EXTERNAL_ADDRESS"), ghttp.CombineHandlers(
-				ghttp.VerifyRequest("POST", "/thingy"),
-				func(w http.ResponseWriter, r *http.Request) {
-					contents, err := ioutil.ReadAll(r.Body)
-					Expect(err).NotTo(HaveOccurred())
-
-					Expect(string(contents)).To(Equal("tasty thingy\n"))
-
-					close(gotRequest)
+			sinkConfig = []func(*helpers.UploadSinkConfig){
+				func(config *helpers.UploadSinkConfig) {
+					config.ServerCertFile = componentMaker.BBSSSLConfig().ServerCert
+					config.ServerKeyFile = componentMaker.BBSSSLConfig().ServerKey
+					config.ClientCACertFile = componentMaker.BBSSSLConfig().CACert
 				},
-			))
-
-			serverURL, err := url.Parse(server.URL)
-			Expect(err).NotTo(HaveOccurred())
-			proxy := httputil.NewSingleHostReverseProxy(serverURL)
-			tlsFileServer = httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
-				proxy.ServeHTTP(rw, req)
-			}))
-			tlsConfig, err := tlsconfig.Build(
-				tlsconfig.WithInternalServiceDefaults(),
-				tlsconfig.WithIdentityFromFile(componentMaker.BBSSSLConfig().ServerCert, componentMaker.BBSSSLConfig().ServerKey),
-			).Server(
-				tlsconfig.WithClientAuthenticationFromFile(componentMaker.BBSSSLConfig().CACert),
-			)
-			Expect(err).NotTo(HaveOccurred())
-			tlsFileServer.TLS = tlsConfig
-			tlsFileServer.StartTLS()
+			}
 		})
 
-		AfterEach(func() {
-			server.Close()
-		})
+		JustBeforeEach(func() {
+			sink = helpers.NewUploadSink(os.Getenv("EXTERNAL_ADDRESS"), sinkConfig...)
 
-		It("uploads the specified files", func() {
 			expectedTask := helpers.TaskCreateRequest(
 				guid,
 				models.Serial(
@@ -189,7 +164,7 @@ var _ = Describe("Secure Downloading and Uploading", func() {
 					},
 					&models.UploadAction{
 						From: "thingy",
-						To:   fmt.Sprintf("%s/thingy", tlsFileServer.URL),
+						To:   sink.URL("thingy"),
 						User: "vcap",
 					},
 				),
@@ -197,8 +172,33 @@ var _ = Describe("Secure Downloading and Uploading", func() {
 
 			err := bbsClient.DesireTask(lgr, expectedTask.TaskGuid, expectedTask.Domain, expectedTask.TaskDefinition)
 			Expect(err).NotTo(HaveOccurred())
+		})
 
-			Eventually(gotRequest).Should(BeClosed())
+		AfterEach(func() {
+			sink.Close()
+		})
+
+		It("uploads the specified files", func() {
+			Eventually(sink.SuccessfulUploads).Should(HaveLen(1))
+
+			upload := sink.SuccessfulUploads()[0]
+			Expect(upload.Path).To(Equal("/thingy"))
+			Expect(string(upload.Body)).To(Equal("tasty thingy\n"))
+		})
+
+		Context("when the upload destination requires a client certificate the rep does not have", func() {
+			BeforeEach(func() {
+				sinkConfig = append(sinkConfig, func(config *helpers.UploadSinkConfig) {
+					config.ClientCACertFile = "../fixtures/certs/wrong-ca.crt"
+				})
+			})
+
+			It("fails the task without delivering the upload", func() {
+				var task models.Task
+				Eventually(helpers.TaskStatePoller(lgr, bbsClient, guid, &task)).Should(Equal(models.Task_Completed))
+				Expect(task.Failed).To(BeTrue())
+				Expect(sink.Uploads()).To(BeEmpty())
+			})
 		})
 	})
 })
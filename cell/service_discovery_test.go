@@ -0,0 +1,138 @@
+package cell_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+
+	"code.cloudfoundry.org/bbs"
+	bbsconfig "code.cloudfoundry.org/bbs/cmd/bbs/config"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/world"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+	"github.com/tedsuo/ifrit/grouper"
+)
+
+var _ = world.DescribeForEachDBDriver("Service discovery", func() {
+	var (
+		dns         *world.DNSServer
+		dnsProcess  ifrit.Process
+		cellProcess ifrit.Process
+	)
+
+	runTask := func(client bbs.InternalClient) {
+		guid := helpers.GenerateGuid()
+		task := helpers.NewTask(guid, &models.RunAction{
+			User: "vcap",
+			Path: "sh",
+			Args: []string{"-c", "exit 0"},
+		})
+		Expect(client.DesireTask(lgr, task.TaskGuid, task.Domain, task.TaskDefinition)).To(Succeed())
+
+		Eventually(helpers.TaskStatePoller(lgr, client, guid, task)).Should(Equal(models.Task_Completed))
+		Expect(task.Failed).To(BeFalse())
+	}
+
+	BeforeEach(func() {
+		if runtime.GOOS == "windows" {
+			Skip(" not yet working on windows")
+		}
+		if os.Geteuid() != 0 {
+			Skip("the DNS server needs root to listen on port 53 and to be mounted over components' resolv.conf")
+		}
+
+		dns = world.NewDNSServer(componentMaker)
+		dnsProcess = ginkgomon.Invoke(dns)
+
+		By("restarting the BBS to find the auctioneer and locket by name")
+		ginkgomon.Interrupt(bbsProcess)
+		bbsProcess = ginkgomon.Invoke(dns.Resolving(componentMaker.BBS(world.BBSUsingServiceNames(componentMaker))))
+
+		cellProcess = ginkgomon.Invoke(grouper.NewParallel(os.Kill, grouper.Members{
+			{"rep", dns.Resolving(componentMaker.Rep(world.RepUsingServiceNames(componentMaker)))},
+			{"auctioneer", dns.Resolving(componentMaker.Auctioneer(world.AuctioneerUsingServiceNames(componentMaker)))},
+		}))
+
+		Eventually(func() (models.CellSet, error) { return bbsServiceClient.Cells(lgr) }).Should(HaveLen(1))
+	})
+
+	AfterEach(func() {
+		helpers.StopProcesses(cellProcess, dnsProcess)
+	})
+
+	It("resolves the service names to the world's components", func() {
+		addresses, err := dns.Resolver().LookupHost(context.Background(), world.BBSServiceName)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(addresses).To(ConsistOf("127.0.0.1"))
+
+		cells, err := bbsServiceClient.Cells(lgr)
+		Expect(err).NotTo(HaveOccurred())
+		for cellID := range cells {
+			addresses, err := dns.Resolver().LookupHost(context.Background(), cellID+"."+world.CellServiceDomain)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(addresses).To(ConsistOf("127.0.0.1"))
+		}
+	})
+
+	It("runs tasks with every component finding the others by name", func() {
+		runTask(bbsClient)
+	})
+
+	Context("when the BBS fails over to another address", func() {
+		var standbyHost string
+
+		BeforeEach(func() {
+			standbyHost = fmt.Sprintf("127.0.%d.2", GinkgoParallelNode())
+			healthPort, err := componentMaker.PortAllocator().ClaimPorts(1)
+			Expect(err).NotTo(HaveOccurred())
+
+			// the standby's certificate also has its own address, so the test
+			// can reach it without resolving its name
+			standbyKey, standbyCert, err := componentMaker.CertAuthority().GenerateSelfSignedCertAndKey(
+				"bbs_server",
+				[]string{"bbs_server", world.BBSServiceName, world.LocketServiceName, standbyHost},
+				false,
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			standby, ok := componentMaker.BBS(world.BBSUsingServiceNames(componentMaker), func(cfg *bbsconfig.BBSConfig) {
+				cfg.UUID = "bbs-inigo-standby"
+				cfg.ListenAddress = world.ServiceAddress(standbyHost, componentMaker.Addresses().BBS)
+				cfg.HealthAddress = fmt.Sprintf("%s:%d", standbyHost, healthPort)
+				cfg.CertFile = standbyCert
+				cfg.KeyFile = standbyKey
+			}).(*ginkgomon.Runner)
+			Expect(ok).To(BeTrue(), "BBS runner is not a ginkgomon runner")
+			// ready as soon as it waits for the lock
+			standby.StartCheck = "bbs.locket-lock.started"
+			standbyProcess := ginkgomon.Invoke(dns.Resolving(standby))
+
+			By("killing the BBS without letting it release the lock")
+			ginkgomon.Kill(bbsProcess)
+			bbsProcess = standbyProcess
+
+			By("moving the BBS's name to the standby")
+			dns.SetRecord(world.BBSServiceName, standbyHost)
+		})
+
+		It("has the cell and auctioneer follow the name to the standby", func() {
+			ssl := componentMaker.BBSSSLConfig()
+			client, err := bbs.NewClient(
+				"https://"+world.ServiceAddress(standbyHost, componentMaker.Addresses().BBS),
+				ssl.CACert,
+				ssl.ClientCert,
+				ssl.ClientKey,
+				0, 0,
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(func() bool { return client.Ping(lgr) }, helpers.BBSFailoverTimeout).Should(BeTrue())
+			runTask(client)
+		})
+	})
+})
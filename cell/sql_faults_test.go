@@ -0,0 +1,195 @@
+package cell_test
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/world"
+	locketconfig "code.cloudfoundry.org/locket/cmd/locket/config"
+	repconfig "code.cloudfoundry.org/rep/cmd/rep/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+)
+
+var _ = world.DescribeForEachDBDriver("SQL faults", func() {
+	var (
+		proxy        *world.SQLProxy
+		proxyProcess ifrit.Process
+
+		// the error the BBS and locket retry a transaction on
+		retriedError world.SQLError
+	)
+
+	BeforeEach(func() {
+		retriedError = world.SQLDeadlock
+		if world.SpecDBDriver() == world.PostgresDriver {
+			retriedError = world.SQLSerializationFailure
+		}
+
+		proxy = world.NewSQLProxy(componentMaker)
+		proxyProcess = ginkgomon.Invoke(proxy)
+	})
+
+	AfterEach(func() {
+		helpers.StopProcesses(proxyProcess)
+	})
+
+	Context("between the BBS and its database", func() {
+		desireLRP := func() error {
+			lrp := helpers.NewLRP(componentMaker.Addresses(), helpers.GenerateGuid())
+			return bbsClient.DesireLRP(lgr, lrp)
+		}
+
+		BeforeEach(func() {
+			ginkgomon.Interrupt(bbsProcess)
+			bbsProcess = ginkgomon.Invoke(componentMaker.BBS(world.BBSThroughSQLProxy(proxy)))
+		})
+
+		It("retries a transaction that fails with a retriable error", func() {
+			fault := proxy.Inject(world.SQLFault{
+				Statement: world.MatchStatement(`^\s*INSERT INTO desired_lrps\b`),
+				Error:     retriedError,
+				Times:     2,
+			})
+
+			Expect(desireLRP()).To(Succeed())
+			Expect(fault.Hits()).To(Equal(2))
+		})
+
+		It("gives up on a transaction that keeps failing, and recovers once it stops", func() {
+			fault := proxy.Inject(world.SQLFault{
+				Statement: world.MatchStatement(`^\s*INSERT INTO desired_lrps\b`),
+				Error:     retriedError,
+			})
+
+			Expect(desireLRP()).NotTo(Succeed())
+			Expect(fault.Hits()).To(BeNumerically(">", 1))
+
+			fault.Remove()
+			Expect(desireLRP()).To(Succeed())
+		})
+
+		It("reconnects after losing its connection", func() {
+			fault := proxy.Inject(world.SQLFault{
+				Statement: world.MatchTable("tasks"),
+				Error:     world.SQLLostConnection,
+				Times:     1,
+			})
+
+			Eventually(func() error {
+				_, err := bbsClient.Tasks(lgr)
+				return err
+			}).Should(Succeed())
+			Expect(fault.Hits()).To(Equal(1))
+		})
+
+		It("waits out slow statements", func() {
+			proxy.Inject(world.SQLFault{
+				Statement: world.MatchTable("tasks"),
+				Latency:   2 * time.Second,
+				Times:     1,
+			})
+
+			start := time.Now()
+			_, err := bbsClient.Tasks(lgr)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(time.Since(start)).To(BeNumerically(">=", 2*time.Second))
+		})
+
+		It("blocks on a hung statement until it is let go", func() {
+			fault := proxy.Inject(world.SQLFault{
+				Statement: world.MatchTable("tasks"),
+				Hang:      true,
+				Times:     1,
+			})
+
+			done := make(chan error, 1)
+			go func() {
+				_, err := bbsClient.Tasks(lgr)
+				done <- err
+			}()
+
+			Consistently(done, 2*time.Second).ShouldNot(Receive())
+			fault.Remove()
+			Eventually(done).Should(Receive(BeNil()))
+		})
+	})
+
+	Context("between locket and its database", func() {
+		var (
+			db                 *sql.DB
+			inspector          *helpers.LocketInspector
+			locketAddress      string
+			locketRunner       *ginkgomon.Runner
+			locketProcess, rep ifrit.Process
+			cellID             string
+		)
+
+		startRepThroughLocket := func() {
+			rep = ginkgomon.Invoke(componentMaker.Rep(func(cfg *repconfig.RepConfig) {
+				cellID = cfg.CellID
+				cfg.ClientLocketConfig.LocketAddress = locketAddress
+			}))
+		}
+
+		BeforeEach(func() {
+			port, err := componentMaker.PortAllocator().ClaimPorts(1)
+			Expect(err).NotTo(HaveOccurred())
+			locketAddress = fmt.Sprintf("127.0.0.1:%d", port)
+
+			// a second locket, so that only the rep goes through the proxy
+			var ok bool
+			locketRunner, ok = componentMaker.Locket(
+				world.LocketThroughSQLProxy(proxy),
+				func(cfg *locketconfig.LocketConfig) {
+					cfg.ListenAddress = locketAddress
+				},
+			).(*ginkgomon.Runner)
+			Expect(ok).To(BeTrue(), "locket runner is not a ginkgomon runner")
+			locketProcess = ginkgomon.Invoke(locketRunner)
+
+			db = helpers.SQLConnection(lgr, componentMaker)
+			inspector = helpers.NewLocketInspector(lgr, componentMaker.LocketClient(lgr), db)
+		})
+
+		AfterEach(func() {
+			helpers.StopProcesses(rep, locketProcess)
+			Expect(db.Close()).To(Succeed())
+		})
+
+		It("retries the transaction that failed with a retriable error", func() {
+			fault := proxy.Inject(world.SQLFault{
+				Statement: world.MatchTable("locks"),
+				Error:     retriedError,
+				Times:     1,
+			})
+			startRepThroughLocket()
+
+			Eventually(inspector.PresenceKeys()).Should(ConsistOf(cellID))
+			Expect(fault.Hits()).To(Equal(1))
+
+			// the rep tries again every LockRetryInterval anyway, so the
+			// presence alone doesn't show that locket retried; its
+			// transaction helper logs each retry of a deadlocked
+			// transaction
+			Expect(locketRunner).To(gbytes.Say("deadlock-transaction"))
+		})
+
+		It("registers a presence after losing its connection", func() {
+			fault := proxy.Inject(world.SQLFault{
+				Statement: world.MatchTable("locks"),
+				Error:     world.SQLLostConnection,
+				Times:     1,
+			})
+			startRepThroughLocket()
+
+			Eventually(inspector.PresenceKeys()).Should(ConsistOf(cellID))
+			Expect(fault.Hits()).To(Equal(1))
+		})
+	})
+})
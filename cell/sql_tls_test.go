@@ -0,0 +1,88 @@
+package cell_test
+
+import (
+	"fmt"
+
+	bbsconfig "code.cloudfoundry.org/bbs/cmd/bbs/config"
+	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/helpers/certauthority"
+	"code.cloudfoundry.org/inigo/world"
+	locketconfig "code.cloudfoundry.org/locket/cmd/locket/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+)
+
+var _ = world.DescribeForEachDBDriver("TLS to the database", func() {
+	var (
+		tlsMaker        world.ComponentMaker
+		databaseProcess ifrit.Process
+		otherCACertFile string
+	)
+
+	expectToFailToStart := func(runner ifrit.Runner) {
+		process := ifrit.Background(runner)
+		Eventually(process.Wait()).Should(Receive(HaveOccurred()))
+		Expect(process.Ready()).NotTo(BeClosed())
+	}
+
+	BeforeEach(func() {
+		database := world.MakeTLSDatabase(componentMaker)
+		databaseProcess = ginkgomon.Invoke(database)
+		tlsMaker = world.WithTLSDatabase(componentMaker, database)
+
+		otherCA, err := certauthority.NewCertAuthority(world.TempDirWithParent(suiteTempDir, "other-ca"), "other-ca")
+		Expect(err).NotTo(HaveOccurred())
+		_, otherCACertFile = otherCA.CAAndKey()
+	})
+
+	AfterEach(func() {
+		helpers.StopProcesses(databaseProcess)
+	})
+
+	Describe("the BBS", func() {
+		BeforeEach(func() {
+			ginkgomon.Interrupt(bbsProcess)
+			bbsProcess = nil
+		})
+
+		It("connects when it trusts the CA that signed the database's certificate", func() {
+			bbsProcess = ginkgomon.Invoke(tlsMaker.BBS())
+
+			Expect(bbsClient.Ping(lgr)).To(BeTrue())
+			lrp := helpers.NewLRP(componentMaker.Addresses(), helpers.GenerateGuid())
+			Expect(bbsClient.DesireLRP(lgr, lrp)).To(Succeed())
+		})
+
+		It("refuses to start when it doesn't", func() {
+			expectToFailToStart(tlsMaker.BBS(func(cfg *bbsconfig.BBSConfig) {
+				cfg.SQLCACertFile = otherCACertFile
+			}))
+		})
+	})
+
+	Describe("locket", func() {
+		var listenAddress func(*locketconfig.LocketConfig)
+
+		BeforeEach(func() {
+			// the suite's locket keeps the world's address
+			port, err := componentMaker.PortAllocator().ClaimPorts(1)
+			Expect(err).NotTo(HaveOccurred())
+			listenAddress = func(cfg *locketconfig.LocketConfig) {
+				cfg.ListenAddress = fmt.Sprintf("127.0.0.1:%d", port)
+			}
+		})
+
+		It("connects when it trusts the CA that signed the database's certificate", func() {
+			locketProcess := ginkgomon.Invoke(tlsMaker.Locket(listenAddress))
+			helpers.StopProcesses(locketProcess)
+		})
+
+		It("refuses to start when it doesn't", func() {
+			expectToFailToStart(tlsMaker.Locket(listenAddress, func(cfg *locketconfig.LocketConfig) {
+				cfg.SQLCACertFile = otherCACertFile
+			}))
+		})
+	})
+})
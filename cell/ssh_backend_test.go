@@ -0,0 +1,144 @@
+package cell_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+
+	archive_helper "code.cloudfoundry.org/archiver/extractor/test_helper"
+	"code.cloudfoundry.org/bbs/models"
+	sshproxyconfig "code.cloudfoundry.org/diego-ssh/cmd/ssh-proxy/config"
+	"code.cloudfoundry.org/inigo/fixtures"
+	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/world"
+	repconfig "code.cloudfoundry.org/rep/cmd/rep/config"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+	"github.com/tedsuo/ifrit/grouper"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = world.DescribeForEachDBDriver("SSH proxy backends", func() {
+	var (
+		processGuid string
+
+		creds          world.ContainerProxyCredentials
+		repConfigs     []func(*repconfig.RepConfig)
+		sshProxyConfig []func(*sshproxyconfig.SSHProxyConfig)
+
+		ifritRuntime ifrit.Process
+		client       *helpers.SSHClient
+	)
+
+	BeforeEach(func() {
+		if runtime.GOOS == "windows" {
+			Skip(" not yet working on windows")
+		}
+
+		processGuid = helpers.GenerateGuid()
+		creds = world.MakeContainerProxyCredentials(suiteTempDir)
+		repConfigs = nil
+		sshProxyConfig = nil
+	})
+
+	JustBeforeEach(func() {
+		fileServer, fileServerStaticDir := componentMaker.FileServer()
+		ifritRuntime = ginkgomon.Invoke(grouper.NewParallel(os.Kill, grouper.Members{
+			{"file-server", fileServer},
+			{"rep", componentMaker.Rep(repConfigs...)},
+			{"auctioneer", componentMaker.Auctioneer()},
+			{"ssh-proxy", componentMaker.SSHProxy(sshProxyConfig...)},
+		}))
+
+		helpers.InstallSSHD(componentMaker.Artifacts(), fileServerStaticDir)
+		archive_helper.CreateZipArchive(
+			filepath.Join(fileServerStaticDir, "lrp.zip"),
+			fixtures.GoServerApp(),
+		)
+
+		lrp := helpers.SSHEnabledLRP(
+			componentMaker.Addresses(),
+			componentMaker.SSHConfig(),
+			helpers.DefaultLRPCreateRequest(componentMaker.Addresses(), processGuid, "log-guid", 1),
+		)
+		err := bbsClient.DesireLRP(lgr, lrp)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(helpers.LRPStatePoller(lgr, bbsClient, processGuid, nil)).Should(Equal(models.ActualLRPStateRunning))
+
+		client, err = helpers.DialSSHProxy(componentMaker.Addresses().SSHProxy, helpers.DiegoSSHUser(processGuid, 0))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		if client != nil {
+			client.Close()
+			client = nil
+		}
+		helpers.StopProcesses(ifritRuntime)
+	})
+
+	itProxiesThrough := func(path helpers.SSHBackendPath) {
+		It("proxies the session through the "+string(path), func() {
+			output, _, exitStatus, err := client.Exec("echo $INSTANCE_INDEX")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exitStatus).To(Equal(0))
+			Expect(string(output)).To(Equal("0\n"))
+
+			addresses := helpers.SSHBackendAddresses(lgr, bbsClient, processGuid, 0)
+			Eventually(func() []helpers.SSHBackendPath {
+				return helpers.EstablishedSSHBackendPaths(addresses)
+			}).Should(ConsistOf(path))
+		})
+	}
+
+	Context("by default", func() {
+		itProxiesThrough(helpers.SSHBackendHostPort)
+	})
+
+	Context("when connecting to the instance address", func() {
+		BeforeEach(func() {
+			sshProxyConfig = append(sshProxyConfig, world.SSHProxyConnectToInstanceAddress)
+		})
+
+		itProxiesThrough(helpers.SSHBackendInstanceAddress)
+	})
+
+	Context("when the container proxy fronts sshd and backend TLS is enabled", func() {
+		BeforeEach(func() {
+			envoyConfigDir := world.TempDirWithParent(suiteTempDir, "envoy_config")
+			repConfigs = append(repConfigs, creds.RepWithContainerProxy(envoyConfigDir, false))
+			sshProxyConfig = append(sshProxyConfig, creds.SSHProxyBackendTLS)
+		})
+
+		itProxiesThrough(helpers.SSHBackendHostTLSPort)
+
+		Context("and connecting to the instance address", func() {
+			BeforeEach(func() {
+				sshProxyConfig = append(sshProxyConfig, world.SSHProxyConnectToInstanceAddress)
+			})
+
+			itProxiesThrough(helpers.SSHBackendInstanceTLSPort)
+		})
+
+		Context("and envoy requires client certificates", func() {
+			BeforeEach(func() {
+				envoyConfigDir := world.TempDirWithParent(suiteTempDir, "envoy_config")
+				repConfigs = []func(*repconfig.RepConfig){creds.RepWithContainerProxy(envoyConfigDir, true)}
+			})
+
+			itProxiesThrough(helpers.SSHBackendHostTLSPort)
+		})
+	})
+
+	Context("when the container proxy fronts sshd but backend TLS is disabled", func() {
+		BeforeEach(func() {
+			envoyConfigDir := world.TempDirWithParent(suiteTempDir, "envoy_config")
+			repConfigs = append(repConfigs, creds.RepWithContainerProxy(envoyConfigDir, false))
+		})
+
+		itProxiesThrough(helpers.SSHBackendHostPort)
+	})
+})
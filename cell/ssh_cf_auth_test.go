@@ -0,0 +1,179 @@
+package cell_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	archive_helper "code.cloudfoundry.org/archiver/extractor/test_helper"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/inigo/fixtures"
+	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/world"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+	"github.com/tedsuo/ifrit/grouper"
+	"golang.org/x/crypto/ssh"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = world.DescribeForEachDBDriver("SSH with CF authentication", func() {
+	var (
+		processGuid string
+		appGuid     string
+
+		uaa *helpers.FakeUAA
+		cc  *helpers.FakeCC
+
+		ifritRuntime ifrit.Process
+		address      string
+	)
+
+	dial := func(code string) (*helpers.SSHClient, error) {
+		return helpers.DialSSHProxy(address, helpers.CFSSHUser(appGuid, 0), ssh.Password(code))
+	}
+
+	BeforeEach(func() {
+		if runtime.GOOS == "windows" {
+			Skip(" not yet working on windows")
+		}
+
+		processGuid = helpers.GenerateGuid()
+		appGuid = helpers.GenerateGuid()
+		address = componentMaker.Addresses().SSHProxy
+
+		uaa = helpers.NewFakeUAA("127.0.0.1")
+		cc = helpers.NewFakeCC("127.0.0.1")
+
+		fileServer, fileServerStaticDir := componentMaker.FileServer()
+		ifritRuntime = ginkgomon.Invoke(grouper.NewParallel(os.Kill, grouper.Members{
+			{"file-server", fileServer},
+			{"rep", componentMaker.Rep()},
+			{"auctioneer", componentMaker.Auctioneer()},
+			{"ssh-proxy", componentMaker.SSHProxy(helpers.SSHProxyCFAuth(uaa, cc))},
+		}))
+
+		helpers.InstallSSHD(componentMaker.Artifacts(), fileServerStaticDir)
+		archive_helper.CreateZipArchive(
+			filepath.Join(fileServerStaticDir, "lrp.zip"),
+			fixtures.GoServerApp(),
+		)
+
+		lrp := helpers.SSHEnabledLRP(
+			componentMaker.Addresses(),
+			componentMaker.SSHConfig(),
+			helpers.DefaultLRPCreateRequest(componentMaker.Addresses(), processGuid, "log-guid", 1),
+		)
+		err := bbsClient.DesireLRP(lgr, lrp)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(helpers.LRPStatePoller(lgr, bbsClient, processGuid, nil)).Should(Equal(models.ActualLRPStateRunning))
+	})
+
+	AfterEach(func() {
+		helpers.StopProcesses(ifritRuntime)
+		uaa.Close()
+		cc.Close()
+	})
+
+	Context("when the code is valid and CC grants access", func() {
+		BeforeEach(func() {
+			uaa.IssueCode("good-code", "good-token")
+			cc.GrantSSHAccess("good-token", appGuid, processGuid)
+		})
+
+		It("connects to the app instance", func() {
+			client, err := dial("good-code")
+			Expect(err).NotTo(HaveOccurred())
+			defer client.Close()
+
+			output, _, exitStatus, err := client.Exec("echo $INSTANCE_INDEX")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exitStatus).To(Equal(0))
+			Expect(string(output)).To(Equal("0\n"))
+
+			Expect(cc.Requests()).To(ContainElement("/internal/apps/" + appGuid + "/ssh_access/0"))
+		})
+
+		It("does not accept the same code twice", func() {
+			client, err := dial("good-code")
+			Expect(err).NotTo(HaveOccurred())
+			client.Close()
+
+			_, err = dial("good-code")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when the user gets the code from UAA's authorize endpoint", func() {
+		BeforeEach(func() {
+			cc.GrantSSHAccess("user-token", appGuid, processGuid)
+		})
+
+		It("connects to the app instance with the code", func() {
+			code, err := uaa.RequestSSHCode("user-token")
+			Expect(err).NotTo(HaveOccurred())
+
+			client, err := dial(code)
+			Expect(err).NotTo(HaveOccurred())
+			defer client.Close()
+
+			output, _, exitStatus, err := client.Exec("echo $INSTANCE_INDEX")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exitStatus).To(Equal(0))
+			Expect(string(output)).To(Equal("0\n"))
+		})
+
+		It("gives out a different code each time", func() {
+			first, err := uaa.RequestSSHCode("user-token")
+			Expect(err).NotTo(HaveOccurred())
+			second, err := uaa.RequestSSHCode("user-token")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(first).NotTo(Equal(second))
+
+			client, err := dial(first)
+			Expect(err).NotTo(HaveOccurred())
+			client.Close()
+
+			client, err = dial(second)
+			Expect(err).NotTo(HaveOccurred())
+			client.Close()
+		})
+
+		It("refuses to issue a code without a token", func() {
+			_, err := uaa.RequestSSHCode("")
+			Expect(err).To(MatchError(ContainSubstring("401")))
+		})
+	})
+
+	Context("when the code has expired", func() {
+		BeforeEach(func() {
+			uaa.ExpireCode("old-code")
+		})
+
+		It("rejects the connection without asking CC", func() {
+			_, err := dial("old-code")
+			Expect(err).To(HaveOccurred())
+
+			Expect(uaa.RequestCount()).To(BeNumerically(">", 0))
+			Expect(cc.Requests()).To(BeEmpty())
+		})
+	})
+
+	Context("when CC denies access", func() {
+		BeforeEach(func() {
+			uaa.IssueCode("good-code", "good-token")
+			cc.DenySSHAccess("good-token", appGuid, http.StatusForbidden)
+		})
+
+		It("rejects the connection", func() {
+			_, err := dial("good-code")
+			Expect(err).To(HaveOccurred())
+
+			Expect(cc.Requests()).To(ContainElement("/internal/apps/" + appGuid + "/ssh_access/0"))
+		})
+	})
+})
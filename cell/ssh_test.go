@@ -1,20 +1,18 @@
 package cell_test
 
 import (
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"time"
 
-	"code.cloudfoundry.org/archiver/compressor"
 	archive_helper "code.cloudfoundry.org/archiver/extractor/test_helper"
 	"code.cloudfoundry.org/bbs/models"
-	ssh_helpers "code.cloudfoundry.org/diego-ssh/helpers"
-	"code.cloudfoundry.org/diego-ssh/routes"
 	"code.cloudfoundry.org/inigo/fixtures"
 	"code.cloudfoundry.org/inigo/helpers"
 	"code.cloudfoundry.org/inigo/world"
@@ -29,22 +27,15 @@ import (
 	. "github.com/onsi/gomega"
 )
 
-var _ = Describe("SSH", func() {
+var _ = world.DescribeForEachDBDriver("SSH", func() {
 	verifySSH := func(address, processGuid string, index int) {
-		clientConfig := &ssh.ClientConfig{
-			User:            fmt.Sprintf("diego:%s/%d", processGuid, index),
-			Auth:            []ssh.AuthMethod{ssh.Password("")},
-			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		}
-
-		client, err := ssh.Dial("tcp", address, clientConfig)
+		client, err := helpers.DialSSHProxy(address, helpers.DiegoSSHUser(processGuid, index))
 		Expect(err).NotTo(HaveOccurred())
+		defer client.Close()
 
-		session, err := client.NewSession()
-		Expect(err).NotTo(HaveOccurred())
-
-		output, err := session.Output("env")
+		output, _, exitStatus, err := client.Exec("env")
 		Expect(err).NotTo(HaveOccurred())
+		Expect(exitStatus).To(Equal(0))
 
 		Expect(string(output)).To(ContainSubstring("USER=root"))
 		Expect(string(output)).To(ContainSubstring("TEST=foobar"))
@@ -79,85 +70,44 @@ var _ = Describe("SSH", func() {
 			{"ssh-proxy", componentMaker.SSHProxy()},
 		}))
 
-		tgCompressor := compressor.NewTgz()
-		err := tgCompressor.Compress(componentMaker.Artifacts().Executables["sshd"], filepath.Join(fileServerStaticDir, "sshd.tgz"))
-		Expect(err).NotTo(HaveOccurred())
+		helpers.InstallSSHD(componentMaker.Artifacts(), fileServerStaticDir)
 
 		archive_helper.CreateZipArchive(
 			filepath.Join(fileServerStaticDir, "lrp.zip"),
 			fixtures.GoServerApp(),
 		)
 
-		sshRoute := routes.SSHRoute{
-			ContainerPort:   3456,
-			PrivateKey:      componentMaker.SSHConfig().PrivateKeyPem,
-			HostFingerprint: ssh_helpers.MD5Fingerprint(componentMaker.SSHConfig().HostKey.PublicKey()),
-		}
-
-		sshRoutePayload, err := json.Marshal(sshRoute)
-		Expect(err).NotTo(HaveOccurred())
-
-		sshRouteMessage := json.RawMessage(sshRoutePayload)
-
-		envVars := []*models.EnvironmentVariable{
-			{Name: "TEST", Value: "foobar"},
-		}
-
-		lrp = models.DesiredLRP{
+		lrp = *helpers.SSHEnabledLRP(componentMaker.Addresses(), componentMaker.SSHConfig(), &models.DesiredLRP{
 			LogGuid:     processGuid,
 			ProcessGuid: processGuid,
 			Domain:      "inigo",
 			Instances:   2,
 			Privileged:  true,
-			Setup: models.WrapAction(models.Serial(
-				&models.DownloadAction{
-					Artifact: "sshd",
-					From:     fmt.Sprintf("http://%s/v1/static/%s", componentMaker.Addresses().FileServer, "sshd.tgz"),
-					To:       "/tmp/diego",
-					CacheKey: "sshd",
-					User:     "root",
-				},
-				&models.DownloadAction{
-					Artifact: "go-server",
-					From:     fmt.Sprintf("http://%s/v1/static/%s", componentMaker.Addresses().FileServer, "lrp.zip"),
-					To:       "/tmp/diego",
-					CacheKey: "lrp-cache-key",
-					User:     "root",
-				},
-			)),
-			Action: models.WrapAction(models.Codependent(
-				&models.RunAction{
-					User: "root",
-					Path: "/tmp/diego/sshd",
-					Args: []string{
-						"-address=0.0.0.0:3456",
-						"-hostKey=" + componentMaker.SSHConfig().HostKeyPem,
-						"-authorizedKey=" + componentMaker.SSHConfig().AuthorizedKey,
-						"-inheritDaemonEnv",
-						"-logLevel=debug",
-					},
-				},
-				&models.RunAction{
-					User: "root",
-					Path: "/tmp/diego/go-server",
-					Env:  []*models.EnvironmentVariable{{"PORT", "9999"}},
-				},
-			)),
+			Setup: models.WrapAction(&models.DownloadAction{
+				Artifact: "go-server",
+				From:     fmt.Sprintf("http://%s/v1/static/%s", componentMaker.Addresses().FileServer, "lrp.zip"),
+				To:       "/tmp/diego",
+				CacheKey: "lrp-cache-key",
+				User:     "root",
+			}),
+			Action: models.WrapAction(&models.RunAction{
+				User: "root",
+				Path: "/tmp/diego/go-server",
+				Env:  []*models.EnvironmentVariable{{"PORT", "9999"}},
+			}),
 			Monitor: models.WrapAction(&models.RunAction{
 				User: "root",
 				Path: "nc",
-				Args: []string{"-z", "127.0.0.1", "3456"},
+				Args: []string{"-z", "127.0.0.1", strconv.Itoa(helpers.SSHDPort)},
 			}),
 			StartTimeoutMs: 60000,
 			RootFs:         "preloaded:" + world.PreloadedStacks[0],
 			MemoryMb:       128,
 			DiskMb:         128,
-			Ports:          []uint32{3456},
-			Routes: &models.Routes{
-				routes.DIEGO_SSH: &sshRouteMessage,
+			EnvironmentVariables: []*models.EnvironmentVariable{
+				{Name: "TEST", Value: "foobar"},
 			},
-			EnvironmentVariables: envVars,
-		}
+		})
 	})
 
 	JustBeforeEach(func() {
@@ -193,14 +143,9 @@ var _ = Describe("SSH", func() {
 		})
 
 		It("supports local port fowarding", func() {
-			clientConfig := &ssh.ClientConfig{
-				User:            fmt.Sprintf("diego:%s/%d", processGuid, 0),
-				Auth:            []ssh.AuthMethod{ssh.Password("")},
-				HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-			}
-
-			client, err := ssh.Dial("tcp", address, clientConfig)
+			client, err := helpers.DialSSHProxy(address, helpers.DiegoSSHUser(processGuid, 0))
 			Expect(err).NotTo(HaveOccurred())
+			defer client.Close()
 
 			httpClient := &http.Client{
 				Transport: &http.Transport{
@@ -219,6 +164,105 @@ var _ = Describe("SSH", func() {
 			Expect(contents).To(ContainSubstring("sup dawg"))
 		})
 
+		It("supports interactive sessions that follow window changes", func() {
+			client, err := helpers.DialSSHProxy(address, helpers.DiegoSSHUser(processGuid, 0))
+			Expect(err).NotTo(HaveOccurred())
+			defer client.Close()
+
+			shell, err := client.Shell("xterm", 80, 24)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(shell.WindowChange(132, 43)).To(Succeed())
+
+			_, err = shell.Stdin.Write([]byte("stty size; exit\n"))
+			Expect(err).NotTo(HaveOccurred())
+
+			output, err := ioutil.ReadAll(shell.Stdout)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(output)).To(ContainSubstring("43 132"))
+
+			Expect(shell.Wait()).To(Succeed())
+		})
+
+		It("supports local port forwarding through a listener", func() {
+			client, err := helpers.DialSSHProxy(address, helpers.DiegoSSHUser(processGuid, 0))
+			Expect(err).NotTo(HaveOccurred())
+			defer client.Close()
+
+			listener, err := client.LocalForward("127.0.0.1:0", "127.0.0.1:9999")
+			Expect(err).NotTo(HaveOccurred())
+			defer listener.Close()
+
+			resp, err := http.Get(fmt.Sprintf("http://%s/yo", listener.Addr()))
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+
+			contents, err := ioutil.ReadAll(resp.Body)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(ContainSubstring("sup dawg"))
+		})
+
+		It("supports remote port forwarding", func() {
+			client, err := helpers.DialSSHProxy(address, helpers.DiegoSSHUser(processGuid, 0))
+			Expect(err).NotTo(HaveOccurred())
+			defer client.Close()
+
+			local, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			defer local.Close()
+
+			go func() {
+				defer GinkgoRecover()
+				conn, err := local.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				conn.Write([]byte("hello from the test\n"))
+			}()
+
+			listener, err := client.RemoteForward("127.0.0.1:7777", local.Addr().String())
+			Expect(err).NotTo(HaveOccurred())
+			defer listener.Close()
+
+			output, _, exitStatus, err := client.Exec("nc 127.0.0.1 7777 </dev/null")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exitStatus).To(Equal(0))
+			Expect(string(output)).To(Equal("hello from the test\n"))
+		})
+
+		It("supports copying files with scp", func() {
+			client, err := helpers.DialSSHProxy(address, helpers.DiegoSSHUser(processGuid, 0))
+			Expect(err).NotTo(HaveOccurred())
+			defer client.Close()
+
+			Expect(client.SCPUpload([]byte("scp contents"), "/tmp/scp-file", 0644)).To(Succeed())
+
+			contents, err := client.SCPDownload("/tmp/scp-file")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("scp contents"))
+		})
+
+		It("supports copying files with sftp", func() {
+			client, err := helpers.DialSSHProxy(address, helpers.DiegoSSHUser(processGuid, 0))
+			Expect(err).NotTo(HaveOccurred())
+			defer client.Close()
+
+			sftpClient, err := client.SFTP()
+			Expect(err).NotTo(HaveOccurred())
+			defer sftpClient.Close()
+
+			file, err := sftpClient.Create("/tmp/sftp-file")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = file.Write([]byte("sftp contents"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(file.Close()).To(Succeed())
+
+			output, _, _, err := client.Exec("cat /tmp/sftp-file")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(output)).To(Equal("sftp contents"))
+		})
+
 		Context("when invalid password is used", func() {
 			var clientConfig *ssh.ClientConfig
 
@@ -250,7 +294,7 @@ var _ = Describe("SSH", func() {
 					Path: "sh",
 					Args: []string{
 						"-c",
-						"echo -n '' | telnet localhost 3456 >/dev/null 2>&1 && echo -n '' | telnet localhost 9999 >/dev/null 2>&1 && true",
+						"echo -n '' | telnet localhost " + strconv.Itoa(helpers.SSHDPort) + " >/dev/null 2>&1 && echo -n '' | telnet localhost 9999 >/dev/null 2>&1 && true",
 					},
 				})
 			})
@@ -261,14 +305,9 @@ var _ = Describe("SSH", func() {
 			})
 
 			It("supports local port fowarding", func() {
-				clientConfig := &ssh.ClientConfig{
-					User:            fmt.Sprintf("diego:%s/%d", processGuid, 0),
-					Auth:            []ssh.AuthMethod{ssh.Password("")},
-					HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-				}
-
-				client, err := ssh.Dial("tcp", address, clientConfig)
+				client, err := helpers.DialSSHProxy(address, helpers.DiegoSSHUser(processGuid, 0))
 				Expect(err).NotTo(HaveOccurred())
+				defer client.Close()
 
 				httpClient := &http.Client{
 					Transport: &http.Transport{
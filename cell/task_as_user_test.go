@@ -10,12 +10,13 @@ import (
 
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/world"
 	repconfig "code.cloudfoundry.org/rep/cmd/rep/config"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
-var _ = Describe("Tasks as specific user", func() {
+var _ = world.DescribeForEachDBDriver("Tasks as specific user", func() {
 	var cellProcess ifrit.Process
 
 	BeforeEach(func() {
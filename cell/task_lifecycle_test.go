@@ -10,7 +10,7 @@ import (
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/durationjson"
 	"code.cloudfoundry.org/inigo/helpers"
-	"code.cloudfoundry.org/inigo/inigo_announcement_server"
+	"code.cloudfoundry.org/inigo/world"
 
 	repconfig "code.cloudfoundry.org/rep/cmd/rep/config"
 	. "github.com/onsi/ginkgo"
@@ -28,7 +28,7 @@ func overrideExpirePendingTaskDuration(config *bbsconfig.BBSConfig) {
 	config.ExpirePendingTaskDuration = durationjson.Duration(time.Second)
 }
 
-var _ = Describe("Task Lifecycle", func() {
+var _ = world.DescribeForEachDBDriver("Task Lifecycle", func() {
 	var (
 		auctioneerProcess ifrit.Process
 		cellProcess       ifrit.Process
@@ -71,7 +71,7 @@ var _ = Describe("Task Lifecycle", func() {
 				taskSleepSeconds = 5
 				taskGuid = helpers.GenerateGuid()
 
-				taskToCreate = helpers.TaskCreateRequestWithMemory(
+				taskToCreate = helpers.NewTask(
 					taskGuid,
 					&models.RunAction{
 						User: "vcap",
@@ -92,10 +92,10 @@ var _ = Describe("Task Lifecycle", func() {
 
 								child=$!
 								wait $child
-							`, inigo_announcement_server.AnnounceURL(taskGuid), taskSleepSeconds),
+							`, announcementServer.AnnounceURL(taskGuid), taskSleepSeconds),
 						},
 					},
-					512,
+					helpers.TaskMemoryMB(512),
 				)
 			})
 
@@ -123,19 +123,19 @@ var _ = Describe("Task Lifecycle", func() {
 
 			Context("when there is a matching rootfs", func() {
 				It("eventually runs the Task", func() {
-					Eventually(inigo_announcement_server.Announcements).Should(ContainElement(taskGuid))
+					Eventually(announcementServer.Names).Should(ContainElement(taskGuid))
 				})
 			})
 
 			Context("when there is no matching rootfs", func() {
 				BeforeEach(func() {
-					taskToCreate = helpers.TaskCreateRequestWithRootFS(
+					taskToCreate = helpers.NewTask(
 						taskGuid,
-						helpers.BogusPreloadedRootFS,
 						&models.RunAction{
 							User: "vcap",
 							Path: "true",
 						},
+						helpers.TaskRootFS(helpers.BogusPreloadedRootFS),
 					)
 				})
 
@@ -146,7 +146,7 @@ var _ = Describe("Task Lifecycle", func() {
 
 			Context("when there is not enough resources", func() {
 				BeforeEach(func() {
-					taskToCreate = helpers.TaskCreateRequestWithMemory(
+					taskToCreate = helpers.NewTask(
 						taskGuid,
 						&models.RunAction{
 							User: "vcap",
@@ -154,10 +154,10 @@ var _ = Describe("Task Lifecycle", func() {
 							Args: []string{
 								"-c",
 								// sleep a bit so that we can make assertions around behavior as it's running
-								fmt.Sprintf("curl %s; sleep %d", inigo_announcement_server.AnnounceURL(taskGuid), taskSleepSeconds),
+								fmt.Sprintf("curl %s; sleep %d", announcementServer.AnnounceURL(taskGuid), taskSleepSeconds),
 							},
 						},
-						2048,
+						helpers.TaskMemoryMB(2048),
 					)
 				})
 
@@ -172,7 +172,7 @@ var _ = Describe("Task Lifecycle", func() {
 				})
 
 				JustBeforeEach(func() {
-					Eventually(inigo_announcement_server.Announcements).Should(ContainElement(taskGuid))
+					Eventually(announcementServer.Names).Should(ContainElement(taskGuid))
 
 					err := bbsClient.CancelTask(lgr, taskGuid)
 					Expect(err).NotTo(HaveOccurred())
@@ -200,7 +200,7 @@ var _ = Describe("Task Lifecycle", func() {
 
 				Context("after the task starts", func() {
 					JustBeforeEach(func() {
-						Eventually(inigo_announcement_server.Announcements).Should(ContainElement(taskGuid))
+						Eventually(announcementServer.Names).Should(ContainElement(taskGuid))
 					})
 
 					Context("when the cellProcess disappears", func() {
@@ -235,7 +235,7 @@ var _ = Describe("Task Lifecycle", func() {
 
 			BeforeEach(func() {
 				taskGuid = helpers.GenerateGuid()
-				taskToCreate = helpers.TaskCreateRequest(
+				taskToCreate = helpers.NewTask(
 					taskGuid,
 					&models.RunAction{
 						User: "vcap",
@@ -249,8 +249,8 @@ exit 0
 					`,
 						},
 					},
+					helpers.TaskResultFile("/tmp/result"),
 				)
-				taskToCreate.ResultFile = "/tmp/result"
 			})
 
 			JustBeforeEach(func() {
@@ -316,7 +316,7 @@ exit 0
 					&models.RunAction{
 						User: "vcap",
 						Path: "curl",
-						Args: []string{inigo_announcement_server.AnnounceURL(taskGuid)},
+						Args: []string{announcementServer.AnnounceURL(taskGuid)},
 					},
 				)
 				err := bbsClient.DesireTask(lgr, taskToDesire.TaskGuid, taskToDesire.Domain, taskToDesire.TaskDefinition)
@@ -333,7 +333,7 @@ exit 0
 				})
 
 				It("eventually runs the Task", func() {
-					Eventually(inigo_announcement_server.Announcements).Should(ContainElement(taskGuid))
+					Eventually(announcementServer.Names).Should(ContainElement(taskGuid))
 				})
 			})
 		})
@@ -360,7 +360,7 @@ exit 0
 					&models.RunAction{
 						User: "vcap",
 						Path: "curl",
-						Args: []string{inigo_announcement_server.AnnounceURL(taskGuid)},
+						Args: []string{announcementServer.AnnounceURL(taskGuid)},
 					},
 				)
 
@@ -382,7 +382,7 @@ exit 0
 				Expect(completedTask.Failed).To(BeTrue())
 				Expect(completedTask.FailureReason).To(ContainSubstring("not started within time limit"))
 
-				Expect(inigo_announcement_server.Announcements()).To(BeEmpty())
+				Expect(announcementServer.Names()).To(BeEmpty())
 			})
 		})
 	})
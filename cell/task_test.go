@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -18,14 +17,13 @@ import (
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/garden"
 	"code.cloudfoundry.org/inigo/helpers"
-	"code.cloudfoundry.org/inigo/inigo_announcement_server"
+	"code.cloudfoundry.org/inigo/world"
 	repconfig "code.cloudfoundry.org/rep/cmd/rep/config"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
-	"github.com/onsi/gomega/ghttp"
 )
 
-var _ = Describe("Tasks", func() {
+var _ = world.DescribeForEachDBDriver("Tasks", func() {
 	var (
 		cellProcess ifrit.Process
 	)
@@ -62,7 +60,7 @@ var _ = Describe("Tasks", func() {
 		})
 
 		It("runs the command with the provided environment", func() {
-			expectedTask := helpers.TaskCreateRequest(
+			expectedTask := helpers.NewTask(
 				guid,
 				&models.RunAction{
 					User: "vcap",
@@ -74,9 +72,10 @@ var _ = Describe("Tasks", func() {
 						{"FOO", "NEW-BAR"},
 					},
 				},
+				helpers.TaskPrivileged(),
 			)
-			expectedTask.Privileged = true
 
+			desiredAt := time.Now()
 			err := bbsClient.DesireTask(lgr, expectedTask.TaskGuid, expectedTask.Domain, expectedTask.TaskDefinition)
 			Expect(err).NotTo(HaveOccurred())
 
@@ -90,12 +89,13 @@ var _ = Describe("Tasks", func() {
 
 				return task.State
 			}).Should(Equal(models.Task_Completed))
+			durations.Since("task-time-to-completed", desiredAt)
 
 			Expect(task.Failed).To(BeFalse())
 		})
 
 		It("runs the command with the provided working directory", func() {
-			expectedTask := helpers.TaskCreateRequest(
+			expectedTask := helpers.NewTask(
 				guid,
 				&models.RunAction{
 					User: "vcap",
@@ -103,8 +103,8 @@ var _ = Describe("Tasks", func() {
 					Args: []string{"-c", `[ $PWD = /tmp ]`},
 					Dir:  "/tmp",
 				},
+				helpers.TaskPrivileged(),
 			)
-			expectedTask.Privileged = true
 
 			err := bbsClient.DesireTask(lgr, expectedTask.TaskGuid, expectedTask.Domain, expectedTask.TaskDefinition)
 
@@ -142,30 +142,28 @@ var _ = Describe("Tasks", func() {
 			})
 
 			It("fetches the metadata", func() {
-				expectedTask := helpers.TaskCreateRequest(
+				expectedTask := helpers.NewTask(
 					guid,
 					&models.RunAction{
 						User: "vcap",
 						Path: "/tmp/diego/dockerapplifecycle/builder",
 						Args: []string{"--dockerRef", privateRef, "--dockerUser", privateUser, "--dockerPassword", privatePassword, "--outputMetadataJSONFilename", "/tmp/result.json"},
 					},
-				)
-				expectedTask.CachedDependencies = []*models.CachedDependency{{
-					From:      fmt.Sprintf("http://%s/v1/static/docker_app_lifecycle/docker_app_lifecycle.tgz", componentMaker.Addresses().FileServer),
-					To:        "/tmp/diego/dockerapplifecycle",
-					Name:      "docker app lifecycle",
-					CacheKey:  "docker-app-lifecycle",
-					LogSource: "docker-app-lifecycle",
-				}}
-				expectedTask.Privileged = true
-				expectedTask.ResultFile = "/tmp/result.json"
-				expectedTask.EgressRules = []*models.SecurityGroupRule{
-					{
-						// allow traffic to the docker registry
+					helpers.TaskCachedDependencies(&models.CachedDependency{
+						From:      fmt.Sprintf("http://%s/v1/static/docker_app_lifecycle/docker_app_lifecycle.tgz", componentMaker.Addresses().FileServer),
+						To:        "/tmp/diego/dockerapplifecycle",
+						Name:      "docker app lifecycle",
+						CacheKey:  "docker-app-lifecycle",
+						LogSource: "docker-app-lifecycle",
+					}),
+					helpers.TaskPrivileged(),
+					helpers.TaskResultFile("/tmp/result.json"),
+					// allow traffic to the docker registry
+					helpers.TaskEgressRules(&models.SecurityGroupRule{
 						Protocol:     models.AllProtocol,
 						Destinations: []string{"0.0.0.0/0"},
-					},
-				}
+					}),
+				)
 
 				err := bbsClient.DesireTask(lgr, expectedTask.TaskGuid, expectedTask.Domain, expectedTask.TaskDefinition)
 				Expect(err).NotTo(HaveOccurred())
@@ -187,7 +185,7 @@ var _ = Describe("Tasks", func() {
 			})
 
 			It("eventually runs", func() {
-				expectedTask := helpers.TaskCreateRequest(
+				expectedTask := helpers.NewTask(
 					guid,
 					&models.RunAction{
 						User: "vcap",
@@ -199,11 +197,10 @@ var _ = Describe("Tasks", func() {
 							{"FOO", "NEW-BAR"},
 						},
 					},
+					helpers.TaskPrivileged(),
+					helpers.TaskRootFS(privateDockerRootFSPath),
+					helpers.TaskImageCredentials(privateUser, privatePassword),
 				)
-				expectedTask.Privileged = true
-				expectedTask.RootFs = privateDockerRootFSPath
-				expectedTask.ImageUsername = privateUser
-				expectedTask.ImagePassword = privatePassword
 
 				err := bbsClient.DesireTask(lgr, expectedTask.TaskGuid, expectedTask.Domain, expectedTask.TaskDefinition)
 				Expect(err).NotTo(HaveOccurred())
@@ -241,30 +238,28 @@ var _ = Describe("Tasks", func() {
 			})
 
 			It("fetches the metadata", func() {
-				expectedTask := helpers.TaskCreateRequest(
+				expectedTask := helpers.NewTask(
 					guid,
 					&models.RunAction{
 						User: "vcap",
 						Path: "/tmp/diego/dockerapplifecycle/builder",
 						Args: []string{"--dockerRef", imageRef, "--dockerUser", imageUsername, "--dockerPassword", imagePassword, "--outputMetadataJSONFilename", "/tmp/result.json"},
 					},
-				)
-				expectedTask.CachedDependencies = []*models.CachedDependency{{
-					From:      fmt.Sprintf("http://%s/v1/static/docker_app_lifecycle/docker_app_lifecycle.tgz", componentMaker.Addresses().FileServer),
-					To:        "/tmp/diego/dockerapplifecycle",
-					Name:      "docker app lifecycle",
-					CacheKey:  "docker-app-lifecycle",
-					LogSource: "docker-app-lifecycle",
-				}}
-				expectedTask.Privileged = true
-				expectedTask.ResultFile = "/tmp/result.json"
-				expectedTask.EgressRules = []*models.SecurityGroupRule{
-					{
-						// allow traffic to the docker registry
+					helpers.TaskCachedDependencies(&models.CachedDependency{
+						From:      fmt.Sprintf("http://%s/v1/static/docker_app_lifecycle/docker_app_lifecycle.tgz", componentMaker.Addresses().FileServer),
+						To:        "/tmp/diego/dockerapplifecycle",
+						Name:      "docker app lifecycle",
+						CacheKey:  "docker-app-lifecycle",
+						LogSource: "docker-app-lifecycle",
+					}),
+					helpers.TaskPrivileged(),
+					helpers.TaskResultFile("/tmp/result.json"),
+					// allow traffic to the docker registry
+					helpers.TaskEgressRules(&models.SecurityGroupRule{
 						Protocol:     models.AllProtocol,
 						Destinations: []string{"0.0.0.0/0"},
-					},
-				}
+					}),
+				)
 
 				err := bbsClient.DesireTask(lgr, expectedTask.TaskGuid, expectedTask.Domain, expectedTask.TaskDefinition)
 				Expect(err).NotTo(HaveOccurred())
@@ -286,7 +281,7 @@ var _ = Describe("Tasks", func() {
 			})
 
 			It("eventually runs", func() {
-				expectedTask := helpers.TaskCreateRequest(
+				expectedTask := helpers.NewTask(
 					guid,
 					&models.RunAction{
 						User: "vcap",
@@ -298,11 +293,10 @@ var _ = Describe("Tasks", func() {
 							{"FOO", "NEW-BAR"},
 						},
 					},
+					helpers.TaskPrivileged(),
+					helpers.TaskRootFS(imageRootFSPath),
+					helpers.TaskImageCredentials(imageUsername, imagePassword),
 				)
-				expectedTask.Privileged = true
-				expectedTask.RootFs = imageRootFSPath
-				expectedTask.ImageUsername = imageUsername
-				expectedTask.ImagePassword = imagePassword
 
 				err := bbsClient.DesireTask(lgr, expectedTask.TaskGuid, expectedTask.Domain, expectedTask.TaskDefinition)
 				Expect(err).NotTo(HaveOccurred())
@@ -324,13 +318,13 @@ var _ = Describe("Tasks", func() {
 
 		Context("when the command exceeds its memory limit", func() {
 			It("should fail the Task", func() {
-				expectedTask := helpers.TaskCreateRequestWithMemoryAndDisk(
+				expectedTask := helpers.NewTask(
 					guid,
 					models.Serial(
 						&models.RunAction{
 							User: "vcap",
 							Path: "curl",
-							Args: []string{inigo_announcement_server.AnnounceURL("before-memory-overdose")},
+							Args: []string{announcementServer.AnnounceURL("before-memory-overdose")},
 						},
 						&models.RunAction{
 							User: "vcap",
@@ -340,18 +334,18 @@ var _ = Describe("Tasks", func() {
 						&models.RunAction{
 							User: "vcap",
 							Path: "curl",
-							Args: []string{inigo_announcement_server.AnnounceURL("after-memory-overdose")},
+							Args: []string{announcementServer.AnnounceURL("after-memory-overdose")},
 						},
 					),
-					10,
-					1024,
+					helpers.TaskMemoryMB(10),
+					helpers.TaskDiskMB(1024),
 				)
 
 				err := bbsClient.DesireTask(lgr, expectedTask.TaskGuid, expectedTask.Domain, expectedTask.TaskDefinition)
 
 				Expect(err).NotTo(HaveOccurred())
 
-				Eventually(inigo_announcement_server.Announcements).Should(ContainElement("before-memory-overdose"))
+				Eventually(announcementServer.Names).Should(ContainElement("before-memory-overdose"))
 
 				var task *models.Task
 				Eventually(func() interface{} {
@@ -366,7 +360,7 @@ var _ = Describe("Tasks", func() {
 				Expect(task.Failed).To(BeTrue())
 				Expect(task.FailureReason).To(ContainSubstring("out of memory"))
 
-				Expect(inigo_announcement_server.Announcements()).NotTo(ContainElement("after-memory-overdose"))
+				Expect(announcementServer.Names()).NotTo(ContainElement("after-memory-overdose"))
 			})
 		})
 
@@ -377,7 +371,7 @@ var _ = Describe("Tasks", func() {
 				rl := &models.ResourceLimits{}
 				rl.SetNofile(nofile)
 
-				expectedTask := helpers.TaskCreateRequest(
+				expectedTask := helpers.NewTask(
 					guid,
 					models.Serial(
 						&models.RunAction{
@@ -427,7 +421,7 @@ echo should have died by now
 
 		Context("when the command times out", func() {
 			It("should fail the Task", func() {
-				expectedTask := helpers.TaskCreateRequest(
+				expectedTask := helpers.NewTask(
 					guid,
 					models.Serial(
 						models.Timeout(
@@ -477,7 +471,7 @@ echo should have died by now
 
 		Context("when properties are present on the task definition", func() {
 			It("propagates them to the garden container", func() {
-				expectedTask := helpers.TaskCreateRequest(
+				expectedTask := helpers.NewTask(
 					guid,
 					&models.RunAction{
 						User: "vcap",
@@ -499,12 +493,10 @@ echo should have died by now
 							`,
 						},
 					},
-				)
-				expectedTask.Network = &models.Network{
-					Properties: map[string]string{
+					helpers.TaskNetworkProperties(map[string]string{
 						"some-key": "some-value",
-					},
-				}
+					}),
+				)
 
 				err := bbsClient.DesireTask(lgr, expectedTask.TaskGuid, expectedTask.Domain, expectedTask.TaskDefinition)
 				Expect(err).NotTo(HaveOccurred())
@@ -532,7 +524,7 @@ echo should have died by now
 			test_helper.CreateTarGZArchive(filepath.Join(fileServerStaticDir, "announce.tar.gz"), []test_helper.ArchiveFile{
 				{
 					Name: "announce",
-					Body: fmt.Sprintf("#!/bin/sh\n\ncurl %s", inigo_announcement_server.AnnounceURL(guid)),
+					Body: fmt.Sprintf("#!/bin/sh\n\ncurl %s", announcementServer.AnnounceURL(guid)),
 					Mode: 0755,
 				},
 			})
@@ -550,7 +542,7 @@ echo should have died by now
 					User: "vcap",
 				}
 
-				expectedTask = helpers.TaskCreateRequest(
+				expectedTask = helpers.NewTask(
 					guid,
 					models.Serial(
 						downloadAction,
@@ -566,7 +558,7 @@ echo should have died by now
 				It("downloads the file", func() {
 					err := bbsClient.DesireTask(lgr, expectedTask.TaskGuid, expectedTask.Domain, expectedTask.TaskDefinition)
 					Expect(err).NotTo(HaveOccurred())
-					Eventually(inigo_announcement_server.Announcements).Should(ContainElement(guid))
+					Eventually(announcementServer.Names).Should(ContainElement(guid))
 				})
 			})
 
@@ -580,7 +572,7 @@ echo should have died by now
 						test_helper.CreateTarGZArchive(archiveFilePath, []test_helper.ArchiveFile{
 							{
 								Name: "announce",
-								Body: fmt.Sprintf("#!/bin/sh\n\ncurl %s", inigo_announcement_server.AnnounceURL(guid)),
+								Body: fmt.Sprintf("#!/bin/sh\n\ncurl %s", announcementServer.AnnounceURL(guid)),
 								Mode: 0755,
 							},
 						})
@@ -597,21 +589,21 @@ echo should have died by now
 						createChecksum("md5")
 						err := bbsClient.DesireTask(lgr, expectedTask.TaskGuid, expectedTask.Domain, expectedTask.TaskDefinition)
 						Expect(err).NotTo(HaveOccurred())
-						Eventually(inigo_announcement_server.Announcements).Should(ContainElement(guid))
+						Eventually(announcementServer.Names).Should(ContainElement(guid))
 					})
 
 					It("downloads the file for sha1", func() {
 						createChecksum("sha1")
 						err := bbsClient.DesireTask(lgr, expectedTask.TaskGuid, expectedTask.Domain, expectedTask.TaskDefinition)
 						Expect(err).NotTo(HaveOccurred())
-						Eventually(inigo_announcement_server.Announcements).Should(ContainElement(guid))
+						Eventually(announcementServer.Names).Should(ContainElement(guid))
 					})
 
 					It("downloads the file for sha256", func() {
 						createChecksum("sha256")
 						err := bbsClient.DesireTask(lgr, expectedTask.TaskGuid, expectedTask.Domain, expectedTask.TaskDefinition)
 						Expect(err).NotTo(HaveOccurred())
-						Eventually(inigo_announcement_server.Announcements).Should(ContainElement(guid))
+						Eventually(announcementServer.Names).Should(ContainElement(guid))
 					})
 				})
 
@@ -667,26 +659,22 @@ echo should have died by now
 					LogSource: "announce-tar",
 				}
 
-				expectedTask = helpers.TaskCreateRequest(
+				expectedTask = helpers.NewTask(
 					guid,
 					&models.RunAction{
 						User: "vcap",
 						Path: "./app/announce",
 					},
+					helpers.TaskCachedDependencies(cachedDependency),
+					helpers.TaskPrivileged(),
 				)
-
-				expectedTask.CachedDependencies = []*models.CachedDependency{
-					cachedDependency,
-				}
-
-				expectedTask.Privileged = true
 			})
 
 			Context("with no checksum", func() {
 				It("downloads the file", func() {
 					err := bbsClient.DesireTask(lgr, expectedTask.TaskGuid, expectedTask.Domain, expectedTask.TaskDefinition)
 					Expect(err).NotTo(HaveOccurred())
-					Eventually(inigo_announcement_server.Announcements).Should(ContainElement(expectedTask.TaskGuid))
+					Eventually(announcementServer.Names).Should(ContainElement(expectedTask.TaskGuid))
 				})
 			})
 
@@ -700,7 +688,7 @@ echo should have died by now
 						test_helper.CreateTarGZArchive(archiveFilePath, []test_helper.ArchiveFile{
 							{
 								Name: "announce",
-								Body: fmt.Sprintf("#!/bin/sh\n\ncurl %s", inigo_announcement_server.AnnounceURL(guid)),
+								Body: fmt.Sprintf("#!/bin/sh\n\ncurl %s", announcementServer.AnnounceURL(guid)),
 								Mode: 0755,
 							},
 						})
@@ -718,7 +706,7 @@ echo should have died by now
 						err := bbsClient.DesireTask(lgr, expectedTask.TaskGuid, expectedTask.Domain, expectedTask.TaskDefinition)
 						Expect(err).NotTo(HaveOccurred())
 						expectedGuid := expectedTask.TaskGuid
-						Eventually(inigo_announcement_server.Announcements).Should(ContainElement(expectedGuid))
+						Eventually(announcementServer.Names).Should(ContainElement(expectedGuid))
 					})
 
 					It("downloads the file for sha1", func() {
@@ -726,7 +714,7 @@ echo should have died by now
 						err := bbsClient.DesireTask(lgr, expectedTask.TaskGuid, expectedTask.Domain, expectedTask.TaskDefinition)
 						Expect(err).NotTo(HaveOccurred())
 						expectedGuid := expectedTask.TaskGuid
-						Eventually(inigo_announcement_server.Announcements).Should(ContainElement(expectedGuid))
+						Eventually(announcementServer.Names).Should(ContainElement(expectedGuid))
 					})
 
 					It("downloads the file for sha256", func() {
@@ -734,7 +722,7 @@ echo should have died by now
 						err := bbsClient.DesireTask(lgr, expectedTask.TaskGuid, expectedTask.Domain, expectedTask.TaskDefinition)
 						Expect(err).NotTo(HaveOccurred())
 						expectedGuid := expectedTask.TaskGuid
-						Eventually(inigo_announcement_server.Announcements).Should(ContainElement(expectedGuid))
+						Eventually(announcementServer.Names).Should(ContainElement(expectedGuid))
 					})
 				})
 
@@ -778,37 +766,21 @@ echo should have died by now
 	})
 
 	Describe("Uploading from the container", func() {
-		var guid string
-
-		var server *httptest.Server
-		var uploadAddr string
-
-		var gotRequest chan struct{}
+		var (
+			guid       string
+			sink       *helpers.UploadSink
+			sinkConfig []func(*helpers.UploadSinkConfig)
+		)
 
 		BeforeEach(func() {
 			guid = helpers.GenerateGuid()
-
-			gotRequest = make(chan struct{})
-
-			server, uploadAddr = helpers.Callback(os.Getenv("EXTERNAL_ADDRESS"), ghttp.CombineHandlers(
-				ghttp.VerifyRequest("POST", "/thingy"),
-				func(w http.ResponseWriter, r *http.Request) {
-					contents, err := ioutil.ReadAll(r.Body)
-					Expect(err).NotTo(HaveOccurred())
-
-					Expect(string(contents)).To(Equal("tasty thingy\n"))
-
-					close(gotRequest)
-				},
-			))
+			sinkConfig = nil
 		})
 
-		AfterEach(func() {
-			server.Close()
-		})
+		JustBeforeEach(func() {
+			sink = helpers.NewUploadSink(os.Getenv("EXTERNAL_ADDRESS"), sinkConfig...)
 
-		It("uploads the specified files", func() {
-			expectedTask := helpers.TaskCreateRequest(
+			expectedTask := helpers.NewTask(
 				guid,
 				models.Serial(
 					&models.RunAction{
@@ -818,23 +790,195 @@ echo should have died by now
 					},
 					&models.UploadAction{
 						From: "thingy",
-						To:   fmt.Sprintf("http://%s/thingy", uploadAddr),
+						To:   sink.URL("thingy"),
 						User: "vcap",
 					},
 					&models.RunAction{
 						User: "vcap",
 						Path: "curl",
-						Args: []string{inigo_announcement_server.AnnounceURL(guid)},
+						Args: []string{announcementServer.AnnounceURL(guid)},
 					},
 				),
 			)
 
 			err := bbsClient.DesireTask(lgr, expectedTask.TaskGuid, expectedTask.Domain, expectedTask.TaskDefinition)
 			Expect(err).NotTo(HaveOccurred())
+		})
 
-			Eventually(gotRequest).Should(BeClosed())
+		AfterEach(func() {
+			sink.Close()
+		})
+
+		It("uploads the specified files", func() {
+			Eventually(sink.SuccessfulUploads).Should(HaveLen(1))
+
+			upload := sink.SuccessfulUploads()[0]
+			Expect(upload.Method).To(Equal("POST"))
+			Expect(upload.Path).To(Equal("/thingy"))
+			Expect(string(upload.Body)).To(Equal("tasty thingy\n"))
+			Expect(upload.ContentLength).To(BeEquivalentTo(len("tasty thingy\n")))
+			Expect(upload.ContentMD5Matches()).To(BeTrue())
+
+			Eventually(announcementServer.Names).Should(ContainElement(guid))
+		})
 
-			Eventually(inigo_announcement_server.Announcements).Should(ContainElement(expectedTask.TaskGuid))
+		Context("when the upload destination fails transiently", func() {
+			BeforeEach(func() {
+				sinkConfig = append(sinkConfig, func(config *helpers.UploadSinkConfig) {
+					config.FailuresBeforeSuccess = 1
+					config.FailureStatusCode = http.StatusServiceUnavailable
+				})
+			})
+
+			It("retries the upload until it succeeds", func() {
+				Eventually(sink.SuccessfulUploads).Should(HaveLen(1))
+
+				uploads := sink.Uploads()
+				Expect(uploads).To(HaveLen(2))
+				Expect(uploads[0].StatusCode).To(Equal(http.StatusServiceUnavailable))
+				Expect(uploads[1].Body).To(Equal(uploads[0].Body))
+
+				Eventually(announcementServer.Names).Should(ContainElement(guid))
+			})
+		})
+
+		Context("when the upload destination resets the connection", func() {
+			BeforeEach(func() {
+				sinkConfig = append(sinkConfig, func(config *helpers.UploadSinkConfig) {
+					config.FailuresBeforeSuccess = 1
+					config.FailureMode = helpers.UploadFailureReset
+				})
+			})
+
+			It("retries the upload until it succeeds", func() {
+				Eventually(sink.SuccessfulUploads).Should(HaveLen(1))
+				Expect(sink.Uploads()[0].StatusCode).To(BeZero())
+
+				Eventually(announcementServer.Names).Should(ContainElement(guid))
+			})
+		})
+
+		Context("when the upload destination is slow to read the upload", func() {
+			BeforeEach(func() {
+				sinkConfig = append(sinkConfig, func(config *helpers.UploadSinkConfig) {
+					config.ReadDelay = 2 * time.Second
+				})
+			})
+
+			It("waits for the upload to complete", func() {
+				Eventually(sink.SuccessfulUploads).Should(HaveLen(1))
+				Eventually(announcementServer.Names).Should(ContainElement(guid))
+			})
+		})
+
+		Context("when the upload destination always fails", func() {
+			BeforeEach(func() {
+				sinkConfig = append(sinkConfig, func(config *helpers.UploadSinkConfig) {
+					config.FailuresBeforeSuccess = -1
+				})
+			})
+
+			It("fails the task without running the subsequent actions", func() {
+				var task models.Task
+				Eventually(helpers.TaskStatePoller(lgr, bbsClient, guid, &task)).Should(Equal(models.Task_Completed))
+				Expect(task.Failed).To(BeTrue())
+
+				Expect(len(sink.Uploads())).To(BeNumerically(">", 1))
+				Expect(sink.SuccessfulUploads()).To(BeEmpty())
+				Expect(announcementServer.Names()).NotTo(ContainElement(guid))
+			})
+		})
+	})
+
+	Describe("Completion callbacks", func() {
+		var (
+			guid           string
+			receiver       *helpers.CompletionCallbackReceiver
+			receiverConfig []func(*helpers.CompletionCallbackReceiverConfig)
+		)
+
+		taskLookupError := func() models.Error_Type {
+			_, err := bbsClient.TaskByGuid(lgr, guid)
+			if err == nil {
+				return models.Error_UnknownError
+			}
+			return models.ConvertError(err).Type
+		}
+
+		BeforeEach(func() {
+			guid = helpers.GenerateGuid()
+			receiverConfig = nil
+		})
+
+		JustBeforeEach(func() {
+			receiver = helpers.NewCompletionCallbackReceiver(os.Getenv("EXTERNAL_ADDRESS"), receiverConfig...)
+
+			task := helpers.NewTask(
+				guid,
+				&models.RunAction{
+					User: "vcap",
+					Path: "sh",
+					Args: []string{"-c", "echo tasty thingy > thingy"},
+				},
+				helpers.TaskResultFile("/home/vcap/thingy"),
+				helpers.TaskCompletionCallbackURL(receiver.URL()),
+			)
+
+			err := bbsClient.DesireTask(lgr, task.TaskGuid, task.Domain, task.TaskDefinition)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			receiver.Close()
+		})
+
+		It("posts the task result to the callback and then deletes the task", func() {
+			Eventually(func() []helpers.CompletionCallback {
+				return receiver.CallbacksForTask(guid)
+			}).Should(HaveLen(1))
+
+			callback := receiver.CallbacksForTask(guid)[0]
+			Expect(callback.Response.Failed).To(BeFalse())
+			Expect(callback.Response.Result).To(Equal("tasty thingy\n"))
+
+			Eventually(taskLookupError).Should(Equal(models.Error_ResourceNotFound))
+		})
+
+		Context("when the callback fails transiently", func() {
+			BeforeEach(func() {
+				receiverConfig = append(receiverConfig, func(config *helpers.CompletionCallbackReceiverConfig) {
+					config.FailuresBeforeSuccess = 2
+				})
+			})
+
+			It("retries the callback until it succeeds and then deletes the task", func() {
+				Eventually(func() []helpers.CompletionCallback {
+					return receiver.CallbacksForTask(guid)
+				}).Should(HaveLen(3))
+
+				callbacks := receiver.CallbacksForTask(guid)
+				Expect(callbacks[0].StatusCode).To(Equal(http.StatusServiceUnavailable))
+				Expect(callbacks[1].StatusCode).To(Equal(http.StatusServiceUnavailable))
+				Expect(callbacks[2].StatusCode).To(Equal(http.StatusOK))
+
+				Eventually(taskLookupError).Should(Equal(models.Error_ResourceNotFound))
+			})
+		})
+
+		Context("when the callback hangs", func() {
+			BeforeEach(func() {
+				receiverConfig = append(receiverConfig, func(config *helpers.CompletionCallbackReceiverConfig) {
+					config.Hang = true
+				})
+			})
+
+			It("leaves the task resolving while the callback is outstanding", func() {
+				Eventually(func() []helpers.CompletionCallback {
+					return receiver.CallbacksForTask(guid)
+				}).ShouldNot(BeEmpty())
+
+				Eventually(helpers.TaskStatePoller(lgr, bbsClient, guid, nil)).Should(Equal(models.Task_Resolving))
+			})
 		})
 	})
 
@@ -842,15 +986,15 @@ echo should have died by now
 		It("should fetch the contents of the requested file and provide the content in the completed Task", func() {
 			guid := helpers.GenerateGuid()
 
-			expectedTask := helpers.TaskCreateRequest(
+			expectedTask := helpers.NewTask(
 				guid,
 				&models.RunAction{
 					User: "vcap",
 					Path: "sh",
 					Args: []string{"-c", "echo tasty thingy > thingy"},
 				},
+				helpers.TaskResultFile("/home/vcap/thingy"),
 			)
-			expectedTask.ResultFile = "/home/vcap/thingy"
 
 			err := bbsClient.DesireTask(lgr, expectedTask.TaskGuid, expectedTask.Domain, expectedTask.TaskDefinition)
 			Expect(err).NotTo(HaveOccurred())
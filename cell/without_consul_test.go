@@ -0,0 +1,79 @@
+package cell_test
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	archive_helper "code.cloudfoundry.org/archiver/extractor/test_helper"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/inigo/fixtures"
+	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/world"
+	repconfig "code.cloudfoundry.org/rep/cmd/rep/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+	"github.com/tedsuo/ifrit/grouper"
+)
+
+var _ = world.DescribeForEachDBDriver("Without Consul", func() {
+	var (
+		cellID   string
+		db       *sql.DB
+		runtimes ifrit.Process
+	)
+
+	BeforeEach(func() {
+		if runtime.GOOS == "windows" {
+			Skip(" not yet working on windows")
+		}
+
+		By("restarting the plumbing and the BBS without Consul")
+		helpers.StopProcesses(bbsProcess, plumbing)
+		componentMaker = world.WithoutConsul(componentMaker)
+		Expect(componentMaker.ConsulEnabled()).To(BeFalse())
+
+		plumbing = ginkgomon.Invoke(world.Plumbing(componentMaker, grouper.Member{Name: "nats", Runner: componentMaker.NATS()}))
+		bbsProcess = ginkgomon.Invoke(componentMaker.BBS())
+		bbsServiceClient = componentMaker.BBSServiceClient(lgr)
+
+		fileServer, fileServerStaticDir := componentMaker.FileServer()
+		archive_helper.CreateZipArchive(filepath.Join(fileServerStaticDir, "lrp.zip"), fixtures.GoServerApp())
+
+		runtimes = ginkgomon.Invoke(grouper.NewParallel(os.Kill, grouper.Members{
+			{"router", componentMaker.Router()},
+			{"file-server", fileServer},
+			{"rep", componentMaker.Rep(func(cfg *repconfig.RepConfig) {
+				cellID = cfg.CellID
+			})},
+			{"auctioneer", componentMaker.Auctioneer()},
+			{"route-emitter", componentMaker.RouteEmitter()},
+		}))
+
+		db = helpers.SQLConnection(lgr, componentMaker)
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).To(Succeed())
+		helpers.StopProcesses(runtimes)
+	})
+
+	It("registers the cell through locket", func() {
+		inspector := helpers.NewLocketInspector(lgr, componentMaker.LocketClient(lgr), db)
+		Eventually(inspector.PresenceKeys()).Should(ConsistOf(cellID))
+
+		Eventually(func() (models.CellSet, error) { return bbsServiceClient.Cells(lgr) }).Should(HaveKey(cellID))
+	})
+
+	It("runs an LRP and routes to it", func() {
+		processGuid := helpers.GenerateGuid()
+		lrp := helpers.NewLRP(componentMaker.Addresses(), processGuid)
+		Expect(bbsClient.DesireLRP(lgr, lrp)).To(Succeed())
+
+		Eventually(helpers.LRPStatePoller(lgr, bbsClient, processGuid, nil)).Should(Equal(models.ActualLRPStateRunning))
+		Eventually(helpers.HelloWorldInstancePoller(componentMaker.Addresses().Router, helpers.DefaultHost)).Should(ConsistOf([]string{"0"}))
+	})
+})
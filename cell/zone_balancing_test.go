@@ -0,0 +1,125 @@
+package cell_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	archive_helper "code.cloudfoundry.org/archiver/extractor/test_helper"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/inigo/fixtures"
+	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/world"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+	"github.com/tedsuo/ifrit/grouper"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = world.DescribeForEachDBDriver("Zone balancing", func() {
+	var (
+		processGuid string
+		reps        []world.ZonedRep
+		zoneA       ifrit.Process
+		zoneB       ifrit.Process
+		auctioneer  ifrit.Process
+		fileServer  ifrit.Process
+
+		distribution func() helpers.InstanceDistribution
+	)
+
+	startZone := func(zone string) ifrit.Process {
+		members := grouper.Members{}
+		for _, rep := range world.RepsInZone(reps, zone) {
+			members = append(members, grouper.Member{Name: rep.CellID, Runner: rep.Runner})
+		}
+		return ginkgomon.Invoke(grouper.NewParallel(os.Kill, members))
+	}
+
+	scaleTo := func(instances int32) {
+		update := &models.DesiredLRPUpdate{}
+		update.SetInstances(instances)
+		Expect(bbsClient.UpdateDesiredLRP(lgr, processGuid, update)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		if runtime.GOOS == "windows" {
+			Skip(" not yet working on windows")
+		}
+
+		processGuid = helpers.GenerateGuid()
+		distribution = helpers.InstanceDistributionPoller(lgr, bbsClient, processGuid)
+
+		By("restarting the bbs with smaller convergeRepeatInterval")
+		ginkgomon.Interrupt(bbsProcess)
+		bbsProcess = ginkgomon.Invoke(componentMaker.BBS(
+			overrideConvergenceRepeatInterval,
+		))
+
+		fileServerRunner, fileServerStaticDir := componentMaker.FileServer()
+		fileServer = ginkgomon.Invoke(fileServerRunner)
+		archive_helper.CreateZipArchive(
+			filepath.Join(fileServerStaticDir, "lrp.zip"),
+			fixtures.GoServerApp(),
+		)
+
+		reps = world.ZonedReps(componentMaker, []string{"z1", "z2"}, 2)
+		zoneA = startZone("z1")
+		zoneB = startZone("z2")
+		auctioneer = ginkgomon.Invoke(componentMaker.Auctioneer())
+
+		Eventually(func() ([]*models.CellPresence, error) { return bbsClient.Cells(lgr) }).Should(HaveLen(4))
+
+		lrp := helpers.NewLRP(componentMaker.Addresses(), processGuid, helpers.LRPInstances(4))
+		Expect(bbsClient.DesireLRP(lgr, lrp)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		helpers.StopProcesses(auctioneer, zoneA, zoneB, fileServer)
+	})
+
+	It("spreads instances evenly across zones and cells", func() {
+		Eventually(func() int { return distribution().Total() }).Should(Equal(4))
+
+		current := distribution()
+		Expect(current).To(helpers.BeBalancedAcrossZones("z1", "z2"))
+		Expect(current.ByCell).To(HaveLen(4))
+	})
+
+	Context("when a whole zone goes away", func() {
+		BeforeEach(func() {
+			Eventually(func() int { return distribution().Total() }).Should(Equal(4))
+			Eventually(distribution).Should(helpers.BeBalancedAcrossZones("z1", "z2"))
+
+			ginkgomon.Kill(zoneB)
+		})
+
+		It("moves every instance into the remaining zone", func() {
+			Eventually(func() map[string]int {
+				return distribution().ByZone
+			}, 2*time.Minute).Should(Equal(map[string]int{"z1": 4}))
+		})
+
+		Context("and comes back", func() {
+			BeforeEach(func() {
+				Eventually(func() map[string]int {
+					return distribution().ByZone
+				}, 2*time.Minute).Should(Equal(map[string]int{"z1": 4}))
+
+				reps = world.ZonedReps(componentMaker, []string{"z1", "z2"}, 2)
+				zoneB = startZone("z2")
+				Eventually(func() ([]*models.CellPresence, error) { return bbsClient.Cells(lgr) }).Should(HaveLen(4))
+			})
+
+			It("places new instances in the recovered zone", func() {
+				scaleTo(8)
+
+				Eventually(func() int { return distribution().Total() }).Should(Equal(8))
+				Expect(distribution()).To(helpers.BeBalancedAcrossZones("z1", "z2"))
+			})
+		})
+	})
+})
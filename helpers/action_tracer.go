@@ -0,0 +1,304 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"github.com/onsi/gomega"
+	"github.com/onsi/gomega/format"
+)
+
+const (
+	traceStarted   = "started"
+	traceFinished  = "finished"
+	traceCancelled = "cancelled"
+)
+
+// ActionTracer builds action trees whose nodes announce when they start,
+// finish and, for run actions, when they are cancelled. Announcements go to
+// the URL returned by announceURL, usually
+// the AnnounceURL method of an inigo_announcement_server.Server.
+//
+// Only run actions have a process to notice a cancel, so a composite's
+// cancellation is worked out from the run actions under it.
+type ActionTracer struct {
+	prefix      string
+	announceURL func(string) string
+	// runs maps each node to the run actions under it, or to itself for a
+	// run action
+	runs map[string][]string
+}
+
+// TracedAction is a node in a traced action tree.
+type TracedAction struct {
+	Name   string
+	action models.ActionInterface
+}
+
+func (a *TracedAction) Action() models.ActionInterface {
+	return a.action
+}
+
+// NewActionTracer returns a tracer whose announcements are prefixed with
+// prefix, so that several trees can report to the same announcement server.
+func NewActionTracer(prefix string, announceURL func(string) string) *ActionTracer {
+	return &ActionTracer{
+		prefix:      prefix,
+		announceURL: announceURL,
+		runs:        map[string][]string{},
+	}
+}
+
+func (t *ActionTracer) announcement(name, event string) string {
+	return fmt.Sprintf("%s-%s-%s", t.prefix, name, event)
+}
+
+func (t *ActionTracer) announceAction(name, event string) *models.RunAction {
+	return &models.RunAction{
+		User: "vcap",
+		Path: "curl",
+		Args: []string{"-s", t.announceURL(t.announcement(name, event))},
+	}
+}
+
+// Run is a leaf that runs script with sh. The script runs in the background
+// so that the trap can announce cancellation while it is still running.
+func (t *ActionTracer) Run(name, script string) *TracedAction {
+	wrapper := fmt.Sprintf(`
+		trap 'curl -s "%[3]s"; kill $child 2>/dev/null; exit 143' TERM
+
+		curl -s "%[1]s"
+		sh -c '%[4]s' &
+		child=$!
+		wait $child
+		status=$?
+		curl -s "%[2]s"
+		exit $status
+	`,
+		t.announceURL(t.announcement(name, traceStarted)),
+		t.announceURL(t.announcement(name, traceFinished)),
+		t.announceURL(t.announcement(name, traceCancelled)),
+		strings.Replace(script, "'", `'"'"'`, -1),
+	)
+
+	t.runs[name] = []string{name}
+
+	return &TracedAction{
+		Name: name,
+		action: &models.RunAction{
+			User: "vcap",
+			Path: "sh",
+			Args: []string{"-c", wrapper},
+		},
+	}
+}
+
+func (t *ActionTracer) wrap(name string, action models.ActionInterface, children ...*TracedAction) *TracedAction {
+	runs := []string{}
+	for _, child := range children {
+		runs = append(runs, t.runs[child.Name]...)
+	}
+	t.runs[name] = runs
+
+	return &TracedAction{
+		Name: name,
+		action: models.Serial(
+			t.announceAction(name, traceStarted),
+			action,
+			t.announceAction(name, traceFinished),
+		),
+	}
+}
+
+func (t *ActionTracer) Serial(name string, children ...*TracedAction) *TracedAction {
+	return t.wrap(name, models.Serial(actionsOf(children)...), children...)
+}
+
+func (t *ActionTracer) Parallel(name string, children ...*TracedAction) *TracedAction {
+	return t.wrap(name, models.Parallel(actionsOf(children)...), children...)
+}
+
+func (t *ActionTracer) Codependent(name string, children ...*TracedAction) *TracedAction {
+	return t.wrap(name, models.Codependent(actionsOf(children)...), children...)
+}
+
+func (t *ActionTracer) Timeout(name string, timeout time.Duration, child *TracedAction) *TracedAction {
+	return t.wrap(name, models.Timeout(child.Action(), timeout), child)
+}
+
+func (t *ActionTracer) Try(name string, child *TracedAction) *TracedAction {
+	return t.wrap(name, models.Try(child.Action()), child)
+}
+
+func actionsOf(children []*TracedAction) []models.ActionInterface {
+	actions := make([]models.ActionInterface, 0, len(children))
+	for _, child := range children {
+		actions = append(actions, child.Action())
+	}
+	return actions
+}
+
+// HaveExecutedSerially succeeds when each named node finished before the
+// next one started.
+func (t *ActionTracer) HaveExecutedSerially(names ...string) gomega.OmegaMatcher {
+	return &traceMatcher{
+		tracer:      t,
+		description: fmt.Sprintf("to have executed serially: %s", strings.Join(names, ", ")),
+		match: func(trace []string) bool {
+			for i := 1; i < len(names); i++ {
+				finished := indexOf(trace, t.announcement(names[i-1], traceFinished))
+				started := indexOf(trace, t.announcement(names[i], traceStarted))
+				if finished < 0 || started < 0 || finished > started {
+					return false
+				}
+			}
+			return true
+		},
+	}
+}
+
+// HaveExecutedInParallel succeeds when every named node started before any
+// of them finished.
+func (t *ActionTracer) HaveExecutedInParallel(names ...string) gomega.OmegaMatcher {
+	return &traceMatcher{
+		tracer:      t,
+		description: fmt.Sprintf("to have executed in parallel: %s", strings.Join(names, ", ")),
+		match: func(trace []string) bool {
+			lastStart := -1
+			firstFinish := len(trace)
+			for _, name := range names {
+				started := indexOf(trace, t.announcement(name, traceStarted))
+				if started < 0 {
+					return false
+				}
+				if started > lastStart {
+					lastStart = started
+				}
+				if finished := indexOf(trace, t.announcement(name, traceFinished)); finished >= 0 && finished < firstFinish {
+					firstFinish = finished
+				}
+			}
+			return lastStart < firstFinish
+		},
+	}
+}
+
+// HaveCompleted succeeds when every named node started and finished.
+func (t *ActionTracer) HaveCompleted(names ...string) gomega.OmegaMatcher {
+	return &traceMatcher{
+		tracer:      t,
+		description: fmt.Sprintf("to have completed: %s", strings.Join(names, ", ")),
+		match: func(trace []string) bool {
+			for _, name := range names {
+				if indexOf(trace, t.announcement(name, traceStarted)) < 0 || indexOf(trace, t.announcement(name, traceFinished)) < 0 {
+					return false
+				}
+			}
+			return true
+		},
+	}
+}
+
+// HaveBeenCancelled succeeds when every named node was cut off while
+// running: it never finished, and it, or for a composite node one of the run
+// nodes under it, was signalled while running. That covers a cancel from
+// above as well as a Timeout or Codependent node under the named one
+// cancelling its own children.
+func (t *ActionTracer) HaveBeenCancelled(names ...string) gomega.OmegaMatcher {
+	return &traceMatcher{
+		tracer:      t,
+		description: fmt.Sprintf("to have been cancelled: %s", strings.Join(names, ", ")),
+		match: func(trace []string) bool {
+			for _, name := range names {
+				if !t.cancelled(trace, name) {
+					return false
+				}
+			}
+			return true
+		},
+	}
+}
+
+func (t *ActionTracer) cancelled(trace []string, name string) bool {
+	if indexOf(trace, t.announcement(name, traceStarted)) < 0 || indexOf(trace, t.announcement(name, traceFinished)) >= 0 {
+		return false
+	}
+	for _, run := range t.runs[name] {
+		if indexOf(trace, t.announcement(run, traceCancelled)) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// HaveNotStarted succeeds when none of the named nodes started.
+func (t *ActionTracer) HaveNotStarted(names ...string) gomega.OmegaMatcher {
+	return &traceMatcher{
+		tracer:      t,
+		description: fmt.Sprintf("not to have started: %s", strings.Join(names, ", ")),
+		match: func(trace []string) bool {
+			for _, name := range names {
+				if indexOf(trace, t.announcement(name, traceStarted)) >= 0 {
+					return false
+				}
+			}
+			return true
+		},
+	}
+}
+
+// Trace returns the announcements made by this tracer's nodes, in order,
+// with the prefix removed.
+func (t *ActionTracer) Trace(announcements []string) []string {
+	trace := []string{}
+	for _, announcement := range announcements {
+		if strings.HasPrefix(announcement, t.prefix+"-") {
+			trace = append(trace, strings.TrimPrefix(announcement, t.prefix+"-"))
+		}
+	}
+	return trace
+}
+
+type traceMatcher struct {
+	tracer      *ActionTracer
+	description string
+	match       func(trace []string) bool
+}
+
+func (matcher *traceMatcher) Match(actual interface{}) (bool, error) {
+	announcements, ok := actual.([]string)
+	if !ok {
+		return false, fmt.Errorf("trace matchers expect a []string of announcements, got\n%s", format.Object(actual, 1))
+	}
+
+	return matcher.match(announcements), nil
+}
+
+func (matcher *traceMatcher) FailureMessage(actual interface{}) string {
+	return matcher.message(actual, "Expected")
+}
+
+func (matcher *traceMatcher) NegatedFailureMessage(actual interface{}) string {
+	return matcher.message(actual, "Did not expect")
+}
+
+func (matcher *traceMatcher) message(actual interface{}, expectation string) string {
+	announcements, _ := actual.([]string)
+	return fmt.Sprintf(
+		"%s the action trace\n%s\n%s",
+		expectation,
+		format.IndentString(strings.Join(matcher.tracer.Trace(announcements), "\n"), 1),
+		matcher.description,
+	)
+}
+
+func indexOf(list []string, element string) int {
+	for i, e := range list {
+		if e == element {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,91 @@
+package helpers
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/inigo/world"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/locket"
+	locketmodels "code.cloudfoundry.org/locket/models"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+)
+
+// AuctioneerFailoverTimeout bounds how long it may take, after the active
+// auctioneer loses the lock, for a standby to take over.
+const AuctioneerFailoverTimeout = locket.DefaultSessionTTL + 2*locket.RetryInterval + 5*time.Second
+
+// AuctioneerClusterProcess is a running world.AuctioneerCluster: the
+// auctioneers, their locket proxies and the failover proxy in front of
+// them.
+type AuctioneerClusterProcess struct {
+	Cluster world.AuctioneerCluster
+
+	logger        lager.Logger
+	locketClient  locketmodels.LocketClient
+	proxy         ifrit.Process
+	locketProxies []ifrit.Process
+	instances     []ifrit.Process
+}
+
+// StartAuctioneerCluster starts every auctioneer of cluster and waits for
+// one of them to take the lock.
+func StartAuctioneerCluster(logger lager.Logger, cluster world.AuctioneerCluster, locketClient locketmodels.LocketClient) *AuctioneerClusterProcess {
+	p := &AuctioneerClusterProcess{
+		Cluster:      cluster,
+		logger:       logger,
+		locketClient: locketClient,
+		proxy:        ginkgomon.Invoke(cluster.FailoverProxy()),
+	}
+
+	for _, instance := range cluster.Instances {
+		p.locketProxies = append(p.locketProxies, ginkgomon.Invoke(instance.LocketProxy))
+		p.instances = append(p.instances, ginkgomon.Invoke(instance.Runner))
+	}
+
+	Eventually(p.Active, AuctioneerFailoverTimeout).ShouldNot(Equal(-1))
+	return p
+}
+
+// Active returns the index of the auctioneer holding the lock, or -1.
+func (p *AuctioneerClusterProcess) Active() int {
+	return p.Cluster.Active(p.logger, p.locketClient)
+}
+
+// Partition cuts auctioneer i off from locket, leaving it reachable by the
+// BBS. It loses the lock once it fails to renew it.
+func (p *AuctioneerClusterProcess) Partition(i int) {
+	p.Cluster.Instances[i].LocketProxy.Partition()
+}
+
+func (p *AuctioneerClusterProcess) Heal(i int) {
+	p.Cluster.Instances[i].LocketProxy.Heal()
+}
+
+// Pause stops auctioneer i, so that it neither renews its lock nor answers
+// requests, until Resume.
+func (p *AuctioneerClusterProcess) Pause(i int) {
+	PauseProcess(p.Cluster.Instances[i].Runner)
+}
+
+func (p *AuctioneerClusterProcess) Resume(i int) {
+	ResumeProcess(p.Cluster.Instances[i].Runner)
+}
+
+// Exited returns a channel that receives auctioneer i's exit error.
+func (p *AuctioneerClusterProcess) Exited(i int) <-chan error {
+	return p.instances[i].Wait()
+}
+
+func (p *AuctioneerClusterProcess) Stop() {
+	for i := range p.Cluster.Instances {
+		p.Heal(i)
+		// a paused auctioneer would not act on SIGTERM until resumed
+		p.Resume(i)
+	}
+
+	StopProcesses(p.instances...)
+	StopProcesses(p.locketProxies...)
+	StopProcesses(p.proxy)
+}
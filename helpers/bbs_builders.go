@@ -0,0 +1,252 @@
+package helpers
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/inigo/world"
+	. "github.com/onsi/gomega"
+)
+
+type TaskOption func(*models.Task)
+
+// NewTask builds a task in the inigo domain running action on the default
+// preloaded rootfs, applies opts, and fails the spec if the result is not a
+// valid task.
+func NewTask(taskGuid string, action models.ActionInterface, opts ...TaskOption) *models.Task {
+	task := &models.Task{
+		TaskGuid: taskGuid,
+		Domain:   defaultDomain,
+
+		TaskDefinition: &models.TaskDefinition{
+			RootFs: defaultPreloadedRootFS,
+			Action: models.WrapAction(action),
+		},
+	}
+
+	for _, opt := range opts {
+		opt(task)
+	}
+
+	Expect(task.Validate()).NotTo(HaveOccurred())
+	return task
+}
+
+func TaskRootFS(rootFS string) TaskOption {
+	return func(task *models.Task) { task.RootFs = rootFS }
+}
+
+func TaskMemoryMB(memoryMB int) TaskOption {
+	return func(task *models.Task) { task.MemoryMb = int32(memoryMB) }
+}
+
+func TaskDiskMB(diskMB int) TaskOption {
+	return func(task *models.Task) { task.DiskMb = int32(diskMB) }
+}
+
+func TaskCPUWeight(weight uint32) TaskOption {
+	return func(task *models.Task) { task.CpuWeight = weight }
+}
+
+func TaskMaxPids(maxPids int) TaskOption {
+	return func(task *models.Task) { task.MaxPids = int32(maxPids) }
+}
+
+func TaskPrivileged() TaskOption {
+	return func(task *models.Task) { task.Privileged = true }
+}
+
+func TaskEnv(env ...*models.EnvironmentVariable) TaskOption {
+	return func(task *models.Task) { task.EnvironmentVariables = append(task.EnvironmentVariables, env...) }
+}
+
+func TaskLogGuid(logGuid, logSource string) TaskOption {
+	return func(task *models.Task) {
+		task.LogGuid = logGuid
+		task.LogSource = logSource
+	}
+}
+
+func TaskPlacementTags(tags ...string) TaskOption {
+	return func(task *models.Task) { task.PlacementTags = tags }
+}
+
+func TaskCertificateProperties(properties *models.CertificateProperties) TaskOption {
+	return func(task *models.Task) { task.CertificateProperties = properties }
+}
+
+func TaskVolumeMounts(mounts ...*models.VolumeMount) TaskOption {
+	return func(task *models.Task) { task.VolumeMounts = append(task.VolumeMounts, mounts...) }
+}
+
+func TaskCachedDependencies(dependencies ...*models.CachedDependency) TaskOption {
+	return func(task *models.Task) { task.CachedDependencies = append(task.CachedDependencies, dependencies...) }
+}
+
+func TaskImageCredentials(username, password string) TaskOption {
+	return func(task *models.Task) {
+		task.ImageUsername = username
+		task.ImagePassword = password
+	}
+}
+
+func TaskImageLayers(layers ...*models.ImageLayer) TaskOption {
+	return func(task *models.Task) { task.ImageLayers = append(task.ImageLayers, layers...) }
+}
+
+func TaskEgressRules(rules ...*models.SecurityGroupRule) TaskOption {
+	return func(task *models.Task) { task.EgressRules = append(task.EgressRules, rules...) }
+}
+
+func TaskMetricTags(tags map[string]*models.MetricTagValue) TaskOption {
+	return func(task *models.Task) { task.MetricTags = tags }
+}
+
+func TaskLogRateLimit(bytesPerSecond int64) TaskOption {
+	return func(task *models.Task) { task.LogRateLimit = &models.LogRateLimit{BytesPerSecond: bytesPerSecond} }
+}
+
+func TaskNetworkProperties(properties map[string]string) TaskOption {
+	return func(task *models.Task) { task.Network = &models.Network{Properties: properties} }
+}
+
+func TaskResultFile(path string) TaskOption {
+	return func(task *models.Task) { task.ResultFile = path }
+}
+
+func TaskCompletionCallbackURL(url string) TaskOption {
+	return func(task *models.Task) { task.CompletionCallbackUrl = url }
+}
+
+type LRPOption func(*models.DesiredLRP)
+
+// NewLRP builds a single-instance LRP running the go-server fixture from the
+// file server, applies opts, and fails the spec if the result is not a valid
+// desired LRP.
+func NewLRP(addresses world.ComponentAddresses, processGuid string, opts ...LRPOption) *models.DesiredLRP {
+	lrp := lrpCreateRequest(addresses, processGuid, defaultLogGuid, defaultPreloadedRootFS, 1, nil, defaultAction, defaultMonitor)
+
+	for _, opt := range opts {
+		opt(lrp)
+	}
+
+	Expect(lrp.Validate()).NotTo(HaveOccurred())
+	return lrp
+}
+
+func LRPInstances(instances int) LRPOption {
+	return func(lrp *models.DesiredLRP) { lrp.Instances = int32(instances) }
+}
+
+func LRPLogGuid(logGuid string) LRPOption {
+	return func(lrp *models.DesiredLRP) { lrp.LogGuid = logGuid }
+}
+
+func LRPRootFS(rootFS string) LRPOption {
+	return func(lrp *models.DesiredLRP) { lrp.RootFs = rootFS }
+}
+
+func LRPMemoryMB(memoryMB int) LRPOption {
+	return func(lrp *models.DesiredLRP) { lrp.MemoryMb = int32(memoryMB) }
+}
+
+func LRPDiskMB(diskMB int) LRPOption {
+	return func(lrp *models.DesiredLRP) { lrp.DiskMb = int32(diskMB) }
+}
+
+func LRPCPUWeight(weight uint32) LRPOption {
+	return func(lrp *models.DesiredLRP) { lrp.CpuWeight = weight }
+}
+
+func LRPMaxPids(maxPids int) LRPOption {
+	return func(lrp *models.DesiredLRP) { lrp.MaxPids = int32(maxPids) }
+}
+
+func LRPPrivileged() LRPOption {
+	return func(lrp *models.DesiredLRP) { lrp.Privileged = true }
+}
+
+func LRPEnv(env ...*models.EnvironmentVariable) LRPOption {
+	return func(lrp *models.DesiredLRP) { lrp.EnvironmentVariables = append(lrp.EnvironmentVariables, env...) }
+}
+
+func LRPSetup(setup models.ActionInterface) LRPOption {
+	return func(lrp *models.DesiredLRP) { lrp.Setup = models.WrapAction(setup) }
+}
+
+func LRPAction(action models.ActionInterface) LRPOption {
+	return func(lrp *models.DesiredLRP) { lrp.Action = models.WrapAction(action) }
+}
+
+// LRPMonitor replaces the monitor action; pass nil to remove it.
+func LRPMonitor(monitor models.ActionInterface) LRPOption {
+	return func(lrp *models.DesiredLRP) { lrp.Monitor = models.WrapAction(monitor) }
+}
+
+// LRPCheckDefinition sets a declarative healthcheck and removes the monitor
+// action.
+func LRPCheckDefinition(checks ...*models.Check) LRPOption {
+	return func(lrp *models.DesiredLRP) {
+		lrp.Monitor = nil
+		lrp.CheckDefinition = &models.CheckDefinition{Checks: checks}
+	}
+}
+
+func LRPStartTimeout(timeout time.Duration) LRPOption {
+	return func(lrp *models.DesiredLRP) { lrp.StartTimeoutMs = int64(timeout / time.Millisecond) }
+}
+
+func LRPSidecars(sidecars ...*models.Sidecar) LRPOption {
+	return func(lrp *models.DesiredLRP) { lrp.Sidecars = append(lrp.Sidecars, sidecars...) }
+}
+
+func LRPPorts(ports ...uint32) LRPOption {
+	return func(lrp *models.DesiredLRP) { lrp.Ports = ports }
+}
+
+func LRPRoutes(routes *models.Routes) LRPOption {
+	return func(lrp *models.DesiredLRP) { lrp.Routes = routes }
+}
+
+func LRPPlacementTags(tags ...string) LRPOption {
+	return func(lrp *models.DesiredLRP) { lrp.PlacementTags = tags }
+}
+
+func LRPCertificateProperties(properties *models.CertificateProperties) LRPOption {
+	return func(lrp *models.DesiredLRP) { lrp.CertificateProperties = properties }
+}
+
+func LRPVolumeMounts(mounts ...*models.VolumeMount) LRPOption {
+	return func(lrp *models.DesiredLRP) { lrp.VolumeMounts = append(lrp.VolumeMounts, mounts...) }
+}
+
+func LRPCachedDependencies(dependencies ...*models.CachedDependency) LRPOption {
+	return func(lrp *models.DesiredLRP) { lrp.CachedDependencies = append(lrp.CachedDependencies, dependencies...) }
+}
+
+func LRPImageCredentials(username, password string) LRPOption {
+	return func(lrp *models.DesiredLRP) {
+		lrp.ImageUsername = username
+		lrp.ImagePassword = password
+	}
+}
+
+func LRPImageLayers(layers ...*models.ImageLayer) LRPOption {
+	return func(lrp *models.DesiredLRP) { lrp.ImageLayers = append(lrp.ImageLayers, layers...) }
+}
+
+func LRPEgressRules(rules ...*models.SecurityGroupRule) LRPOption {
+	return func(lrp *models.DesiredLRP) { lrp.EgressRules = append(lrp.EgressRules, rules...) }
+}
+
+func LRPMetricTags(tags map[string]*models.MetricTagValue) LRPOption {
+	return func(lrp *models.DesiredLRP) { lrp.MetricTags = tags }
+}
+
+func LRPLogRateLimit(bytesPerSecond int64) LRPOption {
+	return func(lrp *models.DesiredLRP) { lrp.LogRateLimit = &models.LogRateLimit{BytesPerSecond: bytesPerSecond} }
+}
+
+func LRPNetworkProperties(properties map[string]string) LRPOption {
+	return func(lrp *models.DesiredLRP) { lrp.Network = &models.Network{Properties: properties} }
+}
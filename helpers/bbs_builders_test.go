@@ -0,0 +1,140 @@
+package helpers_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/world"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BBS builders", func() {
+	var action *models.RunAction
+
+	BeforeEach(func() {
+		action = &models.RunAction{User: "vcap", Path: "true"}
+	})
+
+	Describe("NewTask", func() {
+		It("builds a valid task in the inigo domain", func() {
+			task := helpers.NewTask("some-guid", action)
+
+			Expect(task.TaskGuid).To(Equal("some-guid"))
+			Expect(task.Domain).To(Equal("inigo"))
+			Expect(task.RootFs).To(Equal("preloaded:" + world.DefaultStack))
+			Expect(task.Action).To(Equal(models.WrapAction(action)))
+			Expect(task.Validate()).To(Succeed())
+		})
+
+		It("applies the options in order", func() {
+			dependency := &models.CachedDependency{From: "http://example.com/a.tgz", To: "/tmp/a"}
+			rule := &models.SecurityGroupRule{Protocol: models.TCPProtocol, Destinations: []string{"0.0.0.0/0"}, Ports: []uint32{80}}
+
+			task := helpers.NewTask("some-guid", action,
+				helpers.TaskRootFS("docker:///some/image"),
+				helpers.TaskMemoryMB(128),
+				helpers.TaskDiskMB(256),
+				helpers.TaskPrivileged(),
+				helpers.TaskImageCredentials("user", "password"),
+				helpers.TaskCachedDependencies(dependency),
+				helpers.TaskEgressRules(rule),
+				helpers.TaskNetworkProperties(map[string]string{"key": "value"}),
+				helpers.TaskResultFile("/tmp/result"),
+				helpers.TaskCompletionCallbackURL("http://example.com/callback"),
+				helpers.TaskMemoryMB(64),
+			)
+
+			Expect(task.RootFs).To(Equal("docker:///some/image"))
+			Expect(task.MemoryMb).To(BeEquivalentTo(64))
+			Expect(task.DiskMb).To(BeEquivalentTo(256))
+			Expect(task.Privileged).To(BeTrue())
+			Expect(task.ImageUsername).To(Equal("user"))
+			Expect(task.ImagePassword).To(Equal("password"))
+			Expect(task.CachedDependencies).To(ConsistOf(dependency))
+			Expect(task.EgressRules).To(ConsistOf(rule))
+			Expect(task.Network).To(Equal(&models.Network{Properties: map[string]string{"key": "value"}}))
+			Expect(task.ResultFile).To(Equal("/tmp/result"))
+			Expect(task.CompletionCallbackUrl).To(Equal("http://example.com/callback"))
+		})
+
+		It("appends list options instead of replacing earlier ones", func() {
+			first := &models.EnvironmentVariable{Name: "FIRST", Value: "1"}
+			second := &models.EnvironmentVariable{Name: "SECOND", Value: "2"}
+
+			task := helpers.NewTask("some-guid", action, helpers.TaskEnv(first), helpers.TaskEnv(second))
+			Expect(task.EnvironmentVariables).To(Equal([]*models.EnvironmentVariable{first, second}))
+		})
+
+		It("fails when the task is not valid", func() {
+			failures := InterceptGomegaFailures(func() {
+				helpers.NewTask("some-guid", action, helpers.TaskRootFS(""))
+			})
+			Expect(failures).To(HaveLen(1))
+			Expect(failures[0]).To(ContainSubstring("rootfs"))
+		})
+
+		It("fails when an image credential is missing", func() {
+			failures := InterceptGomegaFailures(func() {
+				helpers.NewTask("some-guid", action,
+					helpers.TaskRootFS("docker:///some/image"),
+					helpers.TaskImageCredentials("user", ""),
+				)
+			})
+			Expect(failures).To(HaveLen(1))
+		})
+	})
+
+	Describe("NewLRP", func() {
+		var addresses world.ComponentAddresses
+
+		BeforeEach(func() {
+			addresses = world.ComponentAddresses{FileServer: "127.0.0.1:8080"}
+		})
+
+		It("builds a valid single-instance LRP downloading from the file server", func() {
+			lrp := helpers.NewLRP(addresses, "some-guid")
+
+			Expect(lrp.ProcessGuid).To(Equal("some-guid"))
+			Expect(lrp.Domain).To(Equal("inigo"))
+			Expect(lrp.Instances).To(BeEquivalentTo(1))
+			Expect(lrp.Setup.DownloadAction).NotTo(BeNil())
+			Expect(lrp.Setup.DownloadAction.From).To(ContainSubstring(addresses.FileServer))
+			Expect(lrp.Monitor).NotTo(BeNil())
+			Expect(lrp.Validate()).To(Succeed())
+		})
+
+		It("applies the options", func() {
+			check := &models.Check{HttpCheck: &models.HTTPCheck{Port: 8080}}
+
+			lrp := helpers.NewLRP(addresses, "some-guid",
+				helpers.LRPInstances(4),
+				helpers.LRPMemoryMB(128),
+				helpers.LRPPlacementTags("red", "blue"),
+				helpers.LRPStartTimeout(time.Minute),
+				helpers.LRPCheckDefinition(check),
+			)
+
+			Expect(lrp.Instances).To(BeEquivalentTo(4))
+			Expect(lrp.MemoryMb).To(BeEquivalentTo(128))
+			Expect(lrp.PlacementTags).To(Equal([]string{"red", "blue"}))
+			Expect(lrp.StartTimeoutMs).To(BeEquivalentTo(60000))
+			Expect(lrp.Monitor).To(BeNil())
+			Expect(lrp.CheckDefinition.Checks).To(ConsistOf(check))
+		})
+
+		It("removes the monitor when given nil", func() {
+			lrp := helpers.NewLRP(addresses, "some-guid", helpers.LRPMonitor(nil))
+			Expect(lrp.Monitor).To(BeNil())
+		})
+
+		It("fails when the LRP is not valid", func() {
+			failures := InterceptGomegaFailures(func() {
+				helpers.NewLRP(addresses, "some-guid", helpers.LRPInstances(-1))
+			})
+			Expect(failures).To(HaveLen(1))
+			Expect(failures[0]).To(ContainSubstring("instances"))
+		})
+	})
+})
@@ -0,0 +1,90 @@
+package helpers
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/inigo/world"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/locket"
+	locketmodels "code.cloudfoundry.org/locket/models"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+)
+
+// BBSFailoverTimeout bounds how long it may take, after the BBS leader dies,
+// for a standby to take the lock and for the reps, auctioneer and
+// route-emitter to be working against it again: the lock has to expire,
+// the standby has to notice, and the clients have to retry.
+const BBSFailoverTimeout = locket.DefaultSessionTTL + 2*locket.RetryInterval + 15*time.Second
+
+// BBSClusterProcess is a running world.BBSCluster and the failover proxy in
+// front of it for the components.
+type BBSClusterProcess struct {
+	Cluster world.BBSCluster
+
+	logger       lager.Logger
+	locketClient locketmodels.LocketClient
+	proxy        ifrit.Process
+	instances    []ifrit.Process
+}
+
+// StartBBSCluster starts the proxy and every instance of cluster, and waits
+// for one of them to take the lock.
+func StartBBSCluster(logger lager.Logger, cluster world.BBSCluster, locketClient locketmodels.LocketClient) *BBSClusterProcess {
+	p := &BBSClusterProcess{
+		Cluster:      cluster,
+		logger:       logger,
+		locketClient: locketClient,
+		proxy:        ginkgomon.Invoke(cluster.FailoverProxy()),
+	}
+
+	for _, instance := range cluster.Instances {
+		p.instances = append(p.instances, ginkgomon.Invoke(instance.Runner))
+	}
+
+	Eventually(p.Leader, BBSFailoverTimeout).ShouldNot(Equal(-1))
+	return p
+}
+
+// Leader returns the index of the instance holding the BBS lock, or -1.
+func (p *BBSClusterProcess) Leader() int {
+	return p.Cluster.Leader(p.logger, p.locketClient)
+}
+
+// KillLeader kills the instance holding the BBS lock without letting it
+// release the lock, and returns its index.
+func (p *BBSClusterProcess) KillLeader() int {
+	leader := p.Leader()
+	Expect(leader).NotTo(Equal(-1), "no BBS instance holds the lock")
+
+	ginkgomon.Kill(p.instances[leader])
+	p.instances[leader] = nil
+	return leader
+}
+
+// ServingClient goes through the cluster's URLs in order, as a
+// failover-aware client would, and returns a client for the first instance
+// that answers along with its index. It returns nil and -1 when none does.
+func (p *BBSClusterProcess) ServingClient() (bbs.InternalClient, int) {
+	for i := range p.Cluster.Instances {
+		client := p.Cluster.Client(i)
+		if client.Ping(p.logger) {
+			return client, i
+		}
+	}
+	return nil, -1
+}
+
+// ServingInstance returns the index of the instance ServingClient would
+// use, or -1.
+func (p *BBSClusterProcess) ServingInstance() int {
+	_, i := p.ServingClient()
+	return i
+}
+
+func (p *BBSClusterProcess) Stop() {
+	StopProcesses(p.instances...)
+	StopProcesses(p.proxy)
+}
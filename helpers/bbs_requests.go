@@ -105,14 +105,6 @@ func DefaultDeclaritiveHealthcheckLRPCreateRequest(addresses world.ComponentAddr
 	return request
 }
 
-func LRPCreateRequestWithPlacementTag(addresses world.ComponentAddresses, processGuid string, tags []string) *models.DesiredLRP {
-	return lrpCreateRequest(addresses, processGuid, defaultLogGuid, defaultPreloadedRootFS, 1, tags, defaultAction, defaultMonitor)
-}
-
-func LRPCreateRequestWithRootFS(addresses world.ComponentAddresses, processGuid, rootfs string) *models.DesiredLRP {
-	return lrpCreateRequest(addresses, processGuid, defaultLogGuid, rootfs, 1, nil, defaultAction, defaultMonitor)
-}
-
 func DockerLRPCreateRequest(addresses world.ComponentAddresses, processGuid string) *models.DesiredLRP {
 	action := models.WrapAction(&models.RunAction{
 		User: "vcap",
@@ -151,42 +143,13 @@ func LightweightLRPCreateRequest(addresses world.ComponentAddresses, processGuid
 }
 
 func TaskCreateRequest(taskGuid string, action models.ActionInterface) *models.Task {
-	return taskCreateRequest(taskGuid, defaultPreloadedRootFS, action, 0, 0, nil)
-}
-
-func TaskCreateRequestWithTags(taskGuid string, action models.ActionInterface, tags []string) *models.Task {
-	task := taskCreateRequest(taskGuid, defaultPreloadedRootFS, action, 0, 0, nil)
-	task.PlacementTags = tags
-	return task
-}
-
-func TaskCreateRequestWithMemory(taskGuid string, action models.ActionInterface, memoryMB int) *models.Task {
-	return taskCreateRequest(taskGuid, defaultPreloadedRootFS, action, memoryMB, 0, nil)
-}
-
-func TaskCreateRequestWithRootFS(taskGuid, rootfs string, action models.ActionInterface) *models.Task {
-	return taskCreateRequest(taskGuid, rootfs, action, 0, 0, nil)
-}
-
-func TaskCreateRequestWithMemoryAndDisk(taskGuid string, action models.ActionInterface, memoryMB, diskMB int) *models.Task {
-	return taskCreateRequest(taskGuid, defaultPreloadedRootFS, action, memoryMB, diskMB, nil)
-}
-
-func TaskCreateRequestWithCertificateProperties(taskGuid string, action models.ActionInterface, certificateProperties *models.CertificateProperties) *models.Task {
-	return taskCreateRequest(taskGuid, defaultPreloadedRootFS, action, 0, 0, certificateProperties)
-}
-
-func taskCreateRequest(taskGuid, rootFS string, action models.ActionInterface, memoryMB, diskMB int, certificateProperties *models.CertificateProperties) *models.Task {
 	return &models.Task{
 		TaskGuid: taskGuid,
 		Domain:   defaultDomain,
 
 		TaskDefinition: &models.TaskDefinition{
-			RootFs:                rootFS,
-			MemoryMb:              int32(memoryMB),
-			DiskMb:                int32(diskMB),
-			Action:                models.WrapAction(action),
-			CertificateProperties: certificateProperties,
+			RootFs: defaultPreloadedRootFS,
+			Action: models.WrapAction(action),
 		},
 	}
 }
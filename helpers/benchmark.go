@@ -0,0 +1,165 @@
+package helpers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	. "github.com/onsi/gomega"
+)
+
+// DurationStats summarises a set of measured durations.
+type DurationStats struct {
+	Count int           `json:"count"`
+	Min   time.Duration `json:"min_ns"`
+	Mean  time.Duration `json:"mean_ns"`
+	P50   time.Duration `json:"p50_ns"`
+	P90   time.Duration `json:"p90_ns"`
+	P99   time.Duration `json:"p99_ns"`
+	Max   time.Duration `json:"max_ns"`
+}
+
+func NewDurationStats(samples []time.Duration) DurationStats {
+	if len(samples) == 0 {
+		return DurationStats{}
+	}
+
+	sorted := make([]time.Duration, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, s := range sorted {
+		total += s
+	}
+
+	return DurationStats{
+		Count: len(sorted),
+		Min:   sorted[0],
+		Mean:  total / time.Duration(len(sorted)),
+		P50:   percentile(sorted, 50),
+		P90:   percentile(sorted, 90),
+		P99:   percentile(sorted, 99),
+		Max:   sorted[len(sorted)-1],
+	}
+}
+
+// DurationSamples collects durations from concurrent callers.
+type DurationSamples struct {
+	lock    sync.Mutex
+	samples []time.Duration
+}
+
+func (s *DurationSamples) Add(d time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.samples = append(s.samples, d)
+}
+
+// Time runs f and records how long it took.
+func (s *DurationSamples) Time(f func()) {
+	start := time.Now()
+	f()
+	s.Add(time.Since(start))
+}
+
+func (s *DurationSamples) Stats() DurationStats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return NewDurationStats(s.samples)
+}
+
+// BenchmarkReport is the machine-readable result of a benchmark run.
+// Latencies and Durations are keyed by measurement name, e.g.
+// "lrp-desire-to-running" or "convergence".
+type BenchmarkReport struct {
+	Name       string                   `json:"name"`
+	StartedAt  time.Time                `json:"started_at"`
+	Cells      int                      `json:"cells"`
+	LRPs       int                      `json:"lrps"`
+	Tasks      int                      `json:"tasks"`
+	DBDriver   string                   `json:"db_driver"`
+	Latencies  map[string]DurationStats `json:"latencies"`
+	Durations  map[string]time.Duration `json:"durations_ns"`
+	Throughput map[string]float64       `json:"throughput_per_second"`
+}
+
+func NewBenchmarkReport(name string, cells, lrps, tasks int) *BenchmarkReport {
+	return &BenchmarkReport{
+		Name:       name,
+		StartedAt:  time.Now(),
+		Cells:      cells,
+		LRPs:       lrps,
+		Tasks:      tasks,
+		Latencies:  map[string]DurationStats{},
+		Durations:  map[string]time.Duration{},
+		Throughput: map[string]float64{},
+	}
+}
+
+// WriteJSON writes the report to path, replacing any existing file.
+func (r *BenchmarkReport) WriteJSON(path string) {
+	payload, err := json.MarshalIndent(r, "", "  ")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(ioutil.WriteFile(path, payload, 0644)).To(Succeed())
+}
+
+// InstanceStateLatencies returns, for every LRP instance in desiredAt, how
+// long after its LRP was desired the instance event stream first reported
+// it in state. Instances that never reached state are omitted.
+func InstanceStateLatencies(recorded []RecordedEvent, desiredAt map[string]time.Time, state string) []time.Duration {
+	type instance struct {
+		processGuid string
+		index       int32
+	}
+
+	reached := map[instance]time.Time{}
+	for _, r := range recorded {
+		event, ok := r.Event.(*models.ActualLRPInstanceChangedEvent)
+		if !ok || event.After == nil || event.After.State != state {
+			continue
+		}
+		if _, ok := desiredAt[event.ProcessGuid]; !ok {
+			continue
+		}
+
+		key := instance{processGuid: event.ProcessGuid, index: event.Index}
+		if _, seen := reached[key]; !seen {
+			reached[key] = r.ReceivedAt
+		}
+	}
+
+	latencies := make([]time.Duration, 0, len(reached))
+	for key, at := range reached {
+		latencies = append(latencies, at.Sub(desiredAt[key.processGuid]))
+	}
+	return latencies
+}
+
+// TaskStateLatencies is InstanceStateLatencies for tasks on the task event
+// stream, keyed by task guid.
+func TaskStateLatencies(recorded []RecordedEvent, desiredAt map[string]time.Time, state models.Task_State) []time.Duration {
+	reached := map[string]time.Time{}
+	for _, r := range recorded {
+		event, ok := r.Event.(*models.TaskChangedEvent)
+		if !ok || event.After == nil || event.After.State != state {
+			continue
+		}
+		if _, ok := desiredAt[event.After.TaskGuid]; !ok {
+			continue
+		}
+
+		if _, seen := reached[event.After.TaskGuid]; !seen {
+			reached[event.After.TaskGuid] = r.ReceivedAt
+		}
+	}
+
+	latencies := make([]time.Duration, 0, len(reached))
+	for guid, at := range reached {
+		latencies = append(latencies, at.Sub(desiredAt[guid]))
+	}
+	return latencies
+}
@@ -0,0 +1,134 @@
+package helpers
+
+import (
+	"math"
+	"sort"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/rep"
+	. "github.com/onsi/gomega"
+)
+
+// DefaultStartingContainerWeight is the auctioneer's
+// StartingContainerWeight in the inigo world.
+const DefaultStartingContainerWeight = 0.33
+
+// CellStates fetches the /state of every cell the BBS knows about, keyed by
+// cell ID, the same way the auctioneer does before an auction.
+func CellStates(logger lager.Logger, client bbs.InternalClient, factory rep.ClientFactory) map[string]rep.CellState {
+	cells, err := client.Cells(logger)
+	Expect(err).NotTo(HaveOccurred())
+
+	states := map[string]rep.CellState{}
+	for _, cell := range cells {
+		repClient, err := factory.CreateClient(cell.RepAddress, cell.RepUrl)
+		Expect(err).NotTo(HaveOccurred())
+
+		state, err := repClient.State(logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		states[cell.CellId] = state
+	}
+
+	return states
+}
+
+// PredictPlacement returns the cell the auctioneer would pick for work
+// needing resource: the one with room for it whose remaining resources
+// score best. When no cell has room, the error is the one the auctioneer
+// reports, e.g. "insufficient resources: memory". Zones, placement tags,
+// rootfses and volume drivers are not considered.
+func PredictPlacement(states map[string]rep.CellState, resource rep.Resource, startingContainerWeight float64) (string, error) {
+	var (
+		best      string
+		bestScore = math.MaxFloat64
+		problems  = map[string]struct{}{}
+	)
+
+	for _, cellID := range sortedCellIDs(states) {
+		state := states[cellID]
+
+		err := state.ResourceMatch(&resource)
+		if err != nil {
+			insufficient, ok := err.(rep.InsufficientResourcesError)
+			if !ok {
+				return "", err
+			}
+			for problem := range insufficient.Problems {
+				problems[problem] = struct{}{}
+			}
+			continue
+		}
+
+		score := state.ComputeScore(&resource, startingContainerWeight)
+		if score < bestScore {
+			best, bestScore = cellID, score
+		}
+	}
+
+	if best == "" {
+		return "", rep.InsufficientResourcesError{Problems: problems}
+	}
+
+	return best, nil
+}
+
+// PredictPlacements places each of resources in turn with
+// PredictPlacement, taking every placement's resources off its cell before
+// placing the next, as the auctioneer does within one auction. It returns
+// the chosen cell IDs in order, stopping at the first that fits nowhere.
+// states is left as it was.
+func PredictPlacements(states map[string]rep.CellState, resources []rep.Resource, startingContainerWeight float64) ([]string, error) {
+	remaining := map[string]rep.CellState{}
+	for cellID, state := range states {
+		remaining[cellID] = copyCellState(state)
+	}
+
+	placements := []string{}
+	for i := range resources {
+		cellID, err := PredictPlacement(remaining, resources[i], startingContainerWeight)
+		if err != nil {
+			return placements, err
+		}
+
+		state := remaining[cellID]
+		state.AddLRP(&rep.LRP{Resource: resources[i]})
+		remaining[cellID] = state
+
+		placements = append(placements, cellID)
+	}
+
+	return placements, nil
+}
+
+// LRPResource is the resource an instance of lrp needs on a cell.
+func LRPResource(lrp *models.DesiredLRP) rep.Resource {
+	return rep.NewResource(lrp.MemoryMb, lrp.DiskMb, lrp.MaxPids)
+}
+
+// TaskResource is the resource task needs on a cell.
+func TaskResource(task *models.Task) rep.Resource {
+	return rep.NewResource(task.MemoryMb, task.DiskMb, task.MaxPids)
+}
+
+// copyCellState copies state along with its slices, which AddLRP and
+// AddTask would otherwise append to in the caller's backing arrays.
+func copyCellState(state rep.CellState) rep.CellState {
+	state.LRPs = append([]rep.LRP(nil), state.LRPs...)
+	state.Tasks = append([]rep.Task(nil), state.Tasks...)
+	state.VolumeDrivers = append([]string(nil), state.VolumeDrivers...)
+	state.PlacementTags = append([]string(nil), state.PlacementTags...)
+	state.OptionalPlacementTags = append([]string(nil), state.OptionalPlacementTags...)
+	return state
+}
+
+func sortedCellIDs(states map[string]rep.CellState) []string {
+	cellIDs := make([]string, 0, len(states))
+	for cellID := range states {
+		cellIDs = append(cellIDs, cellID)
+	}
+	sort.Strings(cellIDs)
+	return cellIDs
+}
@@ -0,0 +1,78 @@
+package helpers_test
+
+import (
+	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/rep"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Capacity", func() {
+	var states map[string]rep.CellState
+
+	cellWith := func(memoryMB int32, containers int) rep.CellState {
+		return rep.CellState{
+			AvailableResources: rep.NewResources(memoryMB, 1024, containers),
+			TotalResources:     rep.NewResources(memoryMB, 1024, containers),
+		}
+	}
+
+	BeforeEach(func() {
+		states = map[string]rep.CellState{
+			"cell-a": cellWith(512, 10),
+			"cell-b": cellWith(1024, 10),
+		}
+	})
+
+	Describe("PredictPlacement", func() {
+		It("picks the cell with the most room left", func() {
+			Expect(helpers.PredictPlacement(states, rep.NewResource(256, 10, 0), helpers.DefaultStartingContainerWeight)).To(Equal("cell-b"))
+		})
+
+		It("reports what every cell is short of when none has room", func() {
+			states["cell-b"] = cellWith(1024, 0)
+
+			_, err := helpers.PredictPlacement(states, rep.NewResource(768, 10, 0), helpers.DefaultStartingContainerWeight)
+			Expect(err).To(BeAssignableToTypeOf(rep.InsufficientResourcesError{}))
+			Expect(err.(rep.InsufficientResourcesError).Problems).To(And(HaveKey("memory"), HaveKey("containers")))
+		})
+	})
+
+	Describe("PredictPlacements", func() {
+		It("takes each placement's resources off its cell", func() {
+			resources := []rep.Resource{
+				rep.NewResource(512, 10, 0),
+				rep.NewResource(512, 10, 0),
+				rep.NewResource(512, 10, 0),
+			}
+
+			placements, err := helpers.PredictPlacements(states, resources, helpers.DefaultStartingContainerWeight)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(placements).To(ConsistOf("cell-a", "cell-b", "cell-b"))
+		})
+
+		It("stops at the first placement that fits nowhere", func() {
+			resources := []rep.Resource{
+				rep.NewResource(1024, 10, 0),
+				rep.NewResource(1024, 10, 0),
+			}
+
+			placements, err := helpers.PredictPlacements(states, resources, helpers.DefaultStartingContainerWeight)
+			Expect(err).To(HaveOccurred())
+			Expect(placements).To(Equal([]string{"cell-b"}))
+		})
+
+		It("leaves the caller's states alone, even where their slices have room to grow", func() {
+			lrps := make([]rep.LRP, 0, 4)
+			state := cellWith(1024, 10)
+			state.LRPs = lrps
+			states = map[string]rep.CellState{"cell-a": state}
+
+			_, err := helpers.PredictPlacements(states, []rep.Resource{rep.NewResource(256, 10, 0)}, helpers.DefaultStartingContainerWeight)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(states["cell-a"]).To(Equal(state))
+			Expect(lrps[:1][0]).To(Equal(rep.LRP{}))
+		})
+	})
+})
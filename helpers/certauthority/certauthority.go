@@ -47,6 +47,9 @@ func (c certAuthority) CAAndKey() (string, string) {
 	return c.caKey, c.caCert
 }
 
+// GenerateSelfSignedCertAndKey signs a certificate for commonName, valid for
+// 127.0.0.1 and sans. SANs that are IP addresses go in as addresses, the
+// rest as names.
 func (c certAuthority) GenerateSelfSignedCertAndKey(commonName string, sans []string, intermediateCA bool) (string, string, error) {
 	key, err := pkix.CreateRSAKey(4096)
 	keyBytes, err := key.ExportPrivate()
@@ -54,8 +57,18 @@ func (c certAuthority) GenerateSelfSignedCertAndKey(commonName string, sans []st
 		return handleError(err)
 	}
 
+	ips := []net.IP{net.ParseIP("127.0.0.1")}
+	domains := []string{}
+	for _, san := range sans {
+		if ip := net.ParseIP(san); ip != nil {
+			ips = append(ips, ip)
+		} else {
+			domains = append(domains, san)
+		}
+	}
+
 	csrLock.Lock()
-	csr, err := pkix.CreateCertificateSigningRequest(key, "", []net.IP{net.ParseIP("127.0.0.1")}, sans, nil, "", "", "", "", commonName)
+	csr, err := pkix.CreateCertificateSigningRequest(key, "", ips, domains, nil, "", "", "", "", commonName)
 	if err != nil {
 		csrLock.Unlock()
 		return handleError(err)
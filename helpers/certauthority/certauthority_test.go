@@ -76,6 +76,18 @@ var _ = Describe("Cert Allocator", func() {
 			parsedCert, _ := parseCert(cert)
 			Expect(parsedCert.Subject.CommonName).To(Equal("some-component"))
 		})
+
+		It("puts SANs that are IP addresses in the certificate as addresses", func() {
+			authority, err = certauthority.NewCertAuthority(depotDir, "some-name")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, cert, err := authority.GenerateSelfSignedCertAndKey("some-component", []string{"some-component", "127.0.0.2"}, false)
+			Expect(err).NotTo(HaveOccurred())
+			parsedCert, _ := parseCert(cert)
+			Expect(parsedCert.DNSNames).To(ConsistOf("some-component"))
+			Expect(parsedCert.VerifyHostname("127.0.0.1")).To(Succeed())
+			Expect(parsedCert.VerifyHostname("127.0.0.2")).To(Succeed())
+		})
 	})
 
 	Context("when depotDir is invalid", func() {
@@ -0,0 +1,121 @@
+package helpers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	. "github.com/onsi/gomega"
+)
+
+type CompletionCallbackReceiverConfig struct {
+	// FailuresBeforeSuccess is the number of callbacks answered with
+	// FailureStatusCode before the receiver starts accepting them. A negative
+	// value rejects every callback.
+	FailuresBeforeSuccess int
+	FailureStatusCode     int
+
+	// Hang makes the receiver hold every callback open until it is closed.
+	Hang bool
+}
+
+type CompletionCallback struct {
+	Response   models.TaskCallbackResponse
+	StatusCode int
+	ReceivedAt time.Time
+}
+
+type CompletionCallbackReceiver struct {
+	server  *httptest.Server
+	addr    string
+	config  CompletionCallbackReceiverConfig
+	release chan struct{}
+
+	lock      sync.Mutex
+	callbacks []CompletionCallback
+	failures  int
+}
+
+// NewCompletionCallbackReceiver starts a server on listenHost that records
+// every task completion callback BBS sends to it.
+func NewCompletionCallbackReceiver(listenHost string, modifyConfigFuncs ...func(*CompletionCallbackReceiverConfig)) *CompletionCallbackReceiver {
+	config := CompletionCallbackReceiverConfig{
+		FailureStatusCode: http.StatusServiceUnavailable,
+	}
+
+	for _, modifyConfig := range modifyConfigFuncs {
+		modifyConfig(&config)
+	}
+
+	receiver := &CompletionCallbackReceiver{
+		config:  config,
+		release: make(chan struct{}),
+	}
+	receiver.server, receiver.addr = Callback(listenHost, receiver.handle)
+
+	return receiver
+}
+
+func (r *CompletionCallbackReceiver) handle(w http.ResponseWriter, req *http.Request) {
+	var response models.TaskCallbackResponse
+	err := json.NewDecoder(req.Body).Decode(&response)
+	Expect(err).NotTo(HaveOccurred())
+
+	callback := CompletionCallback{
+		Response:   response,
+		ReceivedAt: time.Now(),
+	}
+
+	r.lock.Lock()
+	shouldFail := r.config.FailuresBeforeSuccess < 0 || r.failures < r.config.FailuresBeforeSuccess
+	if shouldFail {
+		r.failures++
+		callback.StatusCode = r.config.FailureStatusCode
+	} else {
+		callback.StatusCode = http.StatusOK
+	}
+	r.callbacks = append(r.callbacks, callback)
+	r.lock.Unlock()
+
+	if r.config.Hang {
+		<-r.release
+	}
+
+	w.WriteHeader(callback.StatusCode)
+}
+
+// URL is the value to use as a task's CompletionCallbackUrl.
+func (r *CompletionCallbackReceiver) URL() string {
+	return "http://" + r.addr + "/completed"
+}
+
+// Callbacks returns every callback received, including rejected ones.
+func (r *CompletionCallbackReceiver) Callbacks() []CompletionCallback {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	callbacks := make([]CompletionCallback, len(r.callbacks))
+	copy(callbacks, r.callbacks)
+	return callbacks
+}
+
+// CallbacksForTask returns the callbacks received for the given task guid.
+func (r *CompletionCallbackReceiver) CallbacksForTask(taskGuid string) []CompletionCallback {
+	callbacks := []CompletionCallback{}
+	for _, callback := range r.Callbacks() {
+		if callback.Response.TaskGuid == taskGuid {
+			callbacks = append(callbacks, callback)
+		}
+	}
+	return callbacks
+}
+
+func (r *CompletionCallbackReceiver) Close() {
+	// httptest.Server.Close waits for outstanding requests, so let any hung
+	// callbacks finish first
+	close(r.release)
+	r.server.Close()
+}
@@ -12,6 +12,10 @@ import (
 )
 
 func ConsulWaitUntilReady(addresses world.ComponentAddresses) {
+	if addresses.Consul == "" {
+		return
+	}
+
 	_, port, err := net.SplitHostPort(addresses.Consul)
 	Expect(err).NotTo(HaveOccurred())
 	httpPort, err := strconv.Atoi(port)
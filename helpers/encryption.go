@@ -0,0 +1,118 @@
+package helpers
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager"
+	. "github.com/onsi/gomega"
+)
+
+// EncryptedColumns lists the columns the BBS encrypts, by table.
+var EncryptedColumns = map[string][]string{
+	"desired_lrps": {"run_info", "volume_placement", "routes"},
+	"actual_lrps":  {"net_info"},
+	"tasks":        {"task_definition"},
+}
+
+// The BBS prefixes every stored blob with a two-byte encoding; encrypted
+// ones, raw or base64-wrapped, start with the length of the key label and
+// the label itself.
+var (
+	encodingBase64Encrypted = "02"
+	encodingEncrypted       = "03"
+)
+
+// EncryptionKeyLabel returns the label of the key blob was encrypted with,
+// or "" if it isn't encrypted.
+func EncryptionKeyLabel(blob []byte) (string, error) {
+	if len(blob) < 2 {
+		return "", nil
+	}
+
+	payload := blob[2:]
+	switch string(blob[:2]) {
+	case encodingEncrypted:
+	case encodingBase64Encrypted:
+		decoded, err := base64.StdEncoding.DecodeString(string(payload))
+		if err != nil {
+			return "", err
+		}
+		payload = decoded
+	default:
+		return "", nil
+	}
+
+	if len(payload) < 1 || len(payload) < 1+int(payload[0]) {
+		return "", fmt.Errorf("truncated encrypted blob of %d bytes", len(blob))
+	}
+	return string(payload[1 : 1+payload[0]]), nil
+}
+
+// EncryptionKeyLabels counts the blobs in EncryptedColumns by the label of
+// the key they are encrypted with. Empty columns are skipped.
+func EncryptionKeyLabels(db *sql.DB) map[string]int {
+	labels := map[string]int{}
+
+	for table, columns := range EncryptedColumns {
+		for _, column := range columns {
+			rows, err := db.Query(fmt.Sprintf("SELECT %s FROM %s", column, table))
+			Expect(err).NotTo(HaveOccurred())
+
+			for rows.Next() {
+				var blob []byte
+				Expect(rows.Scan(&blob)).To(Succeed())
+				if len(blob) == 0 {
+					continue
+				}
+
+				label, err := EncryptionKeyLabel(blob)
+				Expect(err).NotTo(HaveOccurred(), "%s.%s", table, column)
+				labels[label]++
+			}
+			Expect(rows.Err()).NotTo(HaveOccurred())
+			Expect(rows.Close()).To(Succeed())
+		}
+	}
+
+	return labels
+}
+
+// EncryptionKeyLabelPoller returns the label the BBS records once its
+// encryptor has re-encrypted everything with the active key, or "" before
+// the first pass.
+func EncryptionKeyLabelPoller(db *sql.DB) func() string {
+	return func() string {
+		var label string
+		err := db.QueryRow(rebind("SELECT value FROM configurations WHERE id = ?"), "encryption_key_label").Scan(&label)
+		if err == sql.ErrNoRows {
+			return ""
+		}
+		Expect(err).NotTo(HaveOccurred())
+		return label
+	}
+}
+
+// ExpectAllRecordsReadable fails unless the BBS can decrypt and return
+// every desired LRP, actual LRP and task.
+func ExpectAllRecordsReadable(logger lager.Logger, client bbs.InternalClient) {
+	desiredLRPs, err := client.DesiredLRPs(logger, models.DesiredLRPFilter{})
+	Expect(err).NotTo(HaveOccurred())
+	for _, desired := range desiredLRPs {
+		_, err := client.DesiredLRPByProcessGuid(logger, desired.ProcessGuid)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	_, err = client.ActualLRPs(logger, models.ActualLRPFilter{})
+	Expect(err).NotTo(HaveOccurred())
+
+	tasks, err := client.Tasks(logger)
+	Expect(err).NotTo(HaveOccurred())
+	for _, task := range tasks {
+		_, err := client.TaskByGuid(logger, task.TaskGuid)
+		Expect(err).NotTo(HaveOccurred())
+	}
+}
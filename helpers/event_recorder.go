@@ -0,0 +1,344 @@
+package helpers
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/events"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager"
+	"github.com/onsi/gomega"
+	"github.com/onsi/gomega/format"
+	"github.com/onsi/gomega/types"
+)
+
+// EventStream selects which BBS event subscription an EventRecorder reads.
+type EventStream int
+
+const (
+	// LegacyEventStream carries ActualLRPGroup-based LRP events.
+	LegacyEventStream EventStream = iota
+	// InstanceEventStream carries ActualLRPInstance* LRP events.
+	InstanceEventStream
+	// TaskEventStream carries Task events.
+	TaskEventStream
+)
+
+func (s EventStream) String() string {
+	switch s {
+	case LegacyEventStream:
+		return "legacy"
+	case InstanceEventStream:
+		return "instance"
+	case TaskEventStream:
+		return "task"
+	default:
+		return fmt.Sprintf("EventStream(%d)", int(s))
+	}
+}
+
+const eventRecorderResubscribeInterval = 100 * time.Millisecond
+
+type RecordedEvent struct {
+	Event      models.Event
+	ReceivedAt time.Time
+}
+
+// EventRecorder buffers every event the BBS sends on one stream for the
+// lifetime of a spec. When the subscription drops, e.g. because the BBS was
+// restarted, it resubscribes until Stop is called; events sent while no
+// subscription is open are lost.
+type EventRecorder struct {
+	logger lager.Logger
+	client bbs.Client
+	stream EventStream
+
+	lock        sync.Mutex
+	events      []RecordedEvent
+	source      events.EventSource
+	subscribes  int
+	stopped     bool
+	stoppedChan chan struct{}
+	done        chan struct{}
+}
+
+// NewEventRecorder subscribes to stream and starts recording. The initial
+// subscription must succeed. Call Stop, typically in an AfterEach, to close
+// it.
+func NewEventRecorder(logger lager.Logger, client bbs.Client, stream EventStream) *EventRecorder {
+	recorder := &EventRecorder{
+		logger:      logger.Session("event-recorder", lager.Data{"stream": stream.String()}),
+		client:      client,
+		stream:      stream,
+		stoppedChan: make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+
+	source, err := recorder.subscribe()
+	gomega.ExpectWithOffset(1, err).NotTo(gomega.HaveOccurred())
+	recorder.source = source
+	recorder.subscribes = 1
+
+	go recorder.record(source)
+
+	return recorder
+}
+
+func (r *EventRecorder) subscribe() (events.EventSource, error) {
+	switch r.stream {
+	case InstanceEventStream:
+		return r.client.SubscribeToInstanceEvents(r.logger)
+	case TaskEventStream:
+		return r.client.SubscribeToTaskEvents(r.logger)
+	default:
+		return r.client.SubscribeToEvents(r.logger)
+	}
+}
+
+func (r *EventRecorder) record(source events.EventSource) {
+	defer close(r.done)
+
+	for {
+		event, err := source.Next()
+		if err == nil {
+			r.lock.Lock()
+			r.events = append(r.events, RecordedEvent{Event: event, ReceivedAt: time.Now()})
+			r.lock.Unlock()
+			continue
+		}
+
+		source.Close()
+
+		source = r.resubscribe()
+		if source == nil {
+			return
+		}
+	}
+}
+
+// resubscribe retries until it subscribes or the recorder is stopped, in
+// which case it returns nil.
+func (r *EventRecorder) resubscribe() events.EventSource {
+	for {
+		select {
+		case <-r.stoppedChan:
+			return nil
+		case <-time.After(eventRecorderResubscribeInterval):
+		}
+
+		source, err := r.subscribe()
+		if err != nil {
+			r.logger.Debug("failed-to-resubscribe", lager.Data{"error": err.Error()})
+			continue
+		}
+
+		r.lock.Lock()
+		if r.stopped {
+			r.lock.Unlock()
+			source.Close()
+			return nil
+		}
+		r.source = source
+		r.subscribes++
+		r.lock.Unlock()
+
+		return source
+	}
+}
+
+// Stop closes the subscription and waits for recording to finish. Recorded
+// events remain available.
+func (r *EventRecorder) Stop() {
+	r.lock.Lock()
+	if r.stopped {
+		r.lock.Unlock()
+		return
+	}
+	r.stopped = true
+	close(r.stoppedChan)
+	r.source.Close()
+	r.lock.Unlock()
+
+	<-r.done
+}
+
+// Reconnects returns how many times the recorder has had to resubscribe.
+func (r *EventRecorder) Reconnects() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.subscribes - 1
+}
+
+// Recorded returns every event received so far, in order.
+func (r *EventRecorder) Recorded() []RecordedEvent {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	recorded := make([]RecordedEvent, len(r.events))
+	copy(recorded, r.events)
+	return recorded
+}
+
+// Events returns every event received so far, in order.
+func (r *EventRecorder) Events() []models.Event {
+	return eventsOf(r.Recorded())
+}
+
+// Mark returns a position that EventsSince can later read from.
+func (r *EventRecorder) Mark() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return len(r.events)
+}
+
+// EventsSince returns the events received after mark was taken.
+func (r *EventRecorder) EventsSince(mark int) []models.Event {
+	recorded := r.Recorded()
+	if mark > len(recorded) {
+		mark = len(recorded)
+	}
+	return eventsOf(recorded[mark:])
+}
+
+// NeverReceive asserts that no event matching matcher arrives during
+// window, starting now.
+func (r *EventRecorder) NeverReceive(matcher types.GomegaMatcher, window time.Duration) {
+	mark := r.Mark()
+	gomega.ConsistentlyWithOffset(1, func() []models.Event {
+		return r.EventsSince(mark)
+	}, window).ShouldNot(gomega.ContainElement(matcher))
+}
+
+func eventsOf(recorded []RecordedEvent) []models.Event {
+	events := make([]models.Event, len(recorded))
+	for i, r := range recorded {
+		events[i] = r.Event
+	}
+	return events
+}
+
+func recordedEvents(actual interface{}) ([]models.Event, error) {
+	switch actual := actual.(type) {
+	case *EventRecorder:
+		return actual.Events(), nil
+	case []models.Event:
+		return actual, nil
+	default:
+		return nil, fmt.Errorf("expected an *EventRecorder or []models.Event, got\n%s", format.Object(actual, 1))
+	}
+}
+
+// ReceiveInOrder succeeds when the recorded events contain, in order but
+// not necessarily adjacent, an event matching each matcher. Poll a recorder
+// with it:
+//
+//	Eventually(recorder).Should(helpers.ReceiveInOrder(
+//		helpers.MatchActualLRPInstanceCreatedEvent(processGuid, 0),
+//		helpers.MatchActualLRPInstanceChangedEvent(processGuid, 0, models.ActualLRPStateRunning),
+//	))
+func ReceiveInOrder(matchers ...types.GomegaMatcher) types.GomegaMatcher {
+	return &receiveInOrderMatcher{matchers: matchers}
+}
+
+type receiveInOrderMatcher struct {
+	matchers []types.GomegaMatcher
+}
+
+func (m *receiveInOrderMatcher) Match(actual interface{}) (bool, error) {
+	events, err := recordedEvents(actual)
+	if err != nil {
+		return false, err
+	}
+
+	matched, err := m.matchedInOrder(events)
+	if err != nil {
+		return false, err
+	}
+	return matched == len(m.matchers), nil
+}
+
+// matchedInOrder is how many of the matchers, from the first, match events
+// in order.
+func (m *receiveInOrderMatcher) matchedInOrder(events []models.Event) (int, error) {
+	matched := 0
+	for _, event := range events {
+		if matched == len(m.matchers) {
+			break
+		}
+
+		ok, err := m.matchers[matched].Match(event)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			matched++
+		}
+	}
+	return matched, nil
+}
+
+// FailureMessage works out what it reports from the events it lists, since
+// a recorder's events can change after Match.
+func (m *receiveInOrderMatcher) FailureMessage(actual interface{}) string {
+	events, _ := recordedEvents(actual)
+	matched, _ := m.matchedInOrder(events)
+	return fmt.Sprintf(
+		"Expected %d events in order, but only the first %d arrived in order. Events:\n%s",
+		len(m.matchers), matched, format.Object(events, 1),
+	)
+}
+
+func (m *receiveInOrderMatcher) NegatedFailureMessage(actual interface{}) string {
+	events, _ := recordedEvents(actual)
+	return fmt.Sprintf("Expected %d events not to arrive in order, but they did. Events:\n%s", len(m.matchers), format.Object(events, 1))
+}
+
+// ReceiveExactly succeeds when exactly count recorded events match
+// matcher. Pair it with Consistently to assert that no more arrive.
+func ReceiveExactly(count int, matcher types.GomegaMatcher) types.GomegaMatcher {
+	return &receiveExactlyMatcher{count: count, matcher: matcher}
+}
+
+type receiveExactlyMatcher struct {
+	count   int
+	matcher types.GomegaMatcher
+}
+
+func (m *receiveExactlyMatcher) Match(actual interface{}) (bool, error) {
+	events, err := recordedEvents(actual)
+	if err != nil {
+		return false, err
+	}
+
+	found, err := m.matching(events)
+	if err != nil {
+		return false, err
+	}
+	return found == m.count, nil
+}
+
+func (m *receiveExactlyMatcher) matching(events []models.Event) (int, error) {
+	found := 0
+	for _, event := range events {
+		ok, err := m.matcher.Match(event)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			found++
+		}
+	}
+	return found, nil
+}
+
+func (m *receiveExactlyMatcher) FailureMessage(actual interface{}) string {
+	events, _ := recordedEvents(actual)
+	found, _ := m.matching(events)
+	return fmt.Sprintf("Expected exactly %d matching events, got %d. Events:\n%s", m.count, found, format.Object(events, 1))
+}
+
+func (m *receiveExactlyMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected not to receive exactly %d matching events, but did", m.count)
+}
@@ -0,0 +1,70 @@
+package helpers_test
+
+import (
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/inigo/helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/types"
+)
+
+var _ = Describe("Event recorder matchers", func() {
+	taskCreated := func(guid string) models.Event {
+		return &models.TaskCreatedEvent{Task: &models.Task{TaskGuid: guid}}
+	}
+
+	Describe("ReceiveInOrder", func() {
+		matcher := func() types.GomegaMatcher {
+			return helpers.ReceiveInOrder(
+				helpers.MatchTaskCreatedEvent("first"),
+				helpers.MatchTaskCreatedEvent("second"),
+			)
+		}
+
+		It("matches events that arrive in order, with others in between", func() {
+			events := []models.Event{taskCreated("first"), taskCreated("other"), taskCreated("second")}
+			Expect(events).To(matcher())
+		})
+
+		It("does not match events that arrive out of order", func() {
+			events := []models.Event{taskCreated("second"), taskCreated("first")}
+			success, err := matcher().Match(events)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(success).To(BeFalse())
+		})
+
+		It("reports on the events it is given, whatever it matched before", func() {
+			m := matcher()
+
+			success, err := m.Match([]models.Event{taskCreated("first")})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(success).To(BeFalse())
+
+			Expect(m.FailureMessage([]models.Event{})).To(ContainSubstring("only the first 0 arrived in order"))
+			Expect(m.FailureMessage([]models.Event{taskCreated("first")})).To(ContainSubstring("only the first 1 arrived in order"))
+		})
+	})
+
+	Describe("ReceiveExactly", func() {
+		It("matches when exactly count events match", func() {
+			events := []models.Event{taskCreated("some-guid"), taskCreated("other"), taskCreated("some-guid")}
+			Expect(events).To(helpers.ReceiveExactly(2, helpers.MatchTaskCreatedEvent("some-guid")))
+			Expect(events).NotTo(helpers.ReceiveExactly(1, helpers.MatchTaskCreatedEvent("some-guid")))
+		})
+
+		It("reports on the events it is given, whatever it matched before", func() {
+			m := helpers.ReceiveExactly(1, helpers.MatchTaskCreatedEvent("some-guid"))
+
+			success, err := m.Match([]models.Event{taskCreated("some-guid"), taskCreated("some-guid")})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(success).To(BeFalse())
+
+			Expect(m.FailureMessage([]models.Event{})).To(ContainSubstring("got 0"))
+		})
+	})
+
+	It("rejects anything other than a recorder or a list of events", func() {
+		_, err := helpers.ReceiveExactly(1, helpers.MatchTaskCreatedEvent("some-guid")).Match("some-string")
+		Expect(err).To(HaveOccurred())
+	})
+})
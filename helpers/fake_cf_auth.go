@@ -0,0 +1,320 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sync"
+
+	sshproxyconfig "code.cloudfoundry.org/diego-ssh/cmd/ssh-proxy/config"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	FakeUAAClientID     = "ssh-proxy"
+	FakeUAAClientSecret = "ssh-proxy-secret"
+)
+
+// FakeUAA stands in for the UAA endpoints of the CF ssh auth flow: the
+// authorize endpoint that hands a logged-in user a one-time authorization
+// code for ssh-proxy, as `cf ssh-code` does, and the token endpoint that
+// ssh-proxy exchanges the code at for the user's access token.
+type FakeUAA struct {
+	server *httptest.Server
+	addr   string
+
+	lock     sync.Mutex
+	codes    map[string]string
+	expired  map[string]bool
+	requests int
+}
+
+func NewFakeUAA(listenHost string) *FakeUAA {
+	uaa := &FakeUAA{
+		codes:   map[string]string{},
+		expired: map[string]bool{},
+	}
+	uaa.server, uaa.addr = Callback(listenHost, uaa.handle)
+	return uaa
+}
+
+func (u *FakeUAA) handle(w http.ResponseWriter, r *http.Request) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	u.requests++
+
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/oauth/authorize":
+		u.authorize(w, r)
+	case r.Method == "POST" && r.URL.Path == "/oauth/token":
+		u.token(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// authorize issues a code for the bearer token the request carries, and
+// redirects to redirect_uri with it as UAA does.
+func (u *FakeUAA) authorize(w http.ResponseWriter, r *http.Request) {
+	var accessToken string
+	fmt.Sscanf(r.Header.Get("Authorization"), "bearer %s", &accessToken)
+	if accessToken == "" {
+		writeUAAError(w, http.StatusUnauthorized, "unauthorized", "Full authentication is required to access this resource")
+		return
+	}
+
+	query := r.URL.Query()
+	if query.Get("response_type") != "code" {
+		writeUAAError(w, http.StatusBadRequest, "unsupported_response_type", "expected a code response")
+		return
+	}
+	if query.Get("client_id") != FakeUAAClientID {
+		writeUAAError(w, http.StatusUnauthorized, "invalid_client", "No client with requested id: "+query.Get("client_id"))
+		return
+	}
+
+	redirect, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || query.Get("redirect_uri") == "" {
+		redirect = &url.URL{Path: "/login"}
+	}
+
+	code := GenerateGuid()
+	u.codes[code] = accessToken
+
+	values := redirect.Query()
+	values.Set("code", code)
+	redirect.RawQuery = values.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (u *FakeUAA) token(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok || clientID != FakeUAAClientID || clientSecret != FakeUAAClientSecret {
+		writeUAAError(w, http.StatusUnauthorized, "unauthorized", "Bad credentials")
+		return
+	}
+
+	Expect(r.ParseForm()).To(Succeed())
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeUAAError(w, http.StatusBadRequest, "unsupported_grant_type", "expected an authorization_code grant")
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	if u.expired[code] {
+		delete(u.expired, code)
+		writeUAAError(w, http.StatusBadRequest, "invalid_grant", "Authorization code has expired")
+		return
+	}
+
+	token, ok := u.codes[code]
+	if !ok {
+		writeUAAError(w, http.StatusBadRequest, "invalid_grant", "Invalid authorization code: "+code)
+		return
+	}
+
+	// codes are one-time use
+	delete(u.codes, code)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": token,
+		"token_type":   "bearer",
+		"expires_in":   600,
+	})
+}
+
+func writeUAAError(w http.ResponseWriter, status int, errorType, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":             errorType,
+		"error_description": description,
+	})
+}
+
+// IssueCode makes code exchangeable, once, for accessToken.
+func (u *FakeUAA) IssueCode(code, accessToken string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.codes[code] = accessToken
+}
+
+// ExpireCode makes the next exchange of code fail as expired.
+func (u *FakeUAA) ExpireCode(code string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	delete(u.codes, code)
+	u.expired[code] = true
+}
+
+// RequestSSHCode gets a one-time code for ssh-proxy from the authorize
+// endpoint on behalf of the user holding accessToken, the way `cf ssh-code`
+// does.
+func (u *FakeUAA) RequestSSHCode(accessToken string) (string, error) {
+	req, err := http.NewRequest("GET", u.AuthorizeURL(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.URL.RawQuery = url.Values{
+		"response_type": {"code"},
+		"client_id":     {FakeUAAClientID},
+	}.Encode()
+	req.Header.Set("Authorization", "bearer "+accessToken)
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusFound {
+		return "", fmt.Errorf("authorize responded with status %d", resp.StatusCode)
+	}
+
+	location, err := resp.Location()
+	if err != nil {
+		return "", err
+	}
+	code := location.Query().Get("code")
+	if code == "" {
+		return "", fmt.Errorf("authorize redirected to %s without a code", location)
+	}
+	return code, nil
+}
+
+func (u *FakeUAA) AuthorizeURL() string {
+	return "http://" + u.addr + "/oauth/authorize"
+}
+
+func (u *FakeUAA) TokenURL() string {
+	return "http://" + u.addr + "/oauth/token"
+}
+
+func (u *FakeUAA) RequestCount() int {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	return u.requests
+}
+
+func (u *FakeUAA) Close() {
+	u.server.Close()
+}
+
+var sshAccessPath = regexp.MustCompile(`^/internal/apps/([^/]+)/ssh_access/(\d+)$`)
+
+type ccGrant struct {
+	processGuid string
+	status      int
+}
+
+// FakeCC stands in for the Cloud Controller endpoint that ssh-proxy asks
+// whether a token may ssh into an app instance.
+type FakeCC struct {
+	server *httptest.Server
+	addr   string
+
+	lock     sync.Mutex
+	grants   map[string]ccGrant
+	requests []string
+}
+
+func NewFakeCC(listenHost string) *FakeCC {
+	cc := &FakeCC{grants: map[string]ccGrant{}}
+	cc.server, cc.addr = Callback(listenHost, cc.handle)
+	return cc
+}
+
+func ccGrantKey(accessToken, appGuid string) string {
+	return accessToken + "|" + appGuid
+}
+
+func (c *FakeCC) handle(w http.ResponseWriter, r *http.Request) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.requests = append(c.requests, r.URL.Path)
+
+	matches := sshAccessPath.FindStringSubmatch(r.URL.Path)
+	if r.Method != "GET" || matches == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var accessToken string
+	fmt.Sscanf(r.Header.Get("Authorization"), "bearer %s", &accessToken)
+
+	grant, ok := c.grants[ccGrantKey(accessToken, matches[1])]
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if grant.status != http.StatusOK {
+		w.WriteHeader(grant.status)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"process_guid": grant.processGuid,
+	})
+}
+
+// GrantSSHAccess lets accessToken ssh into every instance of appGuid, which
+// runs as the LRP processGuid.
+func (c *FakeCC) GrantSSHAccess(accessToken, appGuid, processGuid string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.grants[ccGrantKey(accessToken, appGuid)] = ccGrant{processGuid: processGuid, status: http.StatusOK}
+}
+
+// DenySSHAccess makes requests by accessToken for appGuid fail with status.
+func (c *FakeCC) DenySSHAccess(accessToken, appGuid string, status int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.grants[ccGrantKey(accessToken, appGuid)] = ccGrant{status: status}
+}
+
+func (c *FakeCC) URL() string {
+	return "http://" + c.addr
+}
+
+// Requests returns the paths of every request the fake has received.
+func (c *FakeCC) Requests() []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	requests := make([]string, len(c.requests))
+	copy(requests, c.requests)
+	return requests
+}
+
+func (c *FakeCC) Close() {
+	c.server.Close()
+}
+
+// CFSSHUser is the ssh-proxy username that authenticates with CF credentials
+// against the given app instance.
+func CFSSHUser(appGuid string, index int) string {
+	return fmt.Sprintf("cf:%s/%d", appGuid, index)
+}
+
+// SSHProxyCFAuth configures ssh-proxy to authenticate cf: users against the
+// given UAA and CC stand-ins.
+func SSHProxyCFAuth(uaa *FakeUAA, cc *FakeCC) func(*sshproxyconfig.SSHProxyConfig) {
+	return func(cfg *sshproxyconfig.SSHProxyConfig) {
+		cfg.EnableCFAuth = true
+		cfg.CCAPIURL = cc.URL()
+		cfg.UAATokenURL = uaa.TokenURL()
+		cfg.UAAUsername = FakeUAAClientID
+		cfg.UAAPassword = FakeUAAClientSecret
+	}
+}
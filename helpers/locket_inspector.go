@@ -0,0 +1,164 @@
+package helpers
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	locketmodels "code.cloudfoundry.org/locket/models"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// LocketResource is a lock or presence as locket stores it.
+type LocketResource struct {
+	Key   string
+	Owner string
+	Value string
+	Type  locketmodels.TypeCode
+	TTL   time.Duration
+}
+
+// LocketInspector looks at and tampers with the locks and presences in
+// locket. Locket's API does not return TTLs, so those are read from its
+// table.
+type LocketInspector struct {
+	logger lager.Logger
+	client locketmodels.LocketClient
+	db     *sql.DB
+}
+
+func NewLocketInspector(logger lager.Logger, client locketmodels.LocketClient, db *sql.DB) *LocketInspector {
+	return &LocketInspector{
+		logger: logger.Session("locket-inspector"),
+		client: client,
+		db:     db,
+	}
+}
+
+// Locks returns every lock, sorted by key.
+func (i *LocketInspector) Locks() []LocketResource {
+	return i.fetchAll(locketmodels.LOCK)
+}
+
+// Presences returns every presence, such as the reps' cell registrations,
+// sorted by key.
+func (i *LocketInspector) Presences() []LocketResource {
+	return i.fetchAll(locketmodels.PRESENCE)
+}
+
+// PresenceKeys returns a poller for the keys of every presence.
+func (i *LocketInspector) PresenceKeys() func() []string {
+	return func() []string {
+		keys := []string{}
+		for _, presence := range i.Presences() {
+			keys = append(keys, presence.Key)
+		}
+		return keys
+	}
+}
+
+// Resource returns the lock or presence at key, and false if there is none.
+func (i *LocketInspector) Resource(key string) (LocketResource, bool) {
+	response, err := i.client.Fetch(context.Background(), &locketmodels.FetchRequest{Key: key})
+	if grpc.Code(err) == codes.NotFound {
+		return LocketResource{}, false
+	}
+	Expect(err).NotTo(HaveOccurred())
+
+	resource := i.fromModel(response.Resource)
+	if resource.TTL == 0 {
+		// the row went away between the two reads
+		return LocketResource{}, false
+	}
+	return resource, true
+}
+
+// LockOwner returns a poller for the owner of the lock at key, which is ""
+// while nobody holds it:
+//
+//	Eventually(inspector.LockOwner("bbs")).Should(Equal("bbs-inigo-lock-owner"))
+func (i *LocketInspector) LockOwner(key string) func() string {
+	return func() string {
+		resource, _ := i.Resource(key)
+		return resource.Owner
+	}
+}
+
+// LockTTL returns a poller for the TTL of the lock at key, which is 0 while
+// nobody holds it.
+func (i *LocketInspector) LockTTL(key string) func() time.Duration {
+	return func() time.Duration {
+		resource, _ := i.Resource(key)
+		return resource.TTL
+	}
+}
+
+// Release removes the lock or presence at key on behalf of whoever holds
+// it. A component still running will try to take it again.
+func (i *LocketInspector) Release(key string) {
+	resource, ok := i.Resource(key)
+	Expect(ok).To(BeTrue(), "nothing in locket at %s", key)
+
+	i.logger.Info("releasing", lager.Data{"key": key, "owner": resource.Owner})
+	_, err := i.client.Release(context.Background(), &locketmodels.ReleaseRequest{
+		Resource: &locketmodels.Resource{Key: key, Owner: resource.Owner, TypeCode: resource.Type},
+	})
+	Expect(err).NotTo(HaveOccurred())
+}
+
+// Steal takes the lock at key for owner, releasing it first if someone
+// else holds it. The previous holder finds out the next time it renews the
+// lock; most Diego components exit when that happens.
+func (i *LocketInspector) Steal(key, owner string, ttl time.Duration) {
+	i.logger.Info("stealing", lager.Data{"key": key, "owner": owner})
+	Eventually(func() error {
+		if resource, ok := i.Resource(key); ok && resource.Owner != owner {
+			i.client.Release(context.Background(), &locketmodels.ReleaseRequest{
+				Resource: &locketmodels.Resource{Key: key, Owner: resource.Owner, TypeCode: locketmodels.LOCK},
+			})
+		}
+
+		_, err := i.client.Lock(context.Background(), &locketmodels.LockRequest{
+			Resource:     &locketmodels.Resource{Key: key, Owner: owner, TypeCode: locketmodels.LOCK},
+			TtlInSeconds: int64(ttl / time.Second),
+		})
+		return err
+	}).Should(Succeed())
+}
+
+func (i *LocketInspector) fetchAll(typeCode locketmodels.TypeCode) []LocketResource {
+	response, err := i.client.FetchAll(context.Background(), &locketmodels.FetchAllRequest{TypeCode: typeCode})
+	Expect(err).NotTo(HaveOccurred())
+
+	resources := []LocketResource{}
+	for _, resource := range response.Resources {
+		resources = append(resources, i.fromModel(resource))
+	}
+	sort.Slice(resources, func(a, b int) bool { return resources[a].Key < resources[b].Key })
+
+	return resources
+}
+
+func (i *LocketInspector) fromModel(resource *locketmodels.Resource) LocketResource {
+	return LocketResource{
+		Key:   resource.Key,
+		Owner: resource.Owner,
+		Value: resource.Value,
+		Type:  locketmodels.GetResource(resource).TypeCode,
+		TTL:   i.ttl(resource.Key),
+	}
+}
+
+func (i *LocketInspector) ttl(key string) time.Duration {
+	var seconds int64
+	err := i.db.QueryRow(rebind("SELECT ttl FROM locks WHERE path = ?"), key).Scan(&seconds)
+	if err == sql.ErrNoRows {
+		return 0
+	}
+	Expect(err).NotTo(HaveOccurred())
+	return time.Duration(seconds) * time.Second
+}
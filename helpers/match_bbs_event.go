@@ -2,45 +2,283 @@ package helpers
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
+	"strings"
 
 	"code.cloudfoundry.org/bbs/models"
 	"github.com/onsi/gomega"
 	"github.com/onsi/gomega/format"
+	"github.com/onsi/gomega/types"
 )
 
-//
+// EventFields maps a dotted field path on an event, such as
+// "After.Instance.State" or "DesiredLrp.Instances", to the matcher its value
+// must satisfy. Fields promoted from embedded structs (e.g. "ProcessGuid" on
+// an ActualLRPCrashedEvent) can be named directly, and nil pointers along
+// the path fail the match.
+type EventFields map[string]types.GomegaMatcher
 
-func MatchActualLRPCrashedEvent(processGuid, instanceGuid, cellId string, index int) gomega.OmegaMatcher {
-	return &ActualLRPCrashedEventMatcher{
-		ProcessGuid:  processGuid,
-		Index:        index,
-		InstanceGuid: instanceGuid,
-		CellId:       cellId,
+// MatchEvent matches a models.Event of the same concrete type as example
+// whose fields satisfy every matcher in fields.
+func MatchEvent(example models.Event, fields ...EventFields) types.GomegaMatcher {
+	matcher := &EventMatcher{
+		EventType: reflect.TypeOf(example),
+		Fields:    EventFields{},
 	}
+	for _, f := range fields {
+		for path, m := range f {
+			matcher.Fields[path] = m
+		}
+	}
+	return matcher
+}
+
+func MatchDesiredLRPCreatedEvent(processGuid string, fields ...EventFields) types.GomegaMatcher {
+	return MatchEvent(&models.DesiredLRPCreatedEvent{}, append([]EventFields{{
+		"DesiredLrp.ProcessGuid": gomega.Equal(processGuid),
+	}}, fields...)...)
+}
+
+func MatchDesiredLRPChangedEvent(processGuid string, fields ...EventFields) types.GomegaMatcher {
+	return MatchEvent(&models.DesiredLRPChangedEvent{}, append([]EventFields{{
+		"After.ProcessGuid": gomega.Equal(processGuid),
+	}}, fields...)...)
+}
+
+func MatchDesiredLRPRemovedEvent(processGuid string, fields ...EventFields) types.GomegaMatcher {
+	return MatchEvent(&models.DesiredLRPRemovedEvent{}, append([]EventFields{{
+		"DesiredLrp.ProcessGuid": gomega.Equal(processGuid),
+	}}, fields...)...)
+}
+
+func MatchActualLRPCreatedEvent(processGuid string, index int, fields ...EventFields) types.GomegaMatcher {
+	return MatchEvent(&models.ActualLRPCreatedEvent{}, append([]EventFields{{
+		"ActualLrpGroup.Instance.ProcessGuid": gomega.Equal(processGuid),
+		"ActualLrpGroup.Instance.Index":       gomega.BeEquivalentTo(index),
+	}}, fields...)...)
+}
+
+// MatchActualLRPChangedEvent matches a legacy group change whose instance
+// ends up in state.
+func MatchActualLRPChangedEvent(processGuid string, index int, state string, fields ...EventFields) types.GomegaMatcher {
+	return MatchEvent(&models.ActualLRPChangedEvent{}, append([]EventFields{{
+		"After.Instance.ProcessGuid": gomega.Equal(processGuid),
+		"After.Instance.Index":       gomega.BeEquivalentTo(index),
+		"After.Instance.State":       gomega.Equal(state),
+	}}, fields...)...)
+}
+
+func MatchActualLRPRemovedEvent(processGuid string, index int, fields ...EventFields) types.GomegaMatcher {
+	return MatchEvent(&models.ActualLRPRemovedEvent{}, append([]EventFields{{
+		"ActualLrpGroup.Instance.ProcessGuid": gomega.Equal(processGuid),
+		"ActualLrpGroup.Instance.Index":       gomega.BeEquivalentTo(index),
+	}}, fields...)...)
+}
+
+func MatchActualLRPCrashedEvent(processGuid, instanceGuid, cellId string, index int, fields ...EventFields) types.GomegaMatcher {
+	return MatchEvent(&models.ActualLRPCrashedEvent{}, append([]EventFields{{
+		"ProcessGuid":  gomega.Equal(processGuid),
+		"Index":        gomega.BeEquivalentTo(index),
+		"InstanceGuid": gomega.Equal(instanceGuid),
+		"CellId":       gomega.Equal(cellId),
+	}}, fields...)...)
 }
 
-type ActualLRPCrashedEventMatcher struct {
-	ProcessGuid  string
-	InstanceGuid string
-	CellId       string
-	Index        int
+func MatchActualLRPInstanceCreatedEvent(processGuid string, index int, fields ...EventFields) types.GomegaMatcher {
+	return MatchEvent(&models.ActualLRPInstanceCreatedEvent{}, append([]EventFields{{
+		"ActualLrp.ProcessGuid": gomega.Equal(processGuid),
+		"ActualLrp.Index":       gomega.BeEquivalentTo(index),
+	}}, fields...)...)
 }
 
-func (matcher *ActualLRPCrashedEventMatcher) Match(actual interface{}) (success bool, err error) {
-	event, ok := actual.(*models.ActualLRPCrashedEvent)
-	if !ok {
+// MatchActualLRPInstanceChangedEvent matches an instance change that ends
+// in state.
+func MatchActualLRPInstanceChangedEvent(processGuid string, index int, state string, fields ...EventFields) types.GomegaMatcher {
+	return MatchEvent(&models.ActualLRPInstanceChangedEvent{}, append([]EventFields{{
+		"ProcessGuid": gomega.Equal(processGuid),
+		"Index":       gomega.BeEquivalentTo(index),
+		"After.State": gomega.Equal(state),
+	}}, fields...)...)
+}
+
+func MatchActualLRPInstanceRemovedEvent(processGuid string, index int, fields ...EventFields) types.GomegaMatcher {
+	return MatchEvent(&models.ActualLRPInstanceRemovedEvent{}, append([]EventFields{{
+		"ActualLrp.ProcessGuid": gomega.Equal(processGuid),
+		"ActualLrp.Index":       gomega.BeEquivalentTo(index),
+	}}, fields...)...)
+}
+
+func MatchTaskCreatedEvent(taskGuid string, fields ...EventFields) types.GomegaMatcher {
+	return MatchEvent(&models.TaskCreatedEvent{}, append([]EventFields{{
+		"Task.TaskGuid": gomega.Equal(taskGuid),
+	}}, fields...)...)
+}
+
+// MatchTaskChangedEvent matches a task change that ends in state.
+func MatchTaskChangedEvent(taskGuid string, state models.Task_State, fields ...EventFields) types.GomegaMatcher {
+	return MatchEvent(&models.TaskChangedEvent{}, append([]EventFields{{
+		"After.TaskGuid": gomega.Equal(taskGuid),
+		"After.State":    gomega.Equal(state),
+	}}, fields...)...)
+}
+
+func MatchTaskRemovedEvent(taskGuid string, fields ...EventFields) types.GomegaMatcher {
+	return MatchEvent(&models.TaskRemovedEvent{}, append([]EventFields{{
+		"Task.TaskGuid": gomega.Equal(taskGuid),
+	}}, fields...)...)
+}
+
+type EventMatcher struct {
+	EventType reflect.Type
+	Fields    EventFields
+}
+
+// fieldResult is how one field of an event fared against its matcher.
+type fieldResult struct {
+	path    string
+	value   interface{}
+	failure string
+}
+
+func (matcher *EventMatcher) Match(actual interface{}) (success bool, err error) {
+	if actual == nil || reflect.TypeOf(actual) != matcher.EventType {
 		return false, nil
 	}
-	return event.ProcessGuid == matcher.ProcessGuid &&
-		event.Index == int32(matcher.Index) &&
-		event.CellId == matcher.CellId &&
-		event.InstanceGuid == matcher.InstanceGuid, nil
+
+	results, err := matcher.matchFields(actual)
+	if err != nil {
+		return false, err
+	}
+
+	for _, result := range results {
+		if result.failure != "" {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// matchFields runs every field matcher against actual, in path order. It
+// keeps no state, so the failure messages always describe the event they
+// are given rather than the one last matched.
+func (matcher *EventMatcher) matchFields(actual interface{}) ([]fieldResult, error) {
+	paths := make([]string, 0, len(matcher.Fields))
+	for path := range matcher.Fields {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	results := make([]fieldResult, 0, len(paths))
+	for _, path := range paths {
+		value, err := eventField(actual, path)
+		if err != nil {
+			results = append(results, fieldResult{path: path, failure: err.Error()})
+			continue
+		}
+
+		m := matcher.Fields[path]
+		ok, err := m.Match(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+
+		result := fieldResult{path: path, value: value}
+		if !ok {
+			result.failure = m.FailureMessage(value)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// FailureMessage lists every matched field of the event, marking the ones
+// that differ with "-" and giving their actual value and what their
+// matcher expected of it.
+func (matcher *EventMatcher) FailureMessage(actual interface{}) (message string) {
+	if actual == nil || reflect.TypeOf(actual) != matcher.EventType {
+		return fmt.Sprintf("Expected\n%s\nto be a %s", format.Object(actual, 1), matcher.EventType)
+	}
+
+	results, err := matcher.matchFields(actual)
+	if err != nil {
+		return err.Error()
+	}
+
+	lines := []string{fmt.Sprintf("Expected %s to match all fields, but:", matcher.EventType)}
+	for _, result := range results {
+		if result.failure == "" {
+			lines = append(lines, fmt.Sprintf("  %s", result.path))
+			continue
+		}
+
+		lines = append(lines, fmt.Sprintf("- %s", result.path))
+		if result.value != nil {
+			lines = append(lines, fmt.Sprintf("    actual: %s", format.Object(result.value, 0)))
+		}
+		lines = append(lines, indent(result.failure, "    "))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (matcher *EventMatcher) NegatedFailureMessage(actual interface{}) (message string) {
+	paths := make([]string, 0, len(matcher.Fields))
+	for path := range matcher.Fields {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	return fmt.Sprintf("Expected\n%s\nnot to be a %s matching fields %s", format.Object(actual, 1), matcher.EventType, strings.Join(paths, ", "))
 }
 
-func (matcher *ActualLRPCrashedEventMatcher) FailureMessage(actual interface{}) (message string) {
-	return fmt.Sprintf("Expected\n%s\nto be a ActualLRPCrashedEvent with\n  ProcessGuid=%s\n  Index=%d", format.Object(actual, 1), matcher.ProcessGuid, matcher.Index)
+// eventField walks path through event's fields. Unexported fields can't be
+// read through reflection, so naming one is an error.
+func eventField(event interface{}, path string) (interface{}, error) {
+	value := reflect.ValueOf(event)
+	walked := []string{"event"}
+
+	for _, name := range strings.Split(path, ".") {
+		for value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				return nil, fmt.Errorf("%s is nil", strings.Join(walked, "."))
+			}
+			value = value.Elem()
+		}
+
+		if value.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("%s is a %s, not a struct", strings.Join(walked, "."), value.Type())
+		}
+
+		field, found := value.Type().FieldByName(name)
+		if !found {
+			return nil, fmt.Errorf("no field %s on %s", name, strings.Join(walked, "."))
+		}
+		if field.PkgPath != "" {
+			return nil, fmt.Errorf("field %s on %s is unexported", name, strings.Join(walked, "."))
+		}
+
+		// a promoted field is reached through its embedded structs, any of
+		// which may be a nil pointer
+		for i, index := range field.Index {
+			if i > 0 && value.Kind() == reflect.Ptr {
+				if value.IsNil() {
+					return nil, fmt.Errorf("%s is nil", strings.Join(append(walked, value.Type().Elem().Name()), "."))
+				}
+				value = value.Elem()
+			}
+			value = value.Field(index)
+		}
+		walked = append(walked, name)
+	}
+
+	if !value.CanInterface() {
+		return nil, fmt.Errorf("%s can't be read", strings.Join(walked, "."))
+	}
+	return value.Interface(), nil
 }
 
-func (matcher *ActualLRPCrashedEventMatcher) NegatedFailureMessage(actual interface{}) (message string) {
-	return fmt.Sprintf("Expected\n%s\nnot to be a ActualLRPCrashedEvent with\n  ProcessGuid=%s\n  Index=%d", format.Object(actual, 1), matcher.ProcessGuid, matcher.Index)
+func indent(s, prefix string) string {
+	return prefix + strings.Replace(s, "\n", "\n"+prefix, -1)
 }
@@ -0,0 +1,92 @@
+package helpers_test
+
+import (
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/inigo/helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/types"
+)
+
+// secretEvent is an event with a field the matcher can't read
+type secretEvent struct {
+	models.DesiredLRPCreatedEvent
+	secret string
+}
+
+var _ = Describe("MatchEvent", func() {
+	var matcher types.GomegaMatcher
+
+	taskChanged := func(guid string, state models.Task_State) *models.TaskChangedEvent {
+		return &models.TaskChangedEvent{
+			Before: &models.Task{TaskGuid: guid, State: models.Task_Pending},
+			After:  &models.Task{TaskGuid: guid, State: state},
+		}
+	}
+
+	BeforeEach(func() {
+		matcher = helpers.MatchTaskChangedEvent("some-guid", models.Task_Completed)
+	})
+
+	It("matches an event of the same type whose fields match", func() {
+		Expect(taskChanged("some-guid", models.Task_Completed)).To(matcher)
+	})
+
+	It("does not match an event whose fields differ", func() {
+		Expect(taskChanged("some-guid", models.Task_Running)).NotTo(matcher)
+		Expect(taskChanged("other-guid", models.Task_Completed)).NotTo(matcher)
+	})
+
+	It("does not match events of other types", func() {
+		Expect(&models.TaskCreatedEvent{Task: &models.Task{TaskGuid: "some-guid"}}).NotTo(matcher)
+		Expect(nil).NotTo(matcher)
+	})
+
+	It("names fields promoted from embedded structs directly", func() {
+		event := &models.ActualLRPCrashedEvent{
+			ActualLRPKey:         models.NewActualLRPKey("some-guid", 1, "inigo"),
+			ActualLRPInstanceKey: models.NewActualLRPInstanceKey("some-instance", "some-cell"),
+		}
+		Expect(event).To(helpers.MatchActualLRPCrashedEvent("some-guid", "some-instance", "some-cell", 1))
+	})
+
+	It("fails the field when a pointer on its path is nil", func() {
+		event := &models.TaskChangedEvent{}
+		success, err := matcher.Match(event)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(success).To(BeFalse())
+		Expect(matcher.FailureMessage(event)).To(ContainSubstring("event.After is nil"))
+	})
+
+	It("rejects unexported fields instead of panicking", func() {
+		event := &secretEvent{secret: "shh"}
+		matcher := helpers.MatchEvent(&secretEvent{}, helpers.EventFields{"secret": Equal("shh")})
+
+		success, err := matcher.Match(event)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(success).To(BeFalse())
+		Expect(matcher.FailureMessage(event)).To(ContainSubstring("field secret on event is unexported"))
+	})
+
+	Describe("the failure message", func() {
+		It("lists each field, marking and explaining the ones that differ", func() {
+			event := taskChanged("some-guid", models.Task_Running)
+			Expect(matcher.Match(event)).To(BeFalse())
+
+			message := matcher.FailureMessage(event)
+			Expect(message).To(ContainSubstring("  After.TaskGuid\n"))
+			Expect(message).To(ContainSubstring("- After.State\n    actual: <models.Task_State>"))
+			Expect(message).NotTo(ContainSubstring("- After.TaskGuid"))
+		})
+
+		It("describes the event it is given, not the one last matched", func() {
+			bad := taskChanged("some-guid", models.Task_Running)
+			good := taskChanged("some-guid", models.Task_Completed)
+
+			Expect(matcher.Match(bad)).To(BeFalse())
+			Expect(matcher.Match(good)).To(BeTrue())
+
+			Expect(matcher.FailureMessage(bad)).To(ContainSubstring("- After.State"))
+		})
+	})
+})
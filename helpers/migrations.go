@@ -0,0 +1,114 @@
+package helpers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"sort"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/migrations"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/inigo/world"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/routing-info/cfroutes"
+	. "github.com/onsi/gomega"
+)
+
+// BBSRecords is everything a BBS serves about LRPs and tasks, sorted by
+// guid, so that what two BBSes make of one database can be compared.
+type BBSRecords struct {
+	DesiredLRPs []*models.DesiredLRP
+	ActualLRPs  []*models.ActualLRP
+	Tasks       []*models.Task
+}
+
+// SeedBBS desires, through client, LRPs and tasks that between them fill in
+// the fields the BBS stores in each of its columns. Nothing runs them.
+func SeedBBS(logger lager.Logger, client bbs.InternalClient, addresses world.ComponentAddresses) {
+	UpsertInigoDomain(logger, client)
+
+	routes := cfroutes.CFRoutes{{Hostnames: []string{"seeded-lrp"}, Port: 8080}}.RoutingInfo()
+	lrps := []*models.DesiredLRP{
+		NewLRP(addresses, GenerateGuid()),
+		NewLRP(addresses, GenerateGuid(),
+			LRPInstances(3),
+			LRPRoutes(&routes),
+			LRPEnv(&models.EnvironmentVariable{Name: "SEEDED", Value: "true"}),
+			LRPPlacementTags("seeded"),
+			LRPMemoryMB(128),
+			LRPDiskMB(256),
+		),
+		DockerLRPCreateRequest(addresses, GenerateGuid()),
+	}
+	for _, lrp := range lrps {
+		Expect(client.DesireLRP(logger, lrp)).To(Succeed())
+	}
+
+	tasks := []*models.Task{
+		NewTask(GenerateGuid(), &models.RunAction{User: "vcap", Path: "true"}),
+		NewTask(GenerateGuid(), &models.RunAction{User: "vcap", Path: "sh", Args: []string{"-c", "echo seeded > /tmp/result"}},
+			TaskResultFile("/tmp/result"),
+			TaskEnv(&models.EnvironmentVariable{Name: "SEEDED", Value: "true"}),
+			TaskMemoryMB(64),
+			TaskPrivileged(),
+		),
+	}
+	for _, task := range tasks {
+		Expect(client.DesireTask(logger, task.TaskGuid, task.Domain, task.TaskDefinition)).To(Succeed())
+	}
+}
+
+// FetchBBSRecords reads every desired LRP, actual LRP and task from client.
+func FetchBBSRecords(logger lager.Logger, client bbs.InternalClient) BBSRecords {
+	desiredLRPs, err := client.DesiredLRPs(logger, models.DesiredLRPFilter{})
+	Expect(err).NotTo(HaveOccurred())
+	sort.Slice(desiredLRPs, func(i, j int) bool { return desiredLRPs[i].ProcessGuid < desiredLRPs[j].ProcessGuid })
+
+	actualLRPs, err := client.ActualLRPs(logger, models.ActualLRPFilter{})
+	Expect(err).NotTo(HaveOccurred())
+	sort.Slice(actualLRPs, func(i, j int) bool {
+		if actualLRPs[i].ProcessGuid != actualLRPs[j].ProcessGuid {
+			return actualLRPs[i].ProcessGuid < actualLRPs[j].ProcessGuid
+		}
+		return actualLRPs[i].Index < actualLRPs[j].Index
+	})
+
+	tasks, err := client.Tasks(logger)
+	Expect(err).NotTo(HaveOccurred())
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].TaskGuid < tasks[j].TaskGuid })
+
+	return BBSRecords{
+		DesiredLRPs: desiredLRPs,
+		ActualLRPs:  actualLRPs,
+		Tasks:       tasks,
+	}
+}
+
+// BBSSchemaVersion returns the version of the last migration the BBS ran
+// against db, or 0 if it has not run any.
+func BBSSchemaVersion(db *sql.DB) int64 {
+	var value string
+	err := db.QueryRow("SELECT value FROM configurations WHERE id = 'version'").Scan(&value)
+	if err == sql.ErrNoRows {
+		return 0
+	}
+	Expect(err).NotTo(HaveOccurred())
+
+	var version struct {
+		CurrentVersion int64 `json:"current_version"`
+	}
+	Expect(json.Unmarshal([]byte(value), &version)).To(Succeed())
+	return version.CurrentVersion
+}
+
+// LatestBBSSchemaVersion returns the version of the newest migration the
+// BBS in this tree knows about.
+func LatestBBSSchemaVersion() int64 {
+	var latest int64
+	for _, migration := range migrations.AllMigrations() {
+		if migration.Version() > latest {
+			latest = migration.Version()
+		}
+	}
+	return latest
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+// +build !windows
+
+package helpers
+
+import (
+	"syscall"
+
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit/ginkgomon"
+)
+
+// PauseProcess stops the process started by runner with SIGSTOP, so that it
+// does nothing at all until ResumeProcess. Unlike a partition, the process
+// carries on from where it was once resumed.
+func PauseProcess(runner *ginkgomon.Runner) {
+	Expect(runner.Command.Process).NotTo(BeNil(), "%s has not been started", runner.Name)
+	Expect(runner.Command.Process.Signal(syscall.SIGSTOP)).To(Succeed())
+}
+
+// ResumeProcess continues a process paused by PauseProcess. It is safe to
+// call on a process that isn't paused or has exited.
+func ResumeProcess(runner *ginkgomon.Runner) {
+	if runner.Command.Process != nil {
+		runner.Command.Process.Signal(syscall.SIGCONT)
+	}
+}
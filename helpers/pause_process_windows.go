@@ -0,0 +1,12 @@
+package helpers
+
+import (
+	. "github.com/onsi/ginkgo"
+	"github.com/tedsuo/ifrit/ginkgomon"
+)
+
+func PauseProcess(runner *ginkgomon.Runner) {
+	Fail("pausing processes is not supported on windows")
+}
+
+func ResumeProcess(runner *ginkgomon.Runner) {}
@@ -0,0 +1 @@
+package perfbaseline // import "code.cloudfoundry.org/inigo/helpers/perfbaseline"
@@ -0,0 +1,399 @@
+package perfbaseline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tedsuo/ifrit"
+)
+
+// Measurement is the typical duration of one named operation, the median of
+// the samples it was recorded from.
+type Measurement struct {
+	Duration time.Duration `json:"duration_ns"`
+	Samples  int           `json:"samples"`
+}
+
+// Baseline is a set of named measurements, stored as JSON.
+type Baseline struct {
+	Measurements map[string]Measurement `json:"measurements"`
+}
+
+// Load reads a baseline written by Save.
+func Load(path string) (Baseline, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Baseline{}, err
+	}
+
+	var baseline Baseline
+	err = json.Unmarshal(contents, &baseline)
+	if err != nil {
+		return Baseline{}, fmt.Errorf("invalid baseline %s: %s", path, err)
+	}
+	if baseline.Measurements == nil {
+		baseline.Measurements = map[string]Measurement{}
+	}
+
+	return baseline, nil
+}
+
+// Save writes the baseline to path, replacing any existing file.
+func (b Baseline) Save(path string) error {
+	contents, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(contents, '\n'), 0644)
+}
+
+const (
+	mergeLockRetryInterval = 10 * time.Millisecond
+	mergeLockTimeout       = 30 * time.Second
+
+	// a merge takes milliseconds, so a lock this old was left behind
+	mergeLockStaleAfter = 10 * time.Second
+)
+
+// MergeInto adds b's measurements to the baseline at path, replacing any of
+// the same name and creating the file if needed. Concurrent callers, such as
+// parallel ginkgo nodes, are serialised with a lock file next to path. A
+// lock left behind by a crashed caller, whose process is gone or which is
+// older than any merge takes, is taken over.
+func (b Baseline) MergeInto(path string) error {
+	lockPath := path + ".lock"
+
+	deadline := time.Now().Add(mergeLockTimeout)
+	for {
+		lock, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(lock, "%d\n", os.Getpid())
+			lock.Close()
+			break
+		}
+		if !os.IsExist(err) {
+			return err
+		}
+		if staleLock(lockPath) {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for %s", lockPath)
+		}
+		time.Sleep(mergeLockRetryInterval)
+	}
+	defer os.Remove(lockPath)
+
+	merged, err := Load(path)
+	if os.IsNotExist(err) {
+		merged = Baseline{Measurements: map[string]Measurement{}}
+	} else if err != nil {
+		return err
+	}
+
+	for name, m := range b.Measurements {
+		merged.Measurements[name] = m
+	}
+
+	return merged.Save(path)
+}
+
+// staleLock reports whether the lock file at path was left behind: its
+// holder's process no longer exists, or it is older than any merge takes. A
+// lock whose holder hasn't written its PID yet is judged by age alone.
+func staleLock(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		// gone already; the next attempt will take it
+		return false
+	}
+	if time.Since(info.ModTime()) > mergeLockStaleAfter {
+		return true
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(contents)))
+	if err != nil {
+		return false
+	}
+	return !processExists(pid)
+}
+
+// Recorder collects duration samples by name from concurrent callers.
+type Recorder struct {
+	lock    sync.Mutex
+	samples map[string][]time.Duration
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{samples: map[string][]time.Duration{}}
+}
+
+func (r *Recorder) Record(name string, d time.Duration) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.samples[name] = append(r.samples[name], d)
+}
+
+// Time runs f and records how long it took under name.
+func (r *Recorder) Time(name string, f func()) {
+	start := time.Now()
+	f()
+	r.Record(name, time.Since(start))
+}
+
+// Since records the time elapsed since start under name.
+func (r *Recorder) Since(name string, start time.Time) {
+	r.Record(name, time.Since(start))
+}
+
+// Baseline summarises every name recorded so far by its median sample.
+func (r *Recorder) Baseline() Baseline {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	baseline := Baseline{Measurements: map[string]Measurement{}}
+	for name, samples := range r.samples {
+		sorted := make([]time.Duration, len(samples))
+		copy(sorted, samples)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+		baseline.Measurements[name] = Measurement{
+			Duration: sorted[(len(sorted)-1)/2],
+			Samples:  len(sorted),
+		}
+	}
+
+	return baseline
+}
+
+// TimedRunner wraps runner so that the time from being started to becoming
+// ready is recorded under name, e.g. "bbs-start".
+func TimedRunner(recorder *Recorder, name string, runner ifrit.Runner) ifrit.Runner {
+	return ifrit.RunFunc(func(signals <-chan os.Signal, ready chan<- struct{}) error {
+		start := time.Now()
+		innerReady := make(chan struct{})
+		exited := make(chan struct{})
+		defer close(exited)
+
+		go func() {
+			select {
+			case <-innerReady:
+				recorder.Since(name, start)
+				close(ready)
+			case <-exited:
+			}
+		}()
+
+		return runner.Run(signals, innerReady)
+	})
+}
+
+// Tolerance is how much slower than its baseline a measurement may be:
+// anything above baseline*(1+Relative)+Absolute is a regression. The
+// absolute slack keeps very short operations from flapping.
+type Tolerance struct {
+	Relative float64
+	Absolute time.Duration
+}
+
+func (t Tolerance) limit(baseline time.Duration) time.Duration {
+	return time.Duration(float64(baseline)*(1+t.Relative)) + t.Absolute
+}
+
+type Status string
+
+const (
+	StatusOK        Status = "ok"
+	StatusRegressed Status = "REGRESSED"
+	StatusImproved  Status = "improved"
+	StatusNew       Status = "new"
+)
+
+type Result struct {
+	Name     string
+	Baseline time.Duration
+	Current  time.Duration
+	Limit    time.Duration
+	Status   Status
+}
+
+// Change is the relative difference from the baseline, e.g. 0.25 for 25%
+// slower.
+func (r Result) Change() float64 {
+	if r.Baseline == 0 {
+		return 0
+	}
+	return float64(r.Current-r.Baseline) / float64(r.Baseline)
+}
+
+type Comparison struct {
+	Results []Result
+}
+
+// Compare checks every measurement in current against baseline. Names
+// without a baseline are reported as new; names only in the baseline are
+// ignored, since a run may cover only part of a suite. overrides replaces
+// the default tolerance for individual names.
+func Compare(baseline, current Baseline, tolerance Tolerance, overrides map[string]Tolerance) Comparison {
+	names := make([]string, 0, len(current.Measurements))
+	for name := range current.Measurements {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	comparison := Comparison{}
+	for _, name := range names {
+		result := Result{
+			Name:    name,
+			Current: current.Measurements[name].Duration,
+		}
+
+		base, ok := baseline.Measurements[name]
+		if !ok {
+			result.Status = StatusNew
+			comparison.Results = append(comparison.Results, result)
+			continue
+		}
+
+		t := tolerance
+		if override, ok := overrides[name]; ok {
+			t = override
+		}
+
+		result.Baseline = base.Duration
+		result.Limit = t.limit(base.Duration)
+
+		switch {
+		case result.Current > result.Limit:
+			result.Status = StatusRegressed
+		case result.Current < result.Baseline:
+			result.Status = StatusImproved
+		default:
+			result.Status = StatusOK
+		}
+
+		comparison.Results = append(comparison.Results, result)
+	}
+
+	return comparison
+}
+
+func (c Comparison) Regressions() []Result {
+	regressions := []Result{}
+	for _, r := range c.Results {
+		if r.Status == StatusRegressed {
+			regressions = append(regressions, r)
+		}
+	}
+	return regressions
+}
+
+// Err returns an error listing the regressions, or nil if there are none.
+func (c Comparison) Err() error {
+	if len(c.Regressions()) == 0 {
+		return nil
+	}
+	return errors.New("performance regressions detected:\n" + c.String())
+}
+
+// String renders the comparison as a table, one measurement per line.
+func (c Comparison) String() string {
+	width := len("measurement")
+	for _, r := range c.Results {
+		if len(r.Name) > width {
+			width = len(r.Name)
+		}
+	}
+
+	lines := []string{fmt.Sprintf("%-*s  %12s  %12s  %12s  %8s  %s", width, "measurement", "baseline", "current", "limit", "change", "status")}
+	for _, r := range c.Results {
+		if r.Status == StatusNew {
+			lines = append(lines, fmt.Sprintf("%-*s  %12s  %12s  %12s  %8s  %s", width, r.Name, "-", round(r.Current), "-", "-", r.Status))
+			continue
+		}
+
+		lines = append(lines, fmt.Sprintf("%-*s  %12s  %12s  %12s  %+7.1f%%  %s",
+			width, r.Name, round(r.Baseline), round(r.Current), round(r.Limit), 100*r.Change(), r.Status))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func round(d time.Duration) time.Duration {
+	return d.Round(time.Millisecond)
+}
+
+// Config says what a suite should do with its measurements at the end of a
+// run.
+type Config struct {
+	// Path is the baseline file. Nothing is done when it is empty.
+	Path string
+	// Record merges the run's measurements into Path instead of comparing
+	// against it.
+	Record    bool
+	Tolerance Tolerance
+	Overrides map[string]Tolerance
+}
+
+var DefaultTolerance = Tolerance{Relative: 0.25, Absolute: time.Second}
+
+// ConfigFromEnv reads INIGO_PERF_BASELINE (the baseline path),
+// INIGO_PERF_RECORD_BASELINE (record rather than compare when "true") and
+// INIGO_PERF_TOLERANCE (relative tolerance, e.g. "0.25").
+func ConfigFromEnv() (Config, error) {
+	config := Config{
+		Path:      os.Getenv("INIGO_PERF_BASELINE"),
+		Record:    os.Getenv("INIGO_PERF_RECORD_BASELINE") == "true",
+		Tolerance: DefaultTolerance,
+	}
+
+	if tolerance := os.Getenv("INIGO_PERF_TOLERANCE"); tolerance != "" {
+		relative, err := strconv.ParseFloat(tolerance, 64)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid INIGO_PERF_TOLERANCE %q: %s", tolerance, err)
+		}
+		config.Tolerance.Relative = relative
+	}
+
+	return config, nil
+}
+
+// Apply records current or compares it against the baseline, writing the
+// comparison to w and returning an error describing any regressions. A
+// missing baseline file is reported and skipped.
+func (c Config) Apply(current Baseline, w io.Writer) error {
+	if c.Path == "" || len(current.Measurements) == 0 {
+		return nil
+	}
+
+	if c.Record {
+		return current.MergeInto(c.Path)
+	}
+
+	baseline, err := Load(c.Path)
+	if os.IsNotExist(err) {
+		fmt.Fprintf(w, "no performance baseline at %s; skipping comparison\n", c.Path)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	comparison := Compare(baseline, current, c.Tolerance, c.Overrides)
+	fmt.Fprintln(w, comparison.String())
+
+	return comparison.Err()
+}
@@ -0,0 +1,13 @@
+package perfbaseline_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestPerfbaseline(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Perfbaseline Suite")
+}
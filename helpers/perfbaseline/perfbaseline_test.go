@@ -0,0 +1,285 @@
+package perfbaseline_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/inigo/helpers/perfbaseline"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("Perfbaseline", func() {
+	var tmpDir string
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "perfbaseline")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	baselineOf := func(durations map[string]time.Duration) perfbaseline.Baseline {
+		baseline := perfbaseline.Baseline{Measurements: map[string]perfbaseline.Measurement{}}
+		for name, d := range durations {
+			baseline.Measurements[name] = perfbaseline.Measurement{Duration: d, Samples: 1}
+		}
+		return baseline
+	}
+
+	Describe("Recorder", func() {
+		It("summarises each name by its median sample", func() {
+			recorder := perfbaseline.NewRecorder()
+			recorder.Record("rep-start", 3*time.Second)
+			recorder.Record("rep-start", time.Second)
+			recorder.Record("rep-start", 2*time.Second)
+			recorder.Record("bbs-start", 500*time.Millisecond)
+
+			Expect(recorder.Baseline().Measurements).To(Equal(map[string]perfbaseline.Measurement{
+				"rep-start": {Duration: 2 * time.Second, Samples: 3},
+				"bbs-start": {Duration: 500 * time.Millisecond, Samples: 1},
+			}))
+		})
+
+		It("uses the lower middle sample for an even number of samples", func() {
+			recorder := perfbaseline.NewRecorder()
+			recorder.Record("task", 4*time.Second)
+			recorder.Record("task", time.Second)
+
+			Expect(recorder.Baseline().Measurements["task"].Duration).To(Equal(time.Second))
+		})
+	})
+
+	Describe("TimedRunner", func() {
+		It("records the time until the wrapped runner is ready", func() {
+			recorder := perfbaseline.NewRecorder()
+			runner := ifrit.RunFunc(func(signals <-chan os.Signal, ready chan<- struct{}) error {
+				time.Sleep(50 * time.Millisecond)
+				close(ready)
+				<-signals
+				return nil
+			})
+
+			process := ifrit.Invoke(perfbaseline.TimedRunner(recorder, "component-start", runner))
+			process.Signal(os.Interrupt)
+			Eventually(process.Wait()).Should(Receive(BeNil()))
+
+			measurement := recorder.Baseline().Measurements["component-start"]
+			Expect(measurement.Samples).To(Equal(1))
+			Expect(measurement.Duration).To(BeNumerically(">=", 50*time.Millisecond))
+		})
+	})
+
+	Describe("Save, Load and MergeInto", func() {
+		It("round-trips a baseline through JSON", func() {
+			path := filepath.Join(tmpDir, "baseline.json")
+			baseline := baselineOf(map[string]time.Duration{"lrp-running": 4 * time.Second})
+
+			Expect(baseline.Save(path)).To(Succeed())
+			Expect(perfbaseline.Load(path)).To(Equal(baseline))
+		})
+
+		It("fails to load a file that isn't a baseline", func() {
+			path := filepath.Join(tmpDir, "baseline.json")
+			Expect(ioutil.WriteFile(path, []byte("nope"), 0644)).To(Succeed())
+
+			_, err := perfbaseline.Load(path)
+			Expect(err).To(MatchError(ContainSubstring("invalid baseline")))
+		})
+
+		It("merges concurrent writers into one file", func() {
+			path := filepath.Join(tmpDir, "baseline.json")
+			Expect(baselineOf(map[string]time.Duration{"a": time.Second}).Save(path)).To(Succeed())
+
+			wg := sync.WaitGroup{}
+			for _, name := range []string{"b", "c", "d", "a"} {
+				wg.Add(1)
+				go func(name string) {
+					defer GinkgoRecover()
+					defer wg.Done()
+					Expect(baselineOf(map[string]time.Duration{name: 2 * time.Second}).MergeInto(path)).To(Succeed())
+				}(name)
+			}
+			wg.Wait()
+
+			merged, err := perfbaseline.Load(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(merged).To(Equal(baselineOf(map[string]time.Duration{
+				"a": 2 * time.Second,
+				"b": 2 * time.Second,
+				"c": 2 * time.Second,
+				"d": 2 * time.Second,
+			})))
+			Expect(path + ".lock").NotTo(BeAnExistingFile())
+		})
+
+		Context("when a lock was left behind", func() {
+			var path string
+
+			BeforeEach(func() {
+				path = filepath.Join(tmpDir, "baseline.json")
+			})
+
+			mergeQuickly := func() {
+				merged := make(chan error, 1)
+				go func() {
+					merged <- baselineOf(map[string]time.Duration{"a": time.Second}).MergeInto(path)
+				}()
+				Eventually(merged, 5*time.Second).Should(Receive(BeNil()))
+				Expect(perfbaseline.Load(path)).To(Equal(baselineOf(map[string]time.Duration{"a": time.Second})))
+				Expect(path + ".lock").NotTo(BeAnExistingFile())
+			}
+
+			It("takes over a lock whose process has exited", func() {
+				cmd := exec.Command("go", "version")
+				Expect(cmd.Run()).To(Succeed())
+				deadPID := cmd.Process.Pid
+
+				Expect(ioutil.WriteFile(path+".lock", []byte(fmt.Sprintf("%d\n", deadPID)), 0644)).To(Succeed())
+				mergeQuickly()
+			})
+
+			It("takes over a lock older than any merge takes", func() {
+				Expect(ioutil.WriteFile(path+".lock", []byte(fmt.Sprintf("%d\n", os.Getpid())), 0644)).To(Succeed())
+				anHourAgo := time.Now().Add(-time.Hour)
+				Expect(os.Chtimes(path+".lock", anHourAgo, anHourAgo)).To(Succeed())
+				mergeQuickly()
+			})
+
+			It("waits for a fresh lock held by a running process", func() {
+				Expect(ioutil.WriteFile(path+".lock", []byte(fmt.Sprintf("%d\n", os.Getpid())), 0644)).To(Succeed())
+
+				merged := make(chan error, 1)
+				go func() {
+					merged <- baselineOf(map[string]time.Duration{"a": time.Second}).MergeInto(path)
+				}()
+				Consistently(merged, 200*time.Millisecond).ShouldNot(Receive())
+
+				Expect(os.Remove(path + ".lock")).To(Succeed())
+				Eventually(merged).Should(Receive(BeNil()))
+			})
+		})
+
+		It("creates the file when merging into a missing baseline", func() {
+			path := filepath.Join(tmpDir, "baseline.json")
+			Expect(baselineOf(map[string]time.Duration{"a": time.Second}).MergeInto(path)).To(Succeed())
+			Expect(perfbaseline.Load(path)).To(Equal(baselineOf(map[string]time.Duration{"a": time.Second})))
+		})
+	})
+
+	Describe("Compare", func() {
+		var baseline perfbaseline.Baseline
+
+		BeforeEach(func() {
+			baseline = baselineOf(map[string]time.Duration{
+				"rep-start":   10 * time.Second,
+				"lrp-running": 5 * time.Second,
+				"only-before": time.Second,
+			})
+		})
+
+		It("flags measurements beyond the tolerance", func() {
+			current := baselineOf(map[string]time.Duration{
+				"rep-start":   13 * time.Second,
+				"lrp-running": 5500 * time.Millisecond,
+			})
+
+			comparison := perfbaseline.Compare(baseline, current, perfbaseline.Tolerance{Relative: 0.2}, nil)
+			Expect(comparison.Regressions()).To(ConsistOf(perfbaseline.Result{
+				Name:     "rep-start",
+				Baseline: 10 * time.Second,
+				Current:  13 * time.Second,
+				Limit:    12 * time.Second,
+				Status:   perfbaseline.StatusRegressed,
+			}))
+			Expect(comparison.Err()).To(MatchError(ContainSubstring("rep-start")))
+		})
+
+		It("adds the absolute slack on top of the relative tolerance", func() {
+			current := baselineOf(map[string]time.Duration{"rep-start": 13 * time.Second})
+
+			comparison := perfbaseline.Compare(baseline, current, perfbaseline.Tolerance{Relative: 0.2, Absolute: 2 * time.Second}, nil)
+			Expect(comparison.Regressions()).To(BeEmpty())
+			Expect(comparison.Err()).NotTo(HaveOccurred())
+		})
+
+		It("applies per-name overrides", func() {
+			current := baselineOf(map[string]time.Duration{"rep-start": 13 * time.Second})
+
+			comparison := perfbaseline.Compare(baseline, current, perfbaseline.Tolerance{Relative: 0.2}, map[string]perfbaseline.Tolerance{
+				"rep-start": {Relative: 0.5},
+			})
+			Expect(comparison.Regressions()).To(BeEmpty())
+		})
+
+		It("reports improvements and new measurements, and ignores ones that weren't run", func() {
+			current := baselineOf(map[string]time.Duration{
+				"lrp-running": 4 * time.Second,
+				"brand-new":   time.Second,
+			})
+
+			comparison := perfbaseline.Compare(baseline, current, perfbaseline.Tolerance{}, nil)
+			Expect(comparison.Results).To(HaveLen(2))
+			Expect(comparison.Results[0].Name).To(Equal("brand-new"))
+			Expect(comparison.Results[0].Status).To(Equal(perfbaseline.StatusNew))
+			Expect(comparison.Results[1].Name).To(Equal("lrp-running"))
+			Expect(comparison.Results[1].Status).To(Equal(perfbaseline.StatusImproved))
+			Expect(comparison.Results[1].Change()).To(BeNumerically("~", -0.2, 0.001))
+		})
+
+		It("renders a readable table", func() {
+			current := baselineOf(map[string]time.Duration{"rep-start": 13 * time.Second})
+
+			report := perfbaseline.Compare(baseline, current, perfbaseline.Tolerance{Relative: 0.2}, nil).String()
+			Expect(report).To(ContainSubstring("measurement"))
+			Expect(report).To(MatchRegexp(`rep-start\s+10s\s+13s\s+12s\s+\+30\.0%\s+REGRESSED`))
+		})
+	})
+
+	Describe("Config", func() {
+		var (
+			path    string
+			output  *bytes.Buffer
+			current perfbaseline.Baseline
+		)
+
+		BeforeEach(func() {
+			path = filepath.Join(tmpDir, "baseline.json")
+			output = &bytes.Buffer{}
+			current = baselineOf(map[string]time.Duration{"rep-start": 20 * time.Second})
+		})
+
+		It("does nothing without a path", func() {
+			Expect(perfbaseline.Config{}.Apply(current, output)).To(Succeed())
+			Expect(output.String()).To(BeEmpty())
+		})
+
+		It("skips the comparison when there is no baseline yet", func() {
+			Expect(perfbaseline.Config{Path: path}.Apply(current, output)).To(Succeed())
+			Expect(output.String()).To(ContainSubstring("no performance baseline"))
+		})
+
+		It("records into the baseline", func() {
+			Expect(perfbaseline.Config{Path: path, Record: true}.Apply(current, output)).To(Succeed())
+			Expect(perfbaseline.Load(path)).To(Equal(current))
+		})
+
+		It("compares against the baseline and reports regressions", func() {
+			Expect(baselineOf(map[string]time.Duration{"rep-start": 10 * time.Second}).Save(path)).To(Succeed())
+
+			err := perfbaseline.Config{Path: path, Tolerance: perfbaseline.DefaultTolerance}.Apply(current, output)
+			Expect(err).To(MatchError(ContainSubstring("performance regressions detected")))
+			Expect(output.String()).To(ContainSubstring("REGRESSED"))
+		})
+	})
+})
@@ -0,0 +1,14 @@
+//go:build !windows
+// +build !windows
+
+package perfbaseline
+
+import "syscall"
+
+// processExists reports whether a process with the given PID is running.
+// Signal 0 checks for the process without signalling it; EPERM means it
+// exists but belongs to another user.
+func processExists(pid int) bool {
+	err := syscall.Kill(pid, syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
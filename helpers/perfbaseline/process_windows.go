@@ -0,0 +1,14 @@
+package perfbaseline
+
+import "os"
+
+// processExists reports whether a process with the given PID is running.
+// On Windows finding a process opens it, which fails once it has exited.
+func processExists(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
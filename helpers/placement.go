@@ -0,0 +1,123 @@
+package helpers
+
+import (
+	"fmt"
+	"sort"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/format"
+	"github.com/onsi/gomega/types"
+)
+
+// InstanceDistribution counts the placed (claimed or running, not
+// evacuating) instances of an LRP per zone and per cell.
+type InstanceDistribution struct {
+	ByZone map[string]int
+	ByCell map[string]int
+}
+
+// Zones returns every zone with at least one instance, sorted.
+func (d InstanceDistribution) Zones() []string {
+	zones := []string{}
+	for zone := range d.ByZone {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+	return zones
+}
+
+func (d InstanceDistribution) Total() int {
+	total := 0
+	for _, count := range d.ByZone {
+		total += count
+	}
+	return total
+}
+
+// InstanceDistributionPoller returns the distribution of processGuid's
+// instances across the zones the BBS currently knows cells for. Instances
+// on cells whose presence has gone are counted under the zone "".
+func InstanceDistributionPoller(logger lager.Logger, client bbs.InternalClient, processGuid string) func() InstanceDistribution {
+	return func() InstanceDistribution {
+		cells, err := client.Cells(logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		zoneOf := map[string]string{}
+		for _, cell := range cells {
+			zoneOf[cell.CellId] = cell.Zone
+		}
+
+		lrps, err := client.ActualLRPs(logger, models.ActualLRPFilter{ProcessGuid: processGuid})
+		Expect(err).NotTo(HaveOccurred())
+
+		distribution := InstanceDistribution{
+			ByZone: map[string]int{},
+			ByCell: map[string]int{},
+		}
+		for _, lrp := range lrps {
+			if lrp.Presence == models.ActualLRP_Evacuating {
+				continue
+			}
+			if lrp.State != models.ActualLRPStateClaimed && lrp.State != models.ActualLRPStateRunning {
+				continue
+			}
+
+			distribution.ByZone[zoneOf[lrp.CellId]]++
+			distribution.ByCell[lrp.CellId]++
+		}
+
+		return distribution
+	}
+}
+
+// BeBalancedAcrossZones succeeds for an InstanceDistribution whose
+// instances are spread over exactly zones, each with at least one instance
+// and no two differing by more than one.
+func BeBalancedAcrossZones(zones ...string) types.GomegaMatcher {
+	return &balancedAcrossZonesMatcher{zones: zones}
+}
+
+type balancedAcrossZonesMatcher struct {
+	zones []string
+}
+
+func (m *balancedAcrossZonesMatcher) Match(actual interface{}) (bool, error) {
+	distribution, ok := actual.(InstanceDistribution)
+	if !ok {
+		return false, fmt.Errorf("BeBalancedAcrossZones expects an InstanceDistribution, got\n%s", format.Object(actual, 1))
+	}
+
+	expected := map[string]bool{}
+	for _, zone := range m.zones {
+		expected[zone] = true
+	}
+	for zone := range distribution.ByZone {
+		if !expected[zone] {
+			return false, nil
+		}
+	}
+
+	min, max := -1, 0
+	for _, zone := range m.zones {
+		count := distribution.ByZone[zone]
+		if min == -1 || count < min {
+			min = count
+		}
+		if count > max {
+			max = count
+		}
+	}
+
+	return min > 0 && max-min <= 1, nil
+}
+
+func (m *balancedAcrossZonesMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected instances to be balanced across zones %v, but got\n%s", m.zones, format.Object(actual, 1))
+}
+
+func (m *balancedAcrossZonesMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected instances not to be balanced across zones %v, but got\n%s", m.zones, format.Object(actual, 1))
+}
@@ -0,0 +1,36 @@
+package helpers_test
+
+import (
+	"code.cloudfoundry.org/inigo/helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BeBalancedAcrossZones", func() {
+	distribution := func(byZone map[string]int) helpers.InstanceDistribution {
+		return helpers.InstanceDistribution{ByZone: byZone, ByCell: map[string]int{}}
+	}
+
+	It("matches instances spread evenly over the zones", func() {
+		Expect(distribution(map[string]int{"z1": 2, "z2": 2})).To(helpers.BeBalancedAcrossZones("z1", "z2"))
+		Expect(distribution(map[string]int{"z1": 2, "z2": 1})).To(helpers.BeBalancedAcrossZones("z1", "z2"))
+	})
+
+	It("does not match zones that differ by more than one instance", func() {
+		Expect(distribution(map[string]int{"z1": 3, "z2": 1})).NotTo(helpers.BeBalancedAcrossZones("z1", "z2"))
+	})
+
+	It("does not match when a zone has no instances", func() {
+		Expect(distribution(map[string]int{})).NotTo(helpers.BeBalancedAcrossZones("z1", "z2"))
+		Expect(distribution(map[string]int{"z1": 1})).NotTo(helpers.BeBalancedAcrossZones("z1", "z2"))
+	})
+
+	It("does not match instances in other zones", func() {
+		Expect(distribution(map[string]int{"z1": 1, "z2": 1, "z3": 1})).NotTo(helpers.BeBalancedAcrossZones("z1", "z2"))
+	})
+
+	It("rejects anything other than an InstanceDistribution", func() {
+		_, err := helpers.BeBalancedAcrossZones("z1").Match(map[string]int{"z1": 1})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -89,6 +89,20 @@ func LRPStatePoller(logger lager.Logger, client bbs.InternalClient, processGuid
 	}
 }
 
+// PlacementErrorPoller returns the placement error of processGuid's
+// instance at index, or "" while there is none.
+func PlacementErrorPoller(logger lager.Logger, client bbs.InternalClient, processGuid string, index int) func() string {
+	return func() string {
+		i := int32(index)
+		lrps, err := client.ActualLRPs(logger, models.ActualLRPFilter{ProcessGuid: processGuid, Index: &i})
+		Expect(err).NotTo(HaveOccurred())
+		if len(lrps) == 0 {
+			return ""
+		}
+		return lrps[0].PlacementError
+	}
+}
+
 func LRPInstanceStatePoller(logger lager.Logger, client bbs.InternalClient, processGuid string, index int, lrp *models.ActualLRP) func() string {
 	return func() string {
 		i := int32(index)
@@ -0,0 +1,26 @@
+package helpers
+
+import (
+	"database/sql"
+
+	sqlhelpers "code.cloudfoundry.org/bbs/db/sqldb/helpers"
+	"code.cloudfoundry.org/inigo/world"
+	"code.cloudfoundry.org/lager"
+	. "github.com/onsi/gomega"
+)
+
+// SQLConnection connects to the database the BBS and locket of maker use,
+// for looking at rows directly. The caller closes it.
+func SQLConnection(logger lager.Logger, maker world.ComponentMaker) *sql.DB {
+	db, err := sqlhelpers.Connect(logger, maker.DBDriverName(), maker.Addresses().SQL, "", false)
+	Expect(err).NotTo(HaveOccurred())
+	Eventually(db.Ping).Should(Succeed())
+
+	return db
+}
+
+// rebind rewrites a query written with ? placeholders for the database
+// driver of the running spec.
+func rebind(query string) string {
+	return sqlhelpers.RebindForFlavor(query, world.SpecDBDriver())
+}
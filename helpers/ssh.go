@@ -0,0 +1,91 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"code.cloudfoundry.org/archiver/compressor"
+	"code.cloudfoundry.org/bbs/models"
+	ssh_helpers "code.cloudfoundry.org/diego-ssh/helpers"
+	"code.cloudfoundry.org/diego-ssh/routes"
+	"code.cloudfoundry.org/inigo/world"
+	. "github.com/onsi/gomega"
+)
+
+// SSHDPort is the container port the sshd sidecar added by SSHEnabledLRP
+// listens on.
+const SSHDPort = 2222
+
+const sshdArchive = "sshd.tgz"
+
+// InstallSSHD places the sshd executable in the file server's static
+// directory so that SSH-enabled LRPs can download it.
+func InstallSSHD(artifacts world.BuiltArtifacts, fileServerStaticDir string) {
+	tgCompressor := compressor.NewTgz()
+	err := tgCompressor.Compress(artifacts.Executables["sshd"], filepath.Join(fileServerStaticDir, sshdArchive))
+	Expect(err).NotTo(HaveOccurred())
+}
+
+// SSHEnabledLRP returns a copy of base that also downloads and runs sshd
+// alongside its action and advertises the diego-ssh route for it. sshd runs
+// as root for privileged LRPs and as vcap otherwise. InstallSSHD must have
+// been called for the file server first.
+func SSHEnabledLRP(addresses world.ComponentAddresses, keys world.SSHKeys, base *models.DesiredLRP) *models.DesiredLRP {
+	lrp := *base
+
+	user := "vcap"
+	if lrp.Privileged {
+		user = "root"
+	}
+
+	download := &models.DownloadAction{
+		Artifact: "sshd",
+		From:     fmt.Sprintf("http://%s/v1/static/%s", addresses.FileServer, sshdArchive),
+		To:       "/tmp/diego-ssh",
+		CacheKey: "sshd",
+		User:     user,
+	}
+	if lrp.Setup == nil {
+		lrp.Setup = models.WrapAction(download)
+	} else {
+		lrp.Setup = models.WrapAction(models.Serial(download, lrp.Setup.GetValue().(models.ActionInterface)))
+	}
+
+	lrp.Action = models.WrapAction(models.Codependent(
+		&models.RunAction{
+			User: user,
+			Path: "/tmp/diego-ssh/sshd",
+			Args: []string{
+				fmt.Sprintf("-address=0.0.0.0:%d", SSHDPort),
+				"-hostKey=" + keys.HostKeyPem,
+				"-authorizedKey=" + keys.AuthorizedKey,
+				"-inheritDaemonEnv",
+				"-logLevel=debug",
+			},
+		},
+		lrp.Action.GetValue().(models.ActionInterface),
+	))
+
+	lrp.Ports = append(append([]uint32{}, lrp.Ports...), SSHDPort)
+
+	sshRoute := routes.SSHRoute{
+		ContainerPort:   SSHDPort,
+		PrivateKey:      keys.PrivateKeyPem,
+		HostFingerprint: ssh_helpers.MD5Fingerprint(keys.HostKey.PublicKey()),
+	}
+	sshRoutePayload, err := json.Marshal(sshRoute)
+	Expect(err).NotTo(HaveOccurred())
+	sshRouteMessage := json.RawMessage(sshRoutePayload)
+
+	lrpRoutes := models.Routes{}
+	if lrp.Routes != nil {
+		for key, value := range *lrp.Routes {
+			lrpRoutes[key] = value
+		}
+	}
+	lrpRoutes[routes.DIEGO_SSH] = &sshRouteMessage
+	lrp.Routes = &lrpRoutes
+
+	return &lrp
+}
@@ -0,0 +1,142 @@
+package helpers
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager"
+	. "github.com/onsi/gomega"
+)
+
+// SSHBackendPath names the route ssh-proxy can take to reach an instance's
+// sshd.
+type SSHBackendPath string
+
+const (
+	// SSHBackendHostPort is the cell's external address and host port.
+	SSHBackendHostPort SSHBackendPath = "host-port"
+	// SSHBackendInstanceAddress is the container's address and port, used
+	// when ssh-proxy runs with ConnectToInstanceAddress.
+	SSHBackendInstanceAddress SSHBackendPath = "instance-address"
+	// SSHBackendHostTLSPort is envoy's TLS port as exposed on the cell.
+	SSHBackendHostTLSPort SSHBackendPath = "host-tls-port"
+	// SSHBackendInstanceTLSPort is envoy's TLS port on the container's
+	// address.
+	SSHBackendInstanceTLSPort SSHBackendPath = "instance-tls-port"
+)
+
+// SSHBackendAddresses returns, for each path ssh-proxy could take to the
+// sshd of the given instance, the address it would dial. Paths that the
+// instance does not expose (e.g. TLS ports without a container proxy) are
+// omitted.
+func SSHBackendAddresses(logger lager.Logger, client bbs.InternalClient, processGuid string, index int) map[SSHBackendPath]string {
+	i := int32(index)
+	lrps, err := client.ActualLRPs(logger, models.ActualLRPFilter{ProcessGuid: processGuid, Index: &i})
+	Expect(err).NotTo(HaveOccurred())
+	Expect(lrps).To(HaveLen(1))
+
+	var addresses map[SSHBackendPath]string
+
+	netInfo := lrps[0].ActualLRPNetInfo
+	for _, mapping := range netInfo.Ports {
+		if mapping.ContainerPort != SSHDPort {
+			continue
+		}
+
+		addresses = map[SSHBackendPath]string{
+			SSHBackendHostPort:        joinHostPort(netInfo.Address, mapping.HostPort),
+			SSHBackendInstanceAddress: joinHostPort(netInfo.InstanceAddress, mapping.ContainerPort),
+		}
+		if mapping.HostTlsProxyPort != 0 {
+			addresses[SSHBackendHostTLSPort] = joinHostPort(netInfo.Address, mapping.HostTlsProxyPort)
+		}
+		if mapping.ContainerTlsProxyPort != 0 {
+			addresses[SSHBackendInstanceTLSPort] = joinHostPort(netInfo.InstanceAddress, mapping.ContainerTlsProxyPort)
+		}
+	}
+
+	Expect(addresses).NotTo(BeNil(), "instance %s/%d does not expose the sshd port %d", processGuid, index, SSHDPort)
+	return addresses
+}
+
+func joinHostPort(host string, port uint32) string {
+	return net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
+
+// EstablishedSSHBackendPaths returns the paths that currently have an
+// established TCP connection from this host, which ssh-proxy shares with
+// the test process. Hold an ssh session open while polling it:
+//
+//	Eventually(func() []helpers.SSHBackendPath {
+//		return helpers.EstablishedSSHBackendPaths(addresses)
+//	}).Should(ConsistOf(helpers.SSHBackendInstanceTLSPort))
+//
+// Only IPv4 connections on Linux are visible.
+func EstablishedSSHBackendPaths(addresses map[SSHBackendPath]string) []SSHBackendPath {
+	established := establishedRemoteAddresses()
+
+	paths := []SSHBackendPath{}
+	for path, address := range addresses {
+		if established[address] {
+			paths = append(paths, path)
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool { return paths[i] < paths[j] })
+
+	return paths
+}
+
+const tcpEstablished = "01"
+
+func establishedRemoteAddresses() map[string]bool {
+	file, err := os.Open("/proc/net/tcp")
+	Expect(err).NotTo(HaveOccurred())
+	defer file.Close()
+
+	remotes := map[string]bool{}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[3] != tcpEstablished {
+			continue
+		}
+
+		remote, err := parseProcNetAddress(fields[2])
+		Expect(err).NotTo(HaveOccurred())
+		remotes[remote] = true
+	}
+	Expect(scanner.Err()).NotTo(HaveOccurred())
+
+	return remotes
+}
+
+// parseProcNetAddress decodes an address such as "0100007F:1F90", whose IP
+// is in host (little-endian) byte order.
+func parseProcNetAddress(s string) (string, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return "", fmt.Errorf("malformed address %q", s)
+	}
+
+	ip, err := hex.DecodeString(parts[0])
+	if err != nil || len(ip) != net.IPv4len {
+		return "", fmt.Errorf("malformed IPv4 address %q", parts[0])
+	}
+
+	port, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return "", err
+	}
+
+	return net.JoinHostPort(net.IPv4(ip[3], ip[2], ip[1], ip[0]).String(), strconv.Itoa(int(port))), nil
+}
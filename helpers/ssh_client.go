@@ -0,0 +1,298 @@
+package helpers
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// DiegoSSHUser is the ssh-proxy username that authenticates with diego
+// credentials against the given LRP instance.
+func DiegoSSHUser(processGuid string, index int) string {
+	return fmt.Sprintf("diego:%s/%d", processGuid, index)
+}
+
+type SSHClient struct {
+	*ssh.Client
+}
+
+// DialSSHProxy connects to the ssh-proxy at address as user, using an empty
+// password when no auth methods are given.
+func DialSSHProxy(address, user string, auth ...ssh.AuthMethod) (*SSHClient, error) {
+	if len(auth) == 0 {
+		auth = []ssh.AuthMethod{ssh.Password("")}
+	}
+
+	client, err := ssh.Dial("tcp", address, &ssh.ClientConfig{
+		User:            user,
+		Auth:            auth,
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &SSHClient{Client: client}, nil
+}
+
+// Exec runs command in a new session and returns its output and exit status.
+// err is only set when the command could not be run at all.
+func (c *SSHClient) Exec(command string) (stdout, stderr []byte, exitStatus int, err error) {
+	session, err := c.NewSession()
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer session.Close()
+
+	var outBuffer, errBuffer bytes.Buffer
+	session.Stdout = &outBuffer
+	session.Stderr = &errBuffer
+
+	err = session.Run(command)
+	if exitErr, ok := err.(*ssh.ExitError); ok {
+		return outBuffer.Bytes(), errBuffer.Bytes(), exitErr.ExitStatus(), nil
+	}
+
+	return outBuffer.Bytes(), errBuffer.Bytes(), 0, err
+}
+
+// SSHShell is an interactive shell session with a PTY.
+type SSHShell struct {
+	session *ssh.Session
+	Stdin   io.WriteCloser
+	Stdout  io.Reader
+}
+
+// Shell starts an interactive shell on a PTY of the given size.
+func (c *SSHClient) Shell(term string, width, height int) (*SSHShell, error) {
+	session, err := c.NewSession()
+	if err != nil {
+		return nil, err
+	}
+
+	err = session.RequestPty(term, height, width, ssh.TerminalModes{ssh.ECHO: 0})
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+
+	err = session.Shell()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+
+	return &SSHShell{session: session, Stdin: stdin, Stdout: stdout}, nil
+}
+
+func (s *SSHShell) WindowChange(width, height int) error {
+	return s.session.WindowChange(height, width)
+}
+
+// Wait waits for the shell to exit and closes the session.
+func (s *SSHShell) Wait() error {
+	defer s.session.Close()
+	return s.session.Wait()
+}
+
+// LocalForward listens on localAddress and forwards every connection through
+// the ssh connection to remoteAddress, as `ssh -L` does. Close the returned
+// listener to stop forwarding.
+func (c *SSHClient) LocalForward(localAddress, remoteAddress string) (net.Listener, error) {
+	listener, err := net.Listen("tcp", localAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	go forward(listener, func() (net.Conn, error) {
+		return c.Dial("tcp", remoteAddress)
+	})
+
+	return listener, nil
+}
+
+// RemoteForward asks the server to listen on remoteAddress and forwards
+// every connection it accepts to localAddress, as `ssh -R` does. Close the
+// returned listener to stop forwarding.
+func (c *SSHClient) RemoteForward(remoteAddress, localAddress string) (net.Listener, error) {
+	listener, err := c.Listen("tcp", remoteAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	go forward(listener, func() (net.Conn, error) {
+		return net.Dial("tcp", localAddress)
+	})
+
+	return listener, nil
+}
+
+func forward(listener net.Listener, dial func() (net.Conn, error)) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			defer conn.Close()
+
+			target, err := dial()
+			if err != nil {
+				return
+			}
+			defer target.Close()
+
+			done := make(chan struct{}, 2)
+			go func() { io.Copy(target, conn); done <- struct{}{} }()
+			go func() { io.Copy(conn, target); done <- struct{}{} }()
+			<-done
+		}()
+	}
+}
+
+// SFTP opens an sftp session over the ssh connection.
+func (c *SSHClient) SFTP() (*sftp.Client, error) {
+	return sftp.NewClient(c.Client)
+}
+
+// SCPUpload copies content to remotePath using the scp sink protocol.
+func (c *SSHClient) SCPUpload(content []byte, remotePath string, mode os.FileMode) error {
+	session, err := c.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(stdout)
+
+	err = session.Start("scp -t " + path.Dir(remotePath))
+	if err != nil {
+		return err
+	}
+
+	if err := readSCPAck(reader); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdin, "C%04o %d %s\n", mode.Perm(), len(content), path.Base(remotePath))
+	if err := readSCPAck(reader); err != nil {
+		return err
+	}
+
+	stdin.Write(content)
+	stdin.Write([]byte{0})
+	if err := readSCPAck(reader); err != nil {
+		return err
+	}
+
+	stdin.Close()
+	return session.Wait()
+}
+
+// SCPDownload fetches remotePath using the scp source protocol.
+func (c *SSHClient) SCPDownload(remotePath string) ([]byte, error) {
+	session, err := c.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(stdout)
+
+	err = session.Start("scp -f " + remotePath)
+	if err != nil {
+		return nil, err
+	}
+
+	stdin.Write([]byte{0})
+
+	header, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(header, "C") {
+		return nil, fmt.Errorf("unexpected scp header: %q", header)
+	}
+
+	fields := strings.SplitN(strings.TrimSpace(header), " ", 3)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("malformed scp header: %q", header)
+	}
+
+	size, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	stdin.Write([]byte{0})
+
+	content, err := ioutil.ReadAll(io.LimitReader(reader, size))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := readSCPAck(reader); err != nil {
+		return nil, err
+	}
+
+	stdin.Write([]byte{0})
+	stdin.Close()
+
+	return content, session.Wait()
+}
+
+func readSCPAck(reader *bufio.Reader) error {
+	code, err := reader.ReadByte()
+	if err != nil {
+		return err
+	}
+
+	if code == 0 {
+		return nil
+	}
+
+	message, _ := reader.ReadString('\n')
+	return errors.New("scp: " + strings.TrimSpace(message))
+}
@@ -0,0 +1,326 @@
+package helpers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+type TrafficGeneratorConfig struct {
+	// RequestsPerSecond is the rate requests are started at. Ticks that find
+	// every worker busy are skipped rather than queued.
+	RequestsPerSecond int
+	Concurrency       int
+	RequestTimeout    time.Duration
+	Path              string
+}
+
+// Validate reports a config the generator can't run, such as one without a
+// positive request rate.
+func (c TrafficGeneratorConfig) Validate() error {
+	if c.RequestsPerSecond <= 0 {
+		return fmt.Errorf("RequestsPerSecond must be positive, not %d", c.RequestsPerSecond)
+	}
+	if time.Second/time.Duration(c.RequestsPerSecond) == 0 {
+		return fmt.Errorf("RequestsPerSecond must be at most one per nanosecond, not %d", c.RequestsPerSecond)
+	}
+	if c.Concurrency <= 0 {
+		return fmt.Errorf("Concurrency must be positive, not %d", c.Concurrency)
+	}
+	if c.RequestTimeout < 0 {
+		return fmt.Errorf("RequestTimeout must not be negative, not %s", c.RequestTimeout)
+	}
+	return nil
+}
+
+// TrafficResponse is the outcome of one request through the router.
+// StatusCode is 0 when the request failed before a response arrived, in
+// which case Error is set. Index is the body of a 200 response, which for
+// the hello-world fixture is the answering instance's index.
+type TrafficResponse struct {
+	StartedAt  time.Time
+	Latency    time.Duration
+	StatusCode int
+	Index      string
+	Error      string
+}
+
+func (r TrafficResponse) Succeeded() bool {
+	return r.StatusCode == http.StatusOK
+}
+
+// TrafficGenerator sends a steady stream of requests for a route through the
+// router in the background and records every response.
+type TrafficGenerator struct {
+	routerAddr string
+	host       string
+	config     TrafficGeneratorConfig
+	client     *http.Client
+
+	lock      sync.Mutex
+	responses []TrafficResponse
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// StartTrafficGenerator starts sending requests for host to the router at
+// routerAddr, failing the spec if the config can't be run. Call Stop,
+// typically in an AfterEach, to end it.
+func StartTrafficGenerator(routerAddr, host string, modifyConfigFuncs ...func(*TrafficGeneratorConfig)) *TrafficGenerator {
+	config := TrafficGeneratorConfig{
+		RequestsPerSecond: 20,
+		Concurrency:       4,
+		RequestTimeout:    5 * time.Second,
+		Path:              "/",
+	}
+	for _, f := range modifyConfigFuncs {
+		f(&config)
+	}
+
+	Expect(config.Validate()).To(Succeed())
+
+	g := &TrafficGenerator{
+		routerAddr: routerAddr,
+		host:       host,
+		config:     config,
+		client: &http.Client{
+			Timeout: config.RequestTimeout,
+			Transport: &http.Transport{
+				MaxIdleConnsPerHost: config.Concurrency,
+			},
+		},
+		stop: make(chan struct{}),
+	}
+
+	work := make(chan struct{}, config.Concurrency)
+	for i := 0; i < config.Concurrency; i++ {
+		g.wg.Add(1)
+		go g.worker(work)
+	}
+
+	g.wg.Add(1)
+	go g.tick(work)
+
+	return g
+}
+
+func (g *TrafficGenerator) tick(work chan<- struct{}) {
+	defer g.wg.Done()
+	defer close(work)
+
+	ticker := time.NewTicker(time.Second / time.Duration(g.config.RequestsPerSecond))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-g.stop:
+			return
+		case <-ticker.C:
+			select {
+			case work <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+func (g *TrafficGenerator) worker(work <-chan struct{}) {
+	defer g.wg.Done()
+
+	for range work {
+		response := g.request()
+
+		g.lock.Lock()
+		g.responses = append(g.responses, response)
+		g.lock.Unlock()
+	}
+}
+
+func (g *TrafficGenerator) request() TrafficResponse {
+	request := &http.Request{
+		Method: "GET",
+		URL: &url.URL{
+			Scheme: "http",
+			Host:   g.routerAddr,
+			Path:   g.config.Path,
+		},
+		Header: http.Header{},
+		Host:   g.host,
+	}
+
+	response := TrafficResponse{StartedAt: time.Now()}
+
+	resp, err := g.client.Do(request)
+	if err != nil {
+		response.Latency = time.Since(response.StartedAt)
+		response.Error = err.Error()
+		return response
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	response.Latency = time.Since(response.StartedAt)
+	response.StatusCode = resp.StatusCode
+	if err != nil {
+		response.Error = err.Error()
+	} else if resp.StatusCode == http.StatusOK {
+		response.Index = string(body)
+	}
+
+	return response
+}
+
+// Stop stops sending requests and waits for those in flight to finish. It
+// can be called more than once, from any goroutine.
+func (g *TrafficGenerator) Stop() {
+	g.stopOnce.Do(func() { close(g.stop) })
+	g.wg.Wait()
+}
+
+// Responses returns every response recorded so far, ordered by when the
+// request was sent.
+func (g *TrafficGenerator) Responses() []TrafficResponse {
+	g.lock.Lock()
+	responses := make([]TrafficResponse, len(g.responses))
+	copy(responses, g.responses)
+	g.lock.Unlock()
+
+	sort.Slice(responses, func(i, j int) bool { return responses[i].StartedAt.Before(responses[j].StartedAt) })
+	return responses
+}
+
+// Report summarises every response recorded so far.
+func (g *TrafficGenerator) Report() TrafficReport {
+	return NewTrafficReport(g.Responses())
+}
+
+// ReportBetween summarises the requests sent between from and to, e.g.
+// while a cell was evacuating.
+func (g *TrafficGenerator) ReportBetween(from, to time.Time) TrafficReport {
+	responses := []TrafficResponse{}
+	for _, r := range g.Responses() {
+		if !r.StartedAt.Before(from) && r.StartedAt.Before(to) {
+			responses = append(responses, r)
+		}
+	}
+	return NewTrafficReport(responses)
+}
+
+// TrafficErrorWindow is a run of consecutive failed requests.
+type TrafficErrorWindow struct {
+	Start    time.Time
+	End      time.Time
+	Failures int
+}
+
+func (w TrafficErrorWindow) Duration() time.Duration {
+	return w.End.Sub(w.Start)
+}
+
+type TrafficReport struct {
+	Total      int
+	Successful int
+
+	// StatusCodes counts responses by status; transport errors count
+	// under 0.
+	StatusCodes map[int]int
+
+	// Instances counts successful responses by backend index.
+	Instances map[string]int
+
+	ErrorWindows []TrafficErrorWindow
+
+	LatencyP50 time.Duration
+	LatencyP95 time.Duration
+	LatencyP99 time.Duration
+	LatencyMax time.Duration
+}
+
+// NewTrafficReport summarises responses, which must be ordered by StartedAt.
+func NewTrafficReport(responses []TrafficResponse) TrafficReport {
+	report := TrafficReport{
+		Total:       len(responses),
+		StatusCodes: map[int]int{},
+		Instances:   map[string]int{},
+	}
+
+	latencies := make([]time.Duration, 0, len(responses))
+	var window *TrafficErrorWindow
+
+	for _, r := range responses {
+		report.StatusCodes[r.StatusCode]++
+		latencies = append(latencies, r.Latency)
+
+		if r.Succeeded() {
+			report.Successful++
+			report.Instances[r.Index]++
+
+			if window != nil {
+				report.ErrorWindows = append(report.ErrorWindows, *window)
+				window = nil
+			}
+			continue
+		}
+
+		end := r.StartedAt.Add(r.Latency)
+		if window == nil {
+			window = &TrafficErrorWindow{Start: r.StartedAt, End: end}
+		}
+		if end.After(window.End) {
+			window.End = end
+		}
+		window.Failures++
+	}
+	if window != nil {
+		report.ErrorWindows = append(report.ErrorWindows, *window)
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	report.LatencyP50 = percentile(latencies, 50)
+	report.LatencyP95 = percentile(latencies, 95)
+	report.LatencyP99 = percentile(latencies, 99)
+	report.LatencyMax = percentile(latencies, 100)
+
+	return report
+}
+
+// Availability is the percentage of requests that succeeded, or 0 when no
+// requests were sent.
+func (r TrafficReport) Availability() float64 {
+	if r.Total == 0 {
+		return 0
+	}
+	return 100 * float64(r.Successful) / float64(r.Total)
+}
+
+// LongestErrorWindow returns the longest outage, or zero if there was none.
+func (r TrafficReport) LongestErrorWindow() time.Duration {
+	var longest time.Duration
+	for _, w := range r.ErrorWindows {
+		if w.Duration() > longest {
+			longest = w.Duration()
+		}
+	}
+	return longest
+}
+
+// percentile returns the p-th percentile of sorted using nearest rank.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,100 @@
+package helpers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/inigo/helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TrafficGenerator", func() {
+	var backend *httptest.Server
+
+	BeforeEach(func() {
+		backend = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("0"))
+		}))
+	})
+
+	AfterEach(func() {
+		backend.Close()
+	})
+
+	routerAddr := func() string {
+		return strings.TrimPrefix(backend.URL, "http://")
+	}
+
+	It("records the responses", func() {
+		traffic := helpers.StartTrafficGenerator(routerAddr(), "some-host", func(cfg *helpers.TrafficGeneratorConfig) {
+			cfg.RequestsPerSecond = 100
+		})
+		defer traffic.Stop()
+
+		Eventually(func() int { return traffic.Report().Successful }).Should(BeNumerically(">", 5))
+		Expect(traffic.Report().Instances).To(HaveKey("0"))
+	})
+
+	It("can be stopped twice and from several goroutines at once", func() {
+		traffic := helpers.StartTrafficGenerator(routerAddr(), "some-host")
+
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				traffic.Stop()
+			}()
+		}
+		wg.Wait()
+
+		traffic.Stop()
+
+		stopped := len(traffic.Responses())
+		Consistently(func() int { return len(traffic.Responses()) }, 200*time.Millisecond).Should(Equal(stopped))
+	})
+
+	Describe("validating the config", func() {
+		var config helpers.TrafficGeneratorConfig
+
+		BeforeEach(func() {
+			config = helpers.TrafficGeneratorConfig{
+				RequestsPerSecond: 20,
+				Concurrency:       4,
+				RequestTimeout:    time.Second,
+			}
+		})
+
+		It("accepts a runnable config", func() {
+			Expect(config.Validate()).To(Succeed())
+		})
+
+		It("rejects a zero or negative request rate", func() {
+			config.RequestsPerSecond = 0
+			Expect(config.Validate()).To(MatchError(ContainSubstring("RequestsPerSecond must be positive")))
+
+			config.RequestsPerSecond = -1
+			Expect(config.Validate()).To(MatchError(ContainSubstring("RequestsPerSecond must be positive")))
+		})
+
+		It("rejects a rate faster than the ticker can go", func() {
+			config.RequestsPerSecond = 2 * int(time.Second)
+			Expect(config.Validate()).To(MatchError(ContainSubstring("at most one per nanosecond")))
+		})
+
+		It("rejects a config without workers", func() {
+			config.Concurrency = 0
+			Expect(config.Validate()).To(MatchError(ContainSubstring("Concurrency must be positive")))
+		})
+
+		It("rejects a negative timeout", func() {
+			config.RequestTimeout = -time.Second
+			Expect(config.Validate()).To(MatchError(ContainSubstring("RequestTimeout must not be negative")))
+		})
+	})
+})
@@ -0,0 +1,290 @@
+package helpers
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/tlsconfig"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type UploadFailureMode int
+
+const (
+	// UploadFailureStatus responds to a failed upload with
+	// UploadSinkConfig.FailureStatusCode.
+	UploadFailureStatus UploadFailureMode = iota
+
+	// UploadFailureReset aborts the connection without writing a response.
+	UploadFailureReset
+)
+
+type UploadSinkConfig struct {
+	// FailuresBeforeSuccess is the number of uploads rejected using FailureMode
+	// before the sink starts accepting them. A negative value rejects every
+	// upload.
+	FailuresBeforeSuccess int
+	FailureMode           UploadFailureMode
+	FailureStatusCode     int
+
+	// ReadDelay is how long the sink waits before reading each request body.
+	ReadDelay time.Duration
+
+	// when the server cert and key are set the sink only accepts TLS
+	// connections; when the CA cert is also set clients must present a
+	// certificate signed by it
+	ServerCertFile   string
+	ServerKeyFile    string
+	ClientCACertFile string
+}
+
+type Upload struct {
+	Method        string
+	Path          string
+	Header        http.Header
+	ContentLength int64
+	Body          []byte
+
+	// Checksums holds the hex-encoded digest of the body for md5, sha1 and
+	// sha256.
+	Checksums map[string]string
+
+	// MultipartForm is non-nil when the upload was a multipart/form-data
+	// request.
+	MultipartForm *multipart.Form
+
+	// StatusCode is the status the sink responded with, or zero if the
+	// connection was reset.
+	StatusCode int
+	ReceivedAt time.Time
+}
+
+// ContentMD5Matches reports whether the Content-MD5 header sent with the
+// upload matches the body the sink received.
+func (u Upload) ContentMD5Matches() bool {
+	expected := u.Header.Get("Content-MD5")
+	if expected == "" {
+		return false
+	}
+	sum := md5.Sum(u.Body)
+	return expected == base64.StdEncoding.EncodeToString(sum[:])
+}
+
+type UploadSink struct {
+	server *httptest.Server
+	addr   string
+	config UploadSinkConfig
+
+	lock     sync.Mutex
+	uploads  []Upload
+	failures int
+
+	// the connections the listener accepted, by remote address, so that a
+	// reset reaches the TCP connection under a TLS one
+	connsLock sync.Mutex
+	conns     map[string]net.Conn
+}
+
+// NewUploadSink starts a server on listenHost that records every upload it
+// receives, so that specs can assert on what the executor sent.
+func NewUploadSink(listenHost string, modifyConfigFuncs ...func(*UploadSinkConfig)) *UploadSink {
+	config := UploadSinkConfig{
+		FailureMode:       UploadFailureStatus,
+		FailureStatusCode: http.StatusInternalServerError,
+	}
+
+	for _, modifyConfig := range modifyConfigFuncs {
+		modifyConfig(&config)
+	}
+
+	sink := &UploadSink{
+		config: config,
+		conns:  map[string]net.Conn{},
+	}
+
+	listener, err := net.Listen("tcp", listenHost+":0")
+	Expect(err).NotTo(HaveOccurred())
+
+	sink.server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer GinkgoRecover()
+		sink.handle(w, r)
+	}))
+	sink.server.Listener = &trackingListener{Listener: listener, sink: sink}
+	sink.server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			sink.untrack(conn.RemoteAddr())
+		}
+	}
+	sink.addr = listener.Addr().String()
+
+	if config.ServerCertFile != "" {
+		var serverConfig []tlsconfig.ServerOption
+		if config.ClientCACertFile != "" {
+			serverConfig = append(serverConfig, tlsconfig.WithClientAuthenticationFromFile(config.ClientCACertFile))
+		}
+
+		tlsConfig, err := tlsconfig.Build(
+			tlsconfig.WithInternalServiceDefaults(),
+			tlsconfig.WithIdentityFromFile(config.ServerCertFile, config.ServerKeyFile),
+		).Server(serverConfig...)
+		Expect(err).NotTo(HaveOccurred())
+
+		sink.server.TLS = tlsConfig
+		sink.server.StartTLS()
+	} else {
+		sink.server.Start()
+	}
+
+	return sink
+}
+
+func (s *UploadSink) handle(w http.ResponseWriter, r *http.Request) {
+	if s.config.ReadDelay > 0 {
+		time.Sleep(s.config.ReadDelay)
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	Expect(err).NotTo(HaveOccurred())
+
+	upload := Upload{
+		Method:        r.Method,
+		Path:          r.URL.Path,
+		Header:        r.Header,
+		ContentLength: r.ContentLength,
+		Body:          body,
+		Checksums:     map[string]string{},
+		ReceivedAt:    time.Now(),
+	}
+
+	for _, algorithm := range []string{"md5", "sha1", "sha256"} {
+		hash, err := getHash(algorithm)
+		Expect(err).NotTo(HaveOccurred())
+		hash.Write(body)
+		upload.Checksums[algorithm] = fmt.Sprintf("%x", hash.Sum(nil))
+	}
+
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err == nil && strings.HasPrefix(mediaType, "multipart/") {
+		form, err := multipart.NewReader(bytes.NewReader(body), params["boundary"]).ReadForm(32 << 20)
+		if err == nil {
+			upload.MultipartForm = form
+		}
+	}
+
+	s.lock.Lock()
+	shouldFail := s.config.FailuresBeforeSuccess < 0 || s.failures < s.config.FailuresBeforeSuccess
+	if shouldFail {
+		s.failures++
+	}
+
+	switch {
+	case !shouldFail:
+		upload.StatusCode = http.StatusCreated
+	case s.config.FailureMode == UploadFailureStatus:
+		upload.StatusCode = s.config.FailureStatusCode
+	}
+
+	s.uploads = append(s.uploads, upload)
+	s.lock.Unlock()
+
+	if shouldFail && s.config.FailureMode == UploadFailureReset {
+		s.resetConnection(w)
+		return
+	}
+
+	w.WriteHeader(upload.StatusCode)
+}
+
+func (s *UploadSink) resetConnection(w http.ResponseWriter) {
+	hijacker, ok := w.(http.Hijacker)
+	Expect(ok).To(BeTrue(), "upload sink cannot hijack the connection")
+
+	conn, _, err := hijacker.Hijack()
+	Expect(err).NotTo(HaveOccurred())
+
+	// closing a TLS connection would send a close_notify first, so the TCP
+	// connection under it is closed instead
+	raw := s.untrack(conn.RemoteAddr())
+	Expect(raw).NotTo(BeNil(), "upload sink did not accept the connection from %s", conn.RemoteAddr())
+
+	// a zero linger makes Close send a RST rather than a FIN
+	tcpConn, ok := raw.(*net.TCPConn)
+	Expect(ok).To(BeTrue(), "upload sink cannot reset a %T", raw)
+	Expect(tcpConn.SetLinger(0)).To(Succeed())
+	tcpConn.Close()
+}
+
+func (s *UploadSink) track(conn net.Conn) {
+	s.connsLock.Lock()
+	defer s.connsLock.Unlock()
+	s.conns[conn.RemoteAddr().String()] = conn
+}
+
+func (s *UploadSink) untrack(addr net.Addr) net.Conn {
+	s.connsLock.Lock()
+	defer s.connsLock.Unlock()
+	conn := s.conns[addr.String()]
+	delete(s.conns, addr.String())
+	return conn
+}
+
+type trackingListener struct {
+	net.Listener
+	sink *UploadSink
+}
+
+func (l *trackingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		l.sink.track(conn)
+	}
+	return conn, err
+}
+
+// URL returns the address an UploadAction should upload to in order to reach
+// path on the sink.
+func (s *UploadSink) URL(path string) string {
+	scheme := "http"
+	if s.server.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s/%s", scheme, s.addr, strings.TrimPrefix(path, "/"))
+}
+
+// Uploads returns every upload attempt the sink has received, including the
+// ones it rejected.
+func (s *UploadSink) Uploads() []Upload {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	uploads := make([]Upload, len(s.uploads))
+	copy(uploads, s.uploads)
+	return uploads
+}
+
+// SuccessfulUploads returns the uploads the sink accepted.
+func (s *UploadSink) SuccessfulUploads() []Upload {
+	successful := []Upload{}
+	for _, upload := range s.Uploads() {
+		if upload.StatusCode == http.StatusCreated {
+			successful = append(successful, upload)
+		}
+	}
+	return successful
+}
+
+func (s *UploadSink) Close() {
+	s.server.Close()
+}
@@ -0,0 +1,143 @@
+package helpers_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/helpers/certauthority"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UploadSink", func() {
+	var (
+		sink   *helpers.UploadSink
+		client *http.Client
+	)
+
+	BeforeEach(func() {
+		client = &http.Client{Timeout: 5 * time.Second}
+	})
+
+	AfterEach(func() {
+		sink.Close()
+	})
+
+	upload := func(body string) (*http.Response, error) {
+		return client.Post(sink.URL("some/path"), "text/plain", strings.NewReader(body))
+	}
+
+	It("records and accepts uploads", func() {
+		sink = helpers.NewUploadSink("127.0.0.1")
+
+		resp, err := upload("some-contents")
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+
+		uploads := sink.SuccessfulUploads()
+		Expect(uploads).To(HaveLen(1))
+		Expect(uploads[0].Method).To(Equal("POST"))
+		Expect(uploads[0].Path).To(Equal("/some/path"))
+		Expect(uploads[0].Body).To(Equal([]byte("some-contents")))
+		Expect(uploads[0].Checksums).To(HaveKeyWithValue("md5", "0b9791ad102b5f5f06ef68cef2aae26e"))
+	})
+
+	It("rejects uploads with the failure status until enough have failed", func() {
+		sink = helpers.NewUploadSink("127.0.0.1", func(config *helpers.UploadSinkConfig) {
+			config.FailuresBeforeSuccess = 2
+			config.FailureStatusCode = http.StatusServiceUnavailable
+		})
+
+		statuses := []int{}
+		for i := 0; i < 3; i++ {
+			resp, err := upload("some-contents")
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+			statuses = append(statuses, resp.StatusCode)
+		}
+
+		Expect(statuses).To(Equal([]int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusCreated}))
+		Expect(sink.Uploads()).To(HaveLen(3))
+		Expect(sink.SuccessfulUploads()).To(HaveLen(1))
+	})
+
+	It("resets the connection of a failed upload", func() {
+		sink = helpers.NewUploadSink("127.0.0.1", func(config *helpers.UploadSinkConfig) {
+			config.FailuresBeforeSuccess = 1
+			config.FailureMode = helpers.UploadFailureReset
+		})
+
+		_, err := upload("some-contents")
+		Expect(err).To(MatchError(ContainSubstring("connection reset by peer")))
+		Expect(sink.Uploads()[0].StatusCode).To(BeZero())
+
+		resp, err := upload("some-contents")
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+	})
+
+	It("waits for the read delay before reading the upload", func() {
+		sink = helpers.NewUploadSink("127.0.0.1", func(config *helpers.UploadSinkConfig) {
+			config.ReadDelay = 200 * time.Millisecond
+		})
+
+		start := time.Now()
+		resp, err := upload("some-contents")
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		Expect(sink.Uploads()[0].ReceivedAt.Sub(start)).To(BeNumerically(">=", 200*time.Millisecond))
+	})
+
+	Context("when it serves TLS", func() {
+		var depotDir string
+
+		BeforeEach(func() {
+			var err error
+			depotDir, err = ioutil.TempDir("", "upload-sink-depot")
+			Expect(err).NotTo(HaveOccurred())
+
+			authority, err := certauthority.NewCertAuthority(depotDir, "upload-sink-ca")
+			Expect(err).NotTo(HaveOccurred())
+			serverKey, serverCert, err := authority.GenerateSelfSignedCertAndKey("upload-sink", []string{"upload-sink"}, false)
+			Expect(err).NotTo(HaveOccurred())
+
+			sink = helpers.NewUploadSink("127.0.0.1", func(config *helpers.UploadSinkConfig) {
+				config.ServerCertFile = serverCert
+				config.ServerKeyFile = serverKey
+				config.FailuresBeforeSuccess = 1
+				config.FailureMode = helpers.UploadFailureReset
+			})
+
+			_, caCertFile := authority.CAAndKey()
+			caCert, err := ioutil.ReadFile(caCertFile)
+			Expect(err).NotTo(HaveOccurred())
+			caPool := x509.NewCertPool()
+			Expect(caPool.AppendCertsFromPEM(caCert)).To(BeTrue())
+			client.Transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: caPool}}
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(depotDir)).To(Succeed())
+		})
+
+		It("resets the TCP connection under the TLS one", func() {
+			Expect(sink.URL("some/path")).To(HavePrefix("https://"))
+
+			_, err := upload("some-contents")
+			Expect(err).To(MatchError(ContainSubstring("connection reset by peer")))
+
+			resp, err := upload("some-contents")
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		})
+	})
+})
@@ -2,58 +2,218 @@ package inigo_announcement_server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"sync"
-
+	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
 
 	"code.cloudfoundry.org/inigo/helpers"
-	. "github.com/onsi/gomega"
 )
 
-var server *httptest.Server
-var serverAddr string
+// defaultLongPollTimeout bounds how long /announcements/poll blocks when the
+// caller does not pass a timeout.
+const defaultLongPollTimeout = 30 * time.Second
 
-func Start(externalAddress string) {
-	lock := &sync.RWMutex{}
+type Announcement struct {
+	Name      string    `json:"name"`
+	Timestamp time.Time `json:"timestamp"`
+	SourceIP  string    `json:"source_ip"`
+	Payload   []byte    `json:"payload,omitempty"`
+}
 
-	registered := []string{}
+// Server records announcements made by processes running inside containers.
+// Each spec can run its own Server, so specs are safe to run in parallel.
+type Server struct {
+	server *httptest.Server
+	addr   string
 
-	server, serverAddr = helpers.Callback(externalAddress, func(w http.ResponseWriter, r *http.Request) {
-		switch r.URL.Path {
-		case "/announce":
-			lock.Lock()
-			registered = append(registered, r.URL.Query().Get("announcement"))
-			lock.Unlock()
-		case "/announcements":
-			lock.RLock()
-			json.NewEncoder(w).Encode(registered)
-			lock.RUnlock()
-		default:
-			w.WriteHeader(http.StatusNotFound)
+	lock          sync.Mutex
+	announcements []Announcement
+	// updated is closed and replaced every time an announcement arrives
+	updated chan struct{}
+
+	// stopped is closed by Stop, ending any long-polls
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+func New(externalAddress string) *Server {
+	s := &Server{
+		announcements: []Announcement{},
+		updated:       make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+
+	s.server, s.addr = helpers.Callback(externalAddress, s.handle)
+
+	return s
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/announce":
+		s.record(r)
+	case "/announcements":
+		json.NewEncoder(w).Encode(s.Names())
+	case "/announcements/poll":
+		s.longPoll(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *Server) record(r *http.Request) {
+	announcement := Announcement{
+		Name:      r.URL.Query().Get("announcement"),
+		Timestamp: time.Now(),
+	}
+
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		announcement.SourceIP = host
+	}
+
+	payload, err := ioutil.ReadAll(r.Body)
+	if err == nil && len(payload) > 0 {
+		announcement.Payload = payload
+	}
+
+	s.lock.Lock()
+	s.announcements = append(s.announcements, announcement)
+	close(s.updated)
+	s.updated = make(chan struct{})
+	s.lock.Unlock()
+}
+
+// longPoll responds with the announcements made after the first `since`
+// ones, blocking until there is at least one or the timeout passes.
+func (s *Server) longPoll(w http.ResponseWriter, r *http.Request) {
+	since, err := strconv.Atoi(r.URL.Query().Get("since"))
+	if err != nil {
+		since = 0
+	}
+
+	timeout, err := time.ParseDuration(r.URL.Query().Get("timeout"))
+	if err != nil {
+		timeout = defaultLongPollTimeout
+	}
+
+	json.NewEncoder(w).Encode(s.announcementsSince(since, timeout))
+}
+
+func (s *Server) announcementsSince(since int, timeout time.Duration) []Announcement {
+	deadline := time.After(timeout)
+
+	for {
+		s.lock.Lock()
+		if len(s.announcements) > since {
+			result := make([]Announcement, len(s.announcements)-since)
+			copy(result, s.announcements[since:])
+			s.lock.Unlock()
+			return result
+		}
+		updated := s.updated
+		s.lock.Unlock()
+
+		select {
+		case <-updated:
+		case <-deadline:
+			return []Announcement{}
+		case <-s.stopped:
+			return []Announcement{}
 		}
+	}
+}
+
+// Stop ends outstanding long-polls and shuts the server down. It is safe to
+// call more than once.
+func (s *Server) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopped)
+		s.server.CloseClientConnections()
+		s.server.Close()
 	})
 }
 
-func Stop() {
-	server.Close()
+func (s *Server) Addr() string {
+	return s.addr
 }
 
-func AnnounceURL(announcement string) string {
-	return fmt.Sprintf("http://%s/announce?announcement=%s", serverAddr, announcement)
+// AnnounceURL returns a URL that records announcement when requested. A POST
+// body, if any, is recorded as the announcement's payload.
+func (s *Server) AnnounceURL(announcement string) string {
+	return fmt.Sprintf("http://%s/announce?announcement=%s", s.addr, url.QueryEscape(announcement))
 }
 
-func Announcements() []string {
-	response, err := http.Get(fmt.Sprintf("http://%s/announcements", serverAddr))
-	Expect(err).NotTo(HaveOccurred())
+// PollURL returns the long-poll endpoint for announcements made after the
+// first since ones.
+func (s *Server) PollURL(since int, timeout time.Duration) string {
+	return fmt.Sprintf("http://%s/announcements/poll?since=%d&timeout=%s", s.addr, since, timeout)
+}
 
-	defer response.Body.Close()
+func (s *Server) Announcements() []Announcement {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	announcements := make([]Announcement, len(s.announcements))
+	copy(announcements, s.announcements)
+	return announcements
+}
+
+func (s *Server) Names() []string {
+	names := []string{}
+	for _, announcement := range s.Announcements() {
+		names = append(names, announcement.Name)
+	}
+	return names
+}
+
+// WaitFor blocks until announcement has been made or the timeout passes, and
+// returns its first occurrence.
+func (s *Server) WaitFor(announcement string, timeout time.Duration) (Announcement, error) {
+	deadline := time.Now().Add(timeout)
+	seen := 0
+
+	for {
+		select {
+		case <-s.stopped:
+			return Announcement{}, fmt.Errorf("server stopped while waiting for announcement %q", announcement)
+		default:
+		}
 
-	var responses []string
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return Announcement{}, fmt.Errorf("timed out after %s waiting for announcement %q", timeout, announcement)
+		}
+
+		for _, a := range s.announcementsSince(seen, remaining) {
+			if a.Name == announcement {
+				return a, nil
+			}
+			seen++
+		}
+	}
+}
+
+// Poll fetches announcements from a server's long-poll endpoint, which is
+// useful when the server was started by another process.
+func Poll(pollURL string) ([]Announcement, error) {
+	response, err := http.Get(pollURL)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
 
-	err = json.NewDecoder(response.Body).Decode(&responses)
-	Expect(err).NotTo(HaveOccurred())
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New("unexpected status polling announcements: " + response.Status)
+	}
 
-	return responses
+	var announcements []Announcement
+	err = json.NewDecoder(response.Body).Decode(&announcements)
+	return announcements, err
 }
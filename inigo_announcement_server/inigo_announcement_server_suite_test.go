@@ -0,0 +1,13 @@
+package inigo_announcement_server_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestInigoAnnouncementServer(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Inigo Announcement Server Suite")
+}
@@ -0,0 +1,142 @@
+package inigo_announcement_server_test
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/inigo/inigo_announcement_server"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Server", func() {
+	var server *inigo_announcement_server.Server
+
+	announce := func(name string) {
+		response, err := http.Get(server.AnnounceURL(name))
+		Expect(err).NotTo(HaveOccurred())
+		response.Body.Close()
+	}
+
+	BeforeEach(func() {
+		server = inigo_announcement_server.New("127.0.0.1")
+	})
+
+	AfterEach(func() {
+		server.Stop()
+	})
+
+	It("records each announcement with its source and time", func() {
+		before := time.Now()
+		announce("hello")
+
+		announcements := server.Announcements()
+		Expect(announcements).To(HaveLen(1))
+		Expect(announcements[0].Name).To(Equal("hello"))
+		Expect(announcements[0].SourceIP).To(Equal("127.0.0.1"))
+		Expect(announcements[0].Timestamp).To(BeTemporally(">=", before))
+	})
+
+	It("records a POST body as the payload", func() {
+		response, err := http.Post(server.AnnounceURL("with-payload"), "text/plain", strings.NewReader("some data"))
+		Expect(err).NotTo(HaveOccurred())
+		response.Body.Close()
+
+		Expect(server.Announcements()[0].Payload).To(Equal([]byte("some data")))
+	})
+
+	It("keeps announcements separate between servers", func() {
+		other := inigo_announcement_server.New("127.0.0.1")
+		defer other.Stop()
+
+		announce("mine")
+		Expect(server.Names()).To(ConsistOf("mine"))
+		Expect(other.Names()).To(BeEmpty())
+	})
+
+	Describe("WaitFor", func() {
+		It("returns once the announcement is made", func() {
+			go func() {
+				defer GinkgoRecover()
+				time.Sleep(100 * time.Millisecond)
+				announce("other")
+				announce("expected")
+			}()
+
+			announcement, err := server.WaitFor("expected", 5*time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(announcement.Name).To(Equal("expected"))
+		})
+
+		It("returns an error when the timeout passes", func() {
+			_, err := server.WaitFor("never", 100*time.Millisecond)
+			Expect(err).To(MatchError(ContainSubstring(`waiting for announcement "never"`)))
+		})
+	})
+
+	Describe("the long-poll endpoint", func() {
+		It("returns the announcements made after the given index", func() {
+			announce("first")
+			announce("second")
+
+			announcements, err := inigo_announcement_server.Poll(server.PollURL(1, time.Second))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(announcements).To(HaveLen(1))
+			Expect(announcements[0].Name).To(Equal("second"))
+		})
+
+		It("blocks until a new announcement arrives", func() {
+			go func() {
+				defer GinkgoRecover()
+				time.Sleep(100 * time.Millisecond)
+				announce("late")
+			}()
+
+			announcements, err := inigo_announcement_server.Poll(server.PollURL(0, 5*time.Second))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(announcements).To(HaveLen(1))
+			Expect(announcements[0].Name).To(Equal("late"))
+		})
+
+		It("returns when the server stops instead of waiting out the timeout", func() {
+			polled := make(chan error)
+			go func() {
+				_, err := inigo_announcement_server.Poll(server.PollURL(0, time.Minute))
+				polled <- err
+			}()
+
+			time.Sleep(100 * time.Millisecond)
+			stopped := make(chan struct{})
+			go func() {
+				server.Stop()
+				close(stopped)
+			}()
+
+			Eventually(stopped, 5*time.Second).Should(BeClosed())
+			Eventually(polled, 5*time.Second).Should(Receive())
+		})
+
+		It("returns nothing once the timeout passes", func() {
+			announcements, err := inigo_announcement_server.Poll(server.PollURL(0, 100*time.Millisecond))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(announcements).To(BeEmpty())
+		})
+	})
+
+	Describe("HaveAnnouncedInOrder", func() {
+		It("matches announcements made in the given order", func() {
+			announce("a")
+			announce("noise")
+			announce("b")
+
+			Expect(server).To(inigo_announcement_server.HaveAnnouncedInOrder("a", "b"))
+			Expect(server).NotTo(inigo_announcement_server.HaveAnnouncedInOrder("b", "a"))
+			Expect(server.Announcements()).To(inigo_announcement_server.HaveAnnouncedBefore("a", "b"))
+		})
+
+		It("fails when an announcement is missing", func() {
+			Expect([]string{"a"}).NotTo(inigo_announcement_server.HaveAnnouncedInOrder("a", "b"))
+		})
+	})
+})
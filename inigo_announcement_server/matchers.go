@@ -0,0 +1,76 @@
+package inigo_announcement_server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/onsi/gomega"
+	"github.com/onsi/gomega/format"
+)
+
+// HaveAnnouncedInOrder succeeds when the given announcements were all made,
+// in that order, though not necessarily consecutively. It accepts a *Server,
+// []Announcement or []string.
+func HaveAnnouncedInOrder(names ...string) gomega.OmegaMatcher {
+	return &announcedInOrderMatcher{expected: names}
+}
+
+// HaveAnnouncedBefore succeeds when first was announced before second.
+func HaveAnnouncedBefore(first, second string) gomega.OmegaMatcher {
+	return HaveAnnouncedInOrder(first, second)
+}
+
+type announcedInOrderMatcher struct {
+	expected []string
+}
+
+func (matcher *announcedInOrderMatcher) Match(actual interface{}) (bool, error) {
+	names, err := announcementNames(actual)
+	if err != nil {
+		return false, err
+	}
+
+	next := 0
+	for _, name := range names {
+		if next < len(matcher.expected) && name == matcher.expected[next] {
+			next++
+		}
+	}
+
+	return next == len(matcher.expected), nil
+}
+
+func (matcher *announcedInOrderMatcher) FailureMessage(actual interface{}) string {
+	names, _ := announcementNames(actual)
+	return fmt.Sprintf(
+		"Expected announcements\n%s\nto contain, in order\n%s",
+		format.IndentString(strings.Join(names, "\n"), 1),
+		format.IndentString(strings.Join(matcher.expected, "\n"), 1),
+	)
+}
+
+func (matcher *announcedInOrderMatcher) NegatedFailureMessage(actual interface{}) string {
+	names, _ := announcementNames(actual)
+	return fmt.Sprintf(
+		"Expected announcements\n%s\nnot to contain, in order\n%s",
+		format.IndentString(strings.Join(names, "\n"), 1),
+		format.IndentString(strings.Join(matcher.expected, "\n"), 1),
+	)
+}
+
+func announcementNames(actual interface{}) ([]string, error) {
+	switch a := actual.(type) {
+	case *Server:
+		return a.Names(), nil
+	case []string:
+		return a, nil
+	case []Announcement:
+		names := make([]string, 0, len(a))
+		for _, announcement := range a {
+			names = append(names, announcement.Name)
+		}
+		return names, nil
+	default:
+		return nil, fmt.Errorf("HaveAnnouncedInOrder expects a *Server, []Announcement or []string, got\n%s", format.Object(actual, 1))
+	}
+}
@@ -0,0 +1,77 @@
+package migrations_test
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+
+	"code.cloudfoundry.org/bbs"
+	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/world"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+	"github.com/tedsuo/ifrit/grouper"
+)
+
+var _ = world.DescribeForEachDBDriver("BBS migrations", func() {
+	Context("from an empty database", func() {
+		var (
+			oldMaker, currentMaker world.ComponentMaker
+
+			plumbing, bbsProcess ifrit.Process
+			bbsClient            bbs.InternalClient
+			db                   *sql.DB
+		)
+
+		BeforeEach(func() {
+			oldMaker = world.SpecComponentMaker(componentMakers[world.VersionV0])
+			currentMaker = world.SpecComponentMaker(componentMakers[world.VersionCurrent])
+
+			initialServices := grouper.Members{
+				{"sql", currentMaker.SQL()},
+			}
+			if currentMaker.ConsulEnabled() {
+				initialServices = append(initialServices, grouper.Member{"consul", currentMaker.Consul()})
+			}
+			plumbing = ginkgomon.Invoke(grouper.NewOrdered(os.Kill, grouper.Members{
+				{"initial-services", grouper.NewParallel(os.Kill, initialServices)},
+				{"locket", currentMaker.Locket()},
+			}))
+			helpers.ConsulWaitUntilReady(currentMaker.Addresses())
+
+			bbsClient = currentMaker.BBSClient()
+			db = helpers.SQLConnection(lgr, currentMaker)
+		})
+
+		AfterEach(func() {
+			Expect(db.Close()).To(Succeed())
+			helpers.StopProcesses(bbsProcess, plumbing)
+		})
+
+		It("migrates a database seeded by the old BBS and keeps every record intact", func() {
+			By("seeding an empty database through the old bbs")
+			bbsProcess = ginkgomon.Invoke(oldMaker.BBS())
+			helpers.SeedBBS(lgr, bbsClient, currentMaker.Addresses())
+			before := helpers.FetchBBSRecords(lgr, bbsClient)
+			oldVersion := helpers.BBSSchemaVersion(db)
+			helpers.StopProcesses(bbsProcess)
+
+			latest := helpers.LatestBBSSchemaVersion()
+			if oldVersion >= latest {
+				Skip(fmt.Sprintf("the V0 BBS already migrated the database to the latest schema version (%d), so there is no migration to test", latest))
+			}
+
+			By("starting the current bbs on the same database")
+			bbsProcess = ginkgomon.Invoke(currentMaker.BBS())
+
+			Expect(helpers.BBSSchemaVersion(db)).To(Equal(latest))
+
+			after := helpers.FetchBBSRecords(lgr, bbsClient)
+			Expect(after.DesiredLRPs).To(Equal(before.DesiredLRPs))
+			Expect(after.ActualLRPs).To(Equal(before.ActualLRPs))
+			Expect(after.Tasks).To(Equal(before.Tasks))
+		})
+	})
+})
@@ -0,0 +1,123 @@
+package migrations_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+
+	"code.cloudfoundry.org/consuladapter/consulrunner"
+	"code.cloudfoundry.org/lager"
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/config"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gexec"
+
+	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/helpers/certauthority"
+	"code.cloudfoundry.org/inigo/helpers/portauthority"
+	"code.cloudfoundry.org/inigo/world"
+)
+
+var (
+	componentMakers map[string]world.ComponentMaker
+
+	lgr          lager.Logger
+	suiteTempDir string
+)
+
+var _ = SynchronizedBeforeSuite(func() []byte {
+	suiteTempDir = world.TempDir("before-suite")
+	payload, err := json.Marshal(world.VersionedArtifacts{
+		world.VersionV0:      {Executables: CompileV0Executables()},
+		world.VersionCurrent: {Executables: CompileTestedExecutables()},
+	})
+	Expect(err).NotTo(HaveOccurred())
+
+	return payload
+}, func(encodedBuiltArtifacts []byte) {
+	var builtArtifacts world.VersionedArtifacts
+
+	err := json.Unmarshal(encodedBuiltArtifacts, &builtArtifacts)
+	Expect(err).NotTo(HaveOccurred())
+
+	_, dbBaseConnectionString := world.DBInfo()
+
+	addresses := world.ComponentAddresses{
+		Consul: fmt.Sprintf("127.0.0.1:%d", 12750+config.GinkgoConfig.ParallelNode*consulrunner.PortOffsetLength),
+		BBS:    fmt.Sprintf("127.0.0.1:%d", 20500+config.GinkgoConfig.ParallelNode*2),
+		Health: fmt.Sprintf("127.0.0.1:%d", 20500+config.GinkgoConfig.ParallelNode*2+1),
+		// nothing listens here; the bbs only needs somewhere to send auctions
+		Auctioneer: fmt.Sprintf("127.0.0.1:%d", 23000+config.GinkgoConfig.ParallelNode),
+		Locket:     fmt.Sprintf("127.0.0.1:%d", 26500+config.GinkgoConfig.ParallelNode),
+		SQL:        fmt.Sprintf("%sdiego_%d", dbBaseConnectionString, config.GinkgoConfig.ParallelNode),
+	}
+
+	node := GinkgoParallelNode()
+	startPort := 1000 * node
+	portRange := 950
+	endPort := startPort + portRange
+
+	allocator, err := portauthority.New(startPort, endPort)
+	Expect(err).NotTo(HaveOccurred())
+
+	certDepot := world.TempDirWithParent(suiteTempDir, "cert-depot")
+
+	certAuthority, err := certauthority.NewCertAuthority(certDepot, "ca")
+	Expect(err).NotTo(HaveOccurred())
+
+	componentMakers = map[string]world.ComponentMaker{}
+	for version := range builtArtifacts {
+		componentMakers[version] = builtArtifacts.ComponentMaker(version, addresses, allocator, certAuthority)
+		componentMakers[version].Setup()
+	}
+
+	lgr = lager.NewLogger("test")
+	lgr.RegisterSink(lager.NewWriterSink(GinkgoWriter, lager.DEBUG))
+})
+
+var _ = AfterSuite(func() {
+	for _, maker := range componentMakers {
+		maker.Teardown()
+	}
+
+	deleteSuiteTempDir := func() error { return os.RemoveAll(suiteTempDir) }
+	Eventually(deleteSuiteTempDir).Should(Succeed())
+})
+
+func TestMigrations(t *testing.T) {
+	helpers.RegisterDefaultTimeouts()
+
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Migrations Integration Suite")
+}
+
+func CompileTestedExecutables() world.BuiltExecutables {
+	var err error
+
+	builtExecutables := world.BuiltExecutables{}
+
+	builtExecutables["bbs"], err = gexec.Build("code.cloudfoundry.org/bbs/cmd/bbs", "-race")
+	Expect(err).NotTo(HaveOccurred())
+
+	builtExecutables["locket"], err = gexec.Build("code.cloudfoundry.org/locket/cmd/locket", "-race")
+	Expect(err).NotTo(HaveOccurred())
+
+	return builtExecutables
+}
+
+// CompileV0Executables builds the old BBS from the Diego release checked out
+// in GOPATH_V0.
+func CompileV0Executables() world.BuiltExecutables {
+	var err error
+
+	builtExecutables := world.BuiltExecutables{}
+
+	Expect(os.Setenv("GO111MODULE", "auto")).To(Succeed())
+	builtExecutables["bbs"], err = gexec.BuildIn(os.Getenv("GOPATH_V0"), "code.cloudfoundry.org/bbs/cmd/bbs", "-race")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(os.Setenv("GO111MODULE", "")).To(Succeed())
+
+	return builtExecutables
+}
@@ -0,0 +1 @@
+package migrations // import "code.cloudfoundry.org/inigo/migrations"
@@ -11,6 +11,7 @@ import (
 	"code.cloudfoundry.org/bbs"
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/world"
 	"code.cloudfoundry.org/lager"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -22,7 +23,7 @@ import (
 	"code.cloudfoundry.org/inigo/fixtures"
 )
 
-var _ = Describe("LRPs with volume mounts", func() {
+var _ = world.DescribeForEachDBDriver("LRPs with volume mounts", func() {
 	var (
 		cellProcess         ifrit.Process
 		fileServerStaticDir string
@@ -36,12 +37,15 @@ var _ = Describe("LRPs with volume mounts", func() {
 	BeforeEach(func() {
 		var fileServerRunner ifrit.Runner
 		fileServerRunner, fileServerStaticDir = componentMaker.FileServer()
+		initialServices := grouper.Members{
+			{"sql", componentMaker.SQL()},
+			{"nats", componentMaker.NATS()},
+		}
+		if componentMaker.ConsulEnabled() {
+			initialServices = append(initialServices, grouper.Member{"consul", componentMaker.Consul()})
+		}
 		plumbing = ginkgomon.Invoke(grouper.NewOrdered(os.Kill, grouper.Members{
-			{"initial-services", grouper.NewParallel(os.Kill, grouper.Members{
-				{"sql", componentMaker.SQL()},
-				{"nats", componentMaker.NATS()},
-				{"consul", componentMaker.Consul()},
-			})},
+			{"initial-services", grouper.NewParallel(os.Kill, initialServices)},
 			{"locket", componentMaker.Locket()},
 			{"bbs", componentMaker.BBS()},
 		}))
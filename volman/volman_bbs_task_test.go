@@ -10,6 +10,7 @@ import (
 	"code.cloudfoundry.org/bbs"
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/inigo/helpers"
+	"code.cloudfoundry.org/inigo/world"
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/lager/lagertest"
 	repconfig "code.cloudfoundry.org/rep/cmd/rep/config"
@@ -20,7 +21,7 @@ import (
 	"github.com/tedsuo/ifrit/grouper"
 )
 
-var _ = Describe("Tasks", func() {
+var _ = world.DescribeForEachDBDriver("Tasks", func() {
 	var (
 		cellProcess, plumbing ifrit.Process
 		logger                lager.Logger
@@ -32,11 +33,14 @@ var _ = Describe("Tasks", func() {
 		var fileServerRunner ifrit.Runner
 		fileServerRunner, _ = componentMaker.FileServer()
 
+		initialServices := grouper.Members{
+			{"sql", componentMaker.SQL()},
+		}
+		if componentMaker.ConsulEnabled() {
+			initialServices = append(initialServices, grouper.Member{"consul", componentMaker.Consul()})
+		}
 		plumbing = ginkgomon.Invoke(grouper.NewOrdered(os.Kill, grouper.Members{
-			{"initial-services", grouper.NewParallel(os.Kill, grouper.Members{
-				{"sql", componentMaker.SQL()},
-				{"consul", componentMaker.Consul()},
-			})},
+			{"initial-services", grouper.NewParallel(os.Kill, initialServices)},
 			{"locket", componentMaker.Locket()},
 			{"bbs", componentMaker.BBS()},
 		}))
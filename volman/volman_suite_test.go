@@ -31,7 +31,9 @@ import (
 )
 
 var (
-	componentMaker world.ComponentMaker
+	// componentMaker is suiteComponentMaker switched to the database driver
+	// of the running spec; see world.DescribeForEachDBDriver.
+	componentMaker, suiteComponentMaker world.ComponentMaker
 
 	gardenProcess ifrit.Process
 	gardenClient  garden.Client
@@ -100,16 +102,19 @@ var _ = SynchronizedBeforeSuite(func() []byte {
 	certAuthority, err := certauthority.NewCertAuthority(certDepot, "ca")
 	Expect(err).NotTo(HaveOccurred())
 
-	componentMaker = world.MakeComponentMaker(builtArtifacts, addresses, allocator, certAuthority)
-	componentMaker.Setup()
+	suiteComponentMaker = world.MakeComponentMaker(builtArtifacts, addresses, allocator, certAuthority)
+	suiteComponentMaker.Setup()
+	componentMaker = suiteComponentMaker
 })
 
 var _ = AfterSuite(func() {
 	Expect(os.RemoveAll(certDepot)).To(Succeed())
-	componentMaker.Teardown()
+	suiteComponentMaker.Teardown()
 })
 
 var _ = BeforeEach(func() {
+	componentMaker = world.SpecComponentMaker(suiteComponentMaker)
+
 	logger = lagertest.NewTestLogger("volman-inigo-suite")
 
 	gardenProcess = ginkgomon.Invoke(componentMaker.Garden())
@@ -0,0 +1,95 @@
+package world
+
+import (
+	"fmt"
+	"net"
+
+	auctioneerconfig "code.cloudfoundry.org/auctioneer/cmd/auctioneer/config"
+	"code.cloudfoundry.org/lager"
+	locketmodels "code.cloudfoundry.org/locket/models"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit/ginkgomon"
+)
+
+// AuctioneerLockKey is the locket lock the auctioneers compete for.
+const AuctioneerLockKey = "auctioneer"
+
+// AuctioneerInstance is one member of an AuctioneerCluster. It reaches
+// locket through LocketProxy, so that it can be partitioned from it.
+type AuctioneerInstance struct {
+	UUID          string
+	ListenAddress string
+	LocketProxy   *TCPProxy
+	Runner        *ginkgomon.Runner
+}
+
+// AuctioneerCluster is a set of auctioneers competing for the locket lock.
+// As with BBSCluster, only the lock holder serves, and the BBS keeps using
+// the world's auctioneer address, where the cluster's FailoverProxy
+// listens.
+type AuctioneerCluster struct {
+	Instances []AuctioneerInstance
+	address   string
+}
+
+// MakeAuctioneerCluster makes n auctioneers, each with its own UUID, listen
+// address and locket proxy. Their runners are ready as soon as they start
+// waiting for the lock.
+func MakeAuctioneerCluster(maker ComponentMaker, n int, modifyConfigFuncs ...func(*auctioneerconfig.AuctioneerConfig)) AuctioneerCluster {
+	host, _, err := net.SplitHostPort(maker.Addresses().Auctioneer)
+	Expect(err).NotTo(HaveOccurred())
+
+	cluster := AuctioneerCluster{address: maker.Addresses().Auctioneer}
+	for i := 0; i < n; i++ {
+		ports, err := maker.PortAllocator().ClaimPorts(2)
+		Expect(err).NotTo(HaveOccurred())
+
+		instance := AuctioneerInstance{
+			UUID:          fmt.Sprintf("auctioneer-inigo-lock-owner-%d", i),
+			ListenAddress: fmt.Sprintf("%s:%d", host, ports),
+			LocketProxy:   NewTCPProxy(fmt.Sprintf("%s:%d", host, ports+1), maker.Addresses().Locket),
+		}
+
+		configs := append([]func(*auctioneerconfig.AuctioneerConfig){func(cfg *auctioneerconfig.AuctioneerConfig) {
+			cfg.UUID = instance.UUID
+			cfg.ListenAddress = instance.ListenAddress
+			cfg.ClientLocketConfig.LocketAddress = instance.LocketProxy.Address()
+		}}, modifyConfigFuncs...)
+
+		runner, ok := maker.Auctioneer(configs...).(*ginkgomon.Runner)
+		Expect(ok).To(BeTrue(), "auctioneer runner is not a ginkgomon runner")
+		runner.Name = fmt.Sprintf("auctioneer-%d", i)
+		runner.StartCheck = `"auctioneer.locket-lock.started"`
+		instance.Runner = runner
+
+		cluster.Instances = append(cluster.Instances, instance)
+	}
+
+	return cluster
+}
+
+// Addresses returns the listen addresses of the instances, in order.
+func (c AuctioneerCluster) Addresses() []string {
+	addresses := []string{}
+	for _, instance := range c.Instances {
+		addresses = append(addresses, instance.ListenAddress)
+	}
+	return addresses
+}
+
+// FailoverProxy returns the proxy in front of the cluster.
+func (c AuctioneerCluster) FailoverProxy() *TCPProxy {
+	return NewTCPProxy(c.address, c.Addresses()...)
+}
+
+// Active returns the index of the auctioneer holding the lock, or -1 if
+// none does.
+func (c AuctioneerCluster) Active(logger lager.Logger, client locketmodels.LocketClient) int {
+	owner := lockOwner(logger, client, AuctioneerLockKey)
+	for i, instance := range c.Instances {
+		if instance.UUID == owner {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,142 @@
+package world
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"code.cloudfoundry.org/bbs"
+	bbsconfig "code.cloudfoundry.org/bbs/cmd/bbs/config"
+	"code.cloudfoundry.org/lager"
+	locketmodels "code.cloudfoundry.org/locket/models"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit/ginkgomon"
+)
+
+// BBSLockKey is the locket lock the BBS instances compete for.
+const BBSLockKey = "bbs"
+
+// BBSInstance is one member of a BBSCluster.
+type BBSInstance struct {
+	UUID          string
+	ListenAddress string
+	HealthAddress string
+	Runner        *ginkgomon.Runner
+}
+
+// BBSCluster is a set of BBS instances competing for the locket lock, as in
+// an HA deployment. Only the lock holder serves; the others wait for the
+// lock without listening.
+//
+// Clients given the cluster's URLs fail over by going through the list to
+// the instance that answers, which is always the current leader; Client
+// makes a client for one instance. Components only take one BBS address,
+// which a deployment points at the live instance through DNS. In the world
+// the cluster's FailoverProxy stands in for that: it listens on the world's
+// BBS address and hands each new connection to the first instance in
+// Addresses that accepts it.
+type BBSCluster struct {
+	Instances []BBSInstance
+	address   string
+	ssl       SSLConfig
+}
+
+// MakeBBSCluster makes n BBS instances, each with its own UUID, listen and
+// health address. Their runners are ready as soon as they start waiting for
+// the lock, so that standbys can be invoked; use Leader to find out which
+// one is serving.
+func MakeBBSCluster(maker ComponentMaker, n int, modifyConfigFuncs ...func(*bbsconfig.BBSConfig)) BBSCluster {
+	host, _, err := net.SplitHostPort(maker.Addresses().BBS)
+	Expect(err).NotTo(HaveOccurred())
+
+	cluster := BBSCluster{
+		address: maker.Addresses().BBS,
+		ssl:     maker.BBSSSLConfig(),
+	}
+	for i := 0; i < n; i++ {
+		ports, err := maker.PortAllocator().ClaimPorts(2)
+		Expect(err).NotTo(HaveOccurred())
+
+		instance := BBSInstance{
+			UUID:          fmt.Sprintf("bbs-inigo-lock-owner-%d", i),
+			ListenAddress: fmt.Sprintf("%s:%d", host, ports),
+			HealthAddress: fmt.Sprintf("%s:%d", host, ports+1),
+		}
+
+		configs := append([]func(*bbsconfig.BBSConfig){func(cfg *bbsconfig.BBSConfig) {
+			cfg.UUID = instance.UUID
+			cfg.ListenAddress = instance.ListenAddress
+			cfg.HealthAddress = instance.HealthAddress
+		}}, modifyConfigFuncs...)
+
+		runner, ok := maker.BBS(configs...).(*ginkgomon.Runner)
+		Expect(ok).To(BeTrue(), "BBS runner is not a ginkgomon runner")
+		runner.Name = fmt.Sprintf("bbs-%d", i)
+		runner.StartCheck = "bbs.locket-lock.started"
+		instance.Runner = runner
+
+		cluster.Instances = append(cluster.Instances, instance)
+	}
+
+	return cluster
+}
+
+// Addresses returns the listen addresses of the instances, in order.
+func (c BBSCluster) Addresses() []string {
+	addresses := []string{}
+	for _, instance := range c.Instances {
+		addresses = append(addresses, instance.ListenAddress)
+	}
+	return addresses
+}
+
+// URLs returns the URLs of the instances, in order: the address list a
+// failover-aware client is given.
+func (c BBSCluster) URLs() []string {
+	urls := []string{}
+	for _, address := range c.Addresses() {
+		urls = append(urls, "https://"+address)
+	}
+	return urls
+}
+
+// Client returns a client for instance i alone.
+func (c BBSCluster) Client(i int) bbs.InternalClient {
+	client, err := bbs.NewClient(
+		c.URLs()[i],
+		c.ssl.CACert,
+		c.ssl.ClientCert,
+		c.ssl.ClientKey,
+		0, 0,
+	)
+	Expect(err).NotTo(HaveOccurred())
+	return client
+}
+
+// FailoverProxy returns the proxy in front of the cluster.
+func (c BBSCluster) FailoverProxy() *TCPProxy {
+	return NewTCPProxy(c.address, c.Addresses()...)
+}
+
+// Leader returns the index of the instance holding the BBS lock, or -1 if
+// none does.
+func (c BBSCluster) Leader(logger lager.Logger, client locketmodels.LocketClient) int {
+	owner := lockOwner(logger, client, BBSLockKey)
+	for i, instance := range c.Instances {
+		if instance.UUID == owner {
+			return i
+		}
+	}
+	return -1
+}
+
+// lockOwner returns the owner of the lock key, or "" if it can't be
+// fetched.
+func lockOwner(logger lager.Logger, client locketmodels.LocketClient, key string) string {
+	response, err := client.Fetch(context.Background(), &locketmodels.FetchRequest{Key: key})
+	if err != nil {
+		logger.Debug("fetch-lock-failed", lager.Data{"key": key, "error": err.Error()})
+		return ""
+	}
+	return response.Resource.Owner
+}
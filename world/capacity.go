@@ -0,0 +1,56 @@
+package world
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"code.cloudfoundry.org/guardian/gqt/runner"
+	repconfig "code.cloudfoundry.org/rep/cmd/rep/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+)
+
+// RepCapacity makes a rep advertise memoryMB and diskMB instead of what the
+// shared garden reports. Container capacity always comes from garden; use
+// CellGarden to limit it per cell.
+func RepCapacity(memoryMB, diskMB int) func(*repconfig.RepConfig) {
+	return func(cfg *repconfig.RepConfig) {
+		cfg.MemoryMB = strconv.Itoa(memoryMB)
+		cfg.DiskMB = strconv.Itoa(diskMB)
+	}
+}
+
+// CellGarden makes a garden of its own for the nth rep, allowing at most
+// maxContainers application containers, and returns it along with the rep
+// option that points the rep at it. The garden gets its own port, iptables
+// tag and network pool so that it can run next to the shared one.
+func CellGarden(maker ComponentMaker, n int, maxContainers int) (ifrit.Runner, func(*repconfig.RepConfig)) {
+	host, _, err := net.SplitHostPort(maker.Addresses().Garden)
+	Expect(err).NotTo(HaveOccurred())
+
+	port, err := maker.PortAllocator().ClaimPorts(1)
+	Expect(err).NotTo(HaveOccurred())
+	address := fmt.Sprintf("%s:%d", host, port)
+
+	// the executor keeps one container back for its garden healthcheck
+	gardenMaxContainers := uint64(maxContainers + 1)
+
+	gardenRunner := maker.Garden(func(cfg *runner.GdnRunnerConfig) {
+		cfg.BindPort = intPtr(int(port))
+		cfg.MaxContainers = &gardenMaxContainers
+		cfg.Tag = cellGardenTag(n)
+		cfg.NetworkPool = fmt.Sprintf("10.%d.%d.0/24", 200+n%50, GinkgoParallelNode()%256)
+	})
+
+	return gardenRunner, func(cfg *repconfig.RepConfig) {
+		cfg.GardenAddr = address
+	}
+}
+
+// garden tags are at most two characters; the shared gardens use the
+// parallel node number, so cell gardens use letters.
+func cellGardenTag(n int) string {
+	return fmt.Sprintf("%c%c", 'a'+GinkgoParallelNode()%26, 'a'+n%26)
+}
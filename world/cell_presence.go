@@ -0,0 +1,37 @@
+package world
+
+import (
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/rep/maintain"
+	"github.com/tedsuo/ifrit"
+)
+
+// noopCellPresenceClient stands in for Consul's cell registrations in a
+// world without Consul, where cells only register in locket.
+type noopCellPresenceClient struct{}
+
+var _ maintain.CellPresenceClient = noopCellPresenceClient{}
+
+func (noopCellPresenceClient) NewCellPresenceRunner(logger lager.Logger, cellPresence *models.CellPresence, retryInterval, lockTTL time.Duration) ifrit.Runner {
+	return ifrit.RunFunc(func(signals <-chan os.Signal, ready chan<- struct{}) error {
+		close(ready)
+		<-signals
+		return nil
+	})
+}
+
+func (noopCellPresenceClient) CellById(logger lager.Logger, cellId string) (*models.CellPresence, error) {
+	return nil, models.ErrResourceNotFound
+}
+
+func (noopCellPresenceClient) Cells(logger lager.Logger) (models.CellSet, error) {
+	return models.CellSet{}, nil
+}
+
+func (noopCellPresenceClient) CellEvents(logger lager.Logger) <-chan models.CellEvent {
+	return make(chan models.CellEvent)
+}
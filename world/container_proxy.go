@@ -0,0 +1,104 @@
+package world
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	sshproxyconfig "code.cloudfoundry.org/diego-ssh/cmd/ssh-proxy/config"
+	"code.cloudfoundry.org/durationjson"
+	"code.cloudfoundry.org/inigo/helpers/certauthority"
+	repconfig "code.cloudfoundry.org/rep/cmd/rep/config"
+	. "github.com/onsi/gomega"
+)
+
+// ContainerProxyCredentials is the instance-identity CA a rep signs
+// container certificates with, together with a client certificate that
+// components dialing the container proxy's TLS ports can present.
+type ContainerProxyCredentials struct {
+	CredDir string
+
+	// CACert is the root that both the instance-identity CA and the client
+	// certificate chain to.
+	CACert string
+
+	InstanceIdentityCACert string
+	InstanceIdentityCAKey  string
+
+	ClientCert string
+	ClientKey  string
+}
+
+// MakeContainerProxyCredentials generates a fresh CA under parentDir and
+// issues an instance-identity CA and a client certificate from it.
+func MakeContainerProxyCredentials(parentDir string) ContainerProxyCredentials {
+	depotDir := TempDirWithParent(parentDir, "container-proxy-creds")
+
+	certAuthority, err := certauthority.NewCertAuthority(depotDir, "ca-with-no-max-path-length")
+	Expect(err).NotTo(HaveOccurred())
+	_, caCert := certAuthority.CAAndKey()
+
+	instanceIdentityKey, instanceIdentityCert, err := certAuthority.GenerateSelfSignedCertAndKey("instance-identity", []string{"instance-identity"}, true)
+	Expect(err).NotTo(HaveOccurred())
+
+	clientKey, clientCert, err := certAuthority.GenerateSelfSignedCertAndKey("container-proxy-client", []string{"container-proxy-client"}, false)
+	Expect(err).NotTo(HaveOccurred())
+
+	credDir := filepath.Join(depotDir, "instance-creds")
+	Expect(os.MkdirAll(credDir, 0777)).To(Succeed())
+
+	return ContainerProxyCredentials{
+		CredDir:                credDir,
+		CACert:                 caCert,
+		InstanceIdentityCACert: instanceIdentityCert,
+		InstanceIdentityCAKey:  instanceIdentityKey,
+		ClientCert:             clientCert,
+		ClientKey:              clientKey,
+	}
+}
+
+// RepWithContainerProxy configures the rep to issue instance-identity
+// credentials and to front every container port with envoy. When
+// requireClientCerts is set, envoy only accepts clients presenting a
+// certificate chaining to c.CACert.
+func (c ContainerProxyCredentials) RepWithContainerProxy(envoyConfigDir string, requireClientCerts bool) func(*repconfig.RepConfig) {
+	return func(cfg *repconfig.RepConfig) {
+		cfg.InstanceIdentityCredDir = c.CredDir
+		cfg.InstanceIdentityCAPath = c.InstanceIdentityCACert
+		cfg.InstanceIdentityPrivateKeyPath = c.InstanceIdentityCAKey
+		cfg.InstanceIdentityValidityPeriod = durationjson.Duration(time.Hour)
+
+		cfg.EnableContainerProxy = true
+		cfg.EnvoyConfigRefreshDelay = durationjson.Duration(time.Second)
+		cfg.ContainerProxyPath = os.Getenv("ENVOY_PATH")
+		cfg.ContainerProxyConfigPath = envoyConfigDir
+
+		if requireClientCerts {
+			cfg.ContainerProxyTrustedCACerts = []string{readFile(c.CACert)}
+			cfg.ContainerProxyRequireClientCerts = true
+		}
+	}
+}
+
+// SSHProxyBackendTLS makes ssh-proxy reach sshd through the container
+// proxy's TLS port, verifying envoy's instance-identity certificate and
+// presenting the client certificate.
+func (c ContainerProxyCredentials) SSHProxyBackendTLS(cfg *sshproxyconfig.SSHProxyConfig) {
+	cfg.BackendsTLSEnabled = true
+	cfg.BackendsTLSCACerts = readFile(c.CACert)
+	cfg.BackendsTLSClientCert = readFile(c.ClientCert)
+	cfg.BackendsTLSClientKey = readFile(c.ClientKey)
+}
+
+// SSHProxyConnectToInstanceAddress makes ssh-proxy dial the container's
+// instance address and container port instead of the cell's host port.
+func SSHProxyConnectToInstanceAddress(cfg *sshproxyconfig.SSHProxyConfig) {
+	cfg.ConnectToInstanceAddress = true
+}
+
+func readFile(path string) string {
+	content, err := ioutil.ReadFile(path)
+	Expect(err).NotTo(HaveOccurred())
+	return string(content)
+}
@@ -0,0 +1,115 @@
+package world
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/bbs/db/sqldb/helpers"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+)
+
+// DBDrivers are the database drivers ForEachDBDriver runs specs against.
+var DBDrivers = []string{PostgresDriver, MySQLDriver}
+
+var (
+	availableDBDriversLock sync.Mutex
+	availableDBDrivers     = map[string]bool{}
+)
+
+// DBDriverTag is the text of the context ForEachDBDriver defines for
+// driver, and so shows up in the name of every spec run against it.
+func DBDriverTag(driver string) string {
+	return fmt.Sprintf("[db:%s]", driver)
+}
+
+// ForEachDBDriver defines the specs in body once for each of DBDrivers,
+// each in a context tagged with DBDriverTag. The suite's BeforeEach picks
+// the driver up through SpecComponentMaker.
+func ForEachDBDriver(body func(driver string)) {
+	for _, driver := range DBDrivers {
+		driver := driver
+		Context(DBDriverTag(driver), func() {
+			body(driver)
+		})
+	}
+}
+
+// DescribeForEachDBDriver is Describe with its specs defined once for each
+// of DBDrivers, as ForEachDBDriver does. Suites that start a database use
+// it for their top-level containers, so that every spec runs against every
+// driver:
+//
+//	var _ = world.DescribeForEachDBDriver("Locket", func() {
+//		It("...", func() { ... })
+//	})
+func DescribeForEachDBDriver(text string, body func()) bool {
+	return Describe(text, func() {
+		ForEachDBDriver(func(string) {
+			body()
+		})
+	})
+}
+
+// SpecDBDriver returns the driver the running spec is tagged with by
+// ForEachDBDriver, or the one DBInfo picks for untagged specs.
+func SpecDBDriver() string {
+	driver, _ := specDBDriver()
+	return driver
+}
+
+// SpecComponentMaker returns maker switched to the running spec's
+// SpecDBDriver. Suites call it in their top-level BeforeEach, before
+// starting any component. A spec tagged with a driver whose database is not
+// reachable is skipped, so that a run with only one database available
+// still passes.
+func SpecComponentMaker(maker ComponentMaker) ComponentMaker {
+	driver, tagged := specDBDriver()
+	if tagged && !DBDriverAvailable(driver) {
+		Skip(fmt.Sprintf("no %s database available", driver))
+	}
+	return WithDBDriver(maker, driver)
+}
+
+func specDBDriver() (string, bool) {
+	for _, text := range CurrentGinkgoTestDescription().ComponentTexts {
+		for _, driver := range DBDrivers {
+			if text == DBDriverTag(driver) {
+				return driver, true
+			}
+		}
+	}
+
+	driver, _ := DBInfo()
+	return driver, false
+}
+
+// DBDriverAvailable reports whether the test database for driver accepts
+// connections. The answer is remembered for the rest of the run.
+func DBDriverAvailable(driver string) bool {
+	availableDBDriversLock.Lock()
+	defer availableDBDriversLock.Unlock()
+
+	available, checked := availableDBDrivers[driver]
+	if !checked {
+		available = pingDB(driver)
+		availableDBDrivers[driver] = available
+	}
+	return available
+}
+
+func pingDB(driver string) bool {
+	driverName, baseConnectionString := DBInfoForDriver(driver)
+
+	db, err := helpers.Connect(lagertest.NewTestLogger("db-matrix"), driverName, baseConnectionString, "", false)
+	if err != nil {
+		return false
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return db.PingContext(ctx) == nil
+}
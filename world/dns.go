@@ -0,0 +1,341 @@
+package world
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	auctioneerconfig "code.cloudfoundry.org/auctioneer/cmd/auctioneer/config"
+	bbsconfig "code.cloudfoundry.org/bbs/cmd/bbs/config"
+	repconfig "code.cloudfoundry.org/rep/cmd/rep/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// The names Diego components find each other by in a deployment. Every
+// cell is a subdomain of CellServiceDomain, named after its cell ID.
+const (
+	BBSServiceName        = "bbs.service.cf.internal"
+	LocketServiceName     = "locket.service.cf.internal"
+	AuctioneerServiceName = "auctioneer.service.cf.internal"
+	CellServiceDomain     = "cell.service.cf.internal"
+)
+
+// names under serviceDomain are only ever answered by the DNSServer, so
+// that removing a record makes a service disappear
+const serviceDomain = "service.cf.internal."
+
+const dnsForwardTimeout = 2 * time.Second
+
+// DNSServer stands in for the DNS components find each other through in a
+// deployment. It answers for the service names, pointing them at the
+// world's components, and passes other queries on to the machine's own
+// nameserver. Records can be changed while it runs, e.g. to move a service
+// to another address as a failover would.
+//
+// Components only resolve through it when started with Resolving. DNS
+// clients can't be given a port, so it listens on port 53 of a loopback
+// address of the node's own, which takes root.
+type DNSServer struct {
+	address    string
+	upstream   string
+	dir        string
+	resolvConf string
+
+	lock    sync.Mutex
+	records map[string][]net.IP
+}
+
+// NewDNSServer returns a DNS server with records for the service names of
+// maker's BBS, locket and auctioneer, and for every cell.
+func NewDNSServer(maker ComponentMaker) *DNSServer {
+	d := &DNSServer{
+		address:  fmt.Sprintf("127.0.%d.53:53", GinkgoParallelNode()),
+		upstream: systemNameserver(),
+		dir:      TempDir("dns"),
+		records:  map[string][]net.IP{},
+	}
+
+	host, _, err := net.SplitHostPort(d.address)
+	Expect(err).NotTo(HaveOccurred())
+	d.resolvConf = filepath.Join(d.dir, "resolv.conf")
+	err = ioutil.WriteFile(d.resolvConf, []byte("nameserver "+host+"\n"), 0644)
+	Expect(err).NotTo(HaveOccurred())
+
+	addresses := maker.Addresses()
+	d.SetRecord(BBSServiceName, addressHost(addresses.BBS))
+	d.SetRecord(LocketServiceName, addressHost(addresses.Locket))
+	d.SetRecord(AuctioneerServiceName, addressHost(addresses.Auctioneer))
+	d.SetRecord("*."+CellServiceDomain, addressHost(addresses.Rep))
+
+	return d
+}
+
+func (d *DNSServer) Address() string {
+	return d.address
+}
+
+// SetRecord points name at ips, replacing whatever it pointed at. A name
+// starting with "*." covers the subdomains that have no record of their
+// own.
+func (d *DNSServer) SetRecord(name string, ips ...string) {
+	parsed := []net.IP{}
+	for _, ip := range ips {
+		parsedIP := net.ParseIP(ip).To4()
+		Expect(parsedIP).NotTo(BeNil(), "%q is not an IPv4 address", ip)
+		parsed = append(parsed, parsedIP)
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.records[canonicalName(name)] = parsed
+}
+
+// RemoveRecord makes name unknown.
+func (d *DNSServer) RemoveRecord(name string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	delete(d.records, canonicalName(name))
+}
+
+// Resolver resolves through the server, for the test process.
+func (d *DNSServer) Resolver() *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, d.address)
+		},
+	}
+}
+
+// Resolving makes runner's process resolve names through the server, by
+// running it in a mount namespace of its own with the server as its
+// /etc/resolv.conf. runner must be a ginkgomon runner, and is changed in
+// place.
+func (d *DNSServer) Resolving(runner ifrit.Runner) ifrit.Runner {
+	r, ok := runner.(*ginkgomon.Runner)
+	Expect(ok).To(BeTrue(), "%T is not a ginkgomon runner", runner)
+
+	cmd := r.Command
+	args := append([]string{
+		"--mount", "--",
+		"sh", "-c", `mount --bind "$0" /etc/resolv.conf && exec "$@"`,
+		d.resolvConf, cmd.Path,
+	}, cmd.Args[1:]...)
+
+	wrapped := exec.Command("unshare", args...)
+	wrapped.Env = cmd.Env
+	wrapped.Dir = cmd.Dir
+	r.Command = wrapped
+
+	return r
+}
+
+// ServiceAddress is address with its host replaced by the service name.
+func ServiceAddress(name, address string) string {
+	_, port, err := net.SplitHostPort(address)
+	Expect(err).NotTo(HaveOccurred())
+	return net.JoinHostPort(name, port)
+}
+
+// BBSUsingServiceNames has the BBS find the auctioneer and locket by their
+// service names.
+func BBSUsingServiceNames(maker ComponentMaker) func(*bbsconfig.BBSConfig) {
+	return func(cfg *bbsconfig.BBSConfig) {
+		cfg.AuctioneerAddress = "https://" + ServiceAddress(AuctioneerServiceName, maker.Addresses().Auctioneer)
+		cfg.ClientLocketConfig.LocketAddress = ServiceAddress(LocketServiceName, maker.Addresses().Locket)
+	}
+}
+
+// RepUsingServiceNames has the rep find the BBS and locket by their
+// service names.
+func RepUsingServiceNames(maker ComponentMaker) func(*repconfig.RepConfig) {
+	return func(cfg *repconfig.RepConfig) {
+		cfg.BBSAddress = "https://" + ServiceAddress(BBSServiceName, maker.Addresses().BBS)
+		cfg.ClientLocketConfig.LocketAddress = ServiceAddress(LocketServiceName, maker.Addresses().Locket)
+	}
+}
+
+// AuctioneerUsingServiceNames has the auctioneer find the BBS and locket by
+// their service names.
+func AuctioneerUsingServiceNames(maker ComponentMaker) func(*auctioneerconfig.AuctioneerConfig) {
+	return func(cfg *auctioneerconfig.AuctioneerConfig) {
+		cfg.BBSAddress = "https://" + ServiceAddress(BBSServiceName, maker.Addresses().BBS)
+		cfg.ClientLocketConfig.LocketAddress = ServiceAddress(LocketServiceName, maker.Addresses().Locket)
+	}
+}
+
+func (d *DNSServer) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	defer os.RemoveAll(d.dir)
+
+	conn, err := net.ListenPacket("udp", d.address)
+	if err != nil {
+		return err
+	}
+
+	go d.serve(conn)
+
+	close(ready)
+	<-signals
+
+	conn.Close()
+	return nil
+}
+
+func (d *DNSServer) serve(conn net.PacketConn) {
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		query := append([]byte(nil), buf[:n]...)
+		go func() {
+			response, err := d.answer(query)
+			if err != nil {
+				return
+			}
+			conn.WriteTo(response, addr)
+		}()
+	}
+}
+
+// answer answers query from the records, or passes it on to the upstream
+// nameserver if it isn't for a service name.
+func (d *DNSServer) answer(query []byte) ([]byte, error) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(query)
+	if err != nil {
+		return nil, err
+	}
+	question, err := parser.Question()
+	if err != nil {
+		return nil, err
+	}
+
+	response := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:                 header.ID,
+			Response:           true,
+			RecursionDesired:   header.RecursionDesired,
+			RecursionAvailable: true,
+		},
+		Questions: []dnsmessage.Question{question},
+	}
+
+	name := canonicalName(question.Name.String())
+	ips, found := d.lookup(name)
+	if !found && !strings.HasSuffix(name, "."+serviceDomain) {
+		forwarded, err := d.forward(query)
+		if err == nil {
+			return forwarded, nil
+		}
+		response.RCode = dnsmessage.RCodeServerFailure
+		return response.Pack()
+	}
+
+	response.Authoritative = true
+	if !found {
+		response.RCode = dnsmessage.RCodeNameError
+		return response.Pack()
+	}
+
+	// the names only have IPv4 addresses; other types get no answers
+	if question.Type == dnsmessage.TypeA {
+		for _, ip := range ips {
+			a := &dnsmessage.AResource{}
+			copy(a.A[:], ip)
+			response.Answers = append(response.Answers, dnsmessage.Resource{
+				Header: dnsmessage.ResourceHeader{
+					Name:  question.Name,
+					Type:  dnsmessage.TypeA,
+					Class: dnsmessage.ClassINET,
+				},
+				Body: a,
+			})
+		}
+	}
+
+	return response.Pack()
+}
+
+func (d *DNSServer) lookup(name string) ([]net.IP, bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if ips, found := d.records[name]; found {
+		return ips, true
+	}
+	if i := strings.Index(name, "."); i >= 0 {
+		ips, found := d.records["*"+name[i:]]
+		return ips, found
+	}
+	return nil, false
+}
+
+func (d *DNSServer) forward(query []byte) ([]byte, error) {
+	if d.upstream == "" {
+		return nil, errors.New("no upstream nameserver")
+	}
+
+	conn, err := net.DialTimeout("udp", d.upstream, dnsForwardTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(dnsForwardTimeout))
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// systemNameserver returns the address of the first nameserver in the
+// machine's resolv.conf, or "" if there is none.
+func systemNameserver() string {
+	file, err := os.Open("/etc/resolv.conf")
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			return net.JoinHostPort(fields[1], "53")
+		}
+	}
+	return ""
+}
+
+func canonicalName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, ".")) + "."
+}
+
+func addressHost(address string) string {
+	host, _, err := net.SplitHostPort(address)
+	Expect(err).NotTo(HaveOccurred())
+	return host
+}
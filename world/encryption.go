@@ -0,0 +1,19 @@
+package world
+
+import (
+	bbsconfig "code.cloudfoundry.org/bbs/cmd/bbs/config"
+)
+
+// DefaultEncryptionKeyLabel is the label of the only key BBS() configures.
+const DefaultEncryptionKeyLabel = "secure-key-1"
+
+// BBSEncryptionKeys replaces the BBS's encryption keys, by label, and makes
+// active the one new records are encrypted with. On start the BBS
+// re-encrypts every existing record with the active key, and it needs the
+// keys of all records not yet re-encrypted to read them.
+func BBSEncryptionKeys(active string, keys map[string]string) func(*bbsconfig.BBSConfig) {
+	return func(cfg *bbsconfig.BBSConfig) {
+		cfg.ActiveKeyLabel = active
+		cfg.EncryptionKeys = keys
+	}
+}
@@ -0,0 +1,82 @@
+package world
+
+import (
+	"fmt"
+	"os"
+
+	"code.cloudfoundry.org/consuladapter/consulrunner"
+	"code.cloudfoundry.org/inigo/helpers/certauthority"
+	"code.cloudfoundry.org/inigo/helpers/portauthority"
+	"code.cloudfoundry.org/localip"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/grouper"
+)
+
+// NodeAddresses returns the addresses the components of the running
+// parallel node listen on. Each node gets its own, so that suites can run
+// in parallel.
+func NodeAddresses() ComponentAddresses {
+	node := GinkgoParallelNode()
+
+	_, dbBaseConnectionString := DBInfo()
+
+	localIP, err := localip.LocalIP()
+	Expect(err).NotTo(HaveOccurred())
+
+	return ComponentAddresses{
+		Garden:              fmt.Sprintf("127.0.0.1:%d", 10000+node),
+		NATS:                fmt.Sprintf("127.0.0.1:%d", 11000+node),
+		Consul:              fmt.Sprintf("127.0.0.1:%d", 12750+node*consulrunner.PortOffsetLength),
+		Rep:                 fmt.Sprintf("127.0.0.1:%d", 14000+node),
+		FileServer:          fmt.Sprintf("%s:%d", localIP, 17000+node),
+		Router:              fmt.Sprintf("127.0.0.1:%d", 18000+node),
+		BBS:                 fmt.Sprintf("127.0.0.1:%d", 20500+node*2),
+		Health:              fmt.Sprintf("127.0.0.1:%d", 20500+node*2+1),
+		Auctioneer:          fmt.Sprintf("127.0.0.1:%d", 23000+node),
+		SSHProxy:            fmt.Sprintf("127.0.0.1:%d", 23500+node),
+		SSHProxyHealthCheck: fmt.Sprintf("127.0.0.1:%d", 24500+node),
+		FakeVolmanDriver:    fmt.Sprintf("127.0.0.1:%d", 25500+node),
+		Locket:              fmt.Sprintf("127.0.0.1:%d", 26500+node),
+		SQL:                 fmt.Sprintf("%sdiego_%d", dbBaseConnectionString, node),
+	}
+}
+
+// MakeNodeComponentMaker makes a component maker for the running parallel
+// node, listening on NodeAddresses, allocating further ports from a range
+// of the node's own, and signing certificates with a CA kept under
+// tempDir. Callers still call Setup and Teardown on it.
+func MakeNodeComponentMaker(builtArtifacts BuiltArtifacts, tempDir string) ComponentMaker {
+	startPort := 1000 * GinkgoParallelNode()
+	portRange := 950
+	endPort := startPort + portRange
+
+	allocator, err := portauthority.New(startPort, endPort)
+	Expect(err).NotTo(HaveOccurred())
+
+	certDepot := TempDirWithParent(tempDir, "cert-depot")
+
+	certAuthority, err := certauthority.NewCertAuthority(certDepot, "ca")
+	Expect(err).NotTo(HaveOccurred())
+
+	return MakeComponentMaker(builtArtifacts, NodeAddresses(), allocator, certAuthority)
+}
+
+// Plumbing runs the services a spec needs before it can start the BBS: the
+// database, Consul if the world has it and any extraServices in parallel,
+// then locket.
+func Plumbing(maker ComponentMaker, extraServices ...grouper.Member) ifrit.Runner {
+	services := grouper.Members{
+		{"sql", maker.SQL()},
+	}
+	if maker.ConsulEnabled() {
+		services = append(services, grouper.Member{"consul", maker.Consul()})
+	}
+	services = append(services, extraServices...)
+
+	return grouper.NewOrdered(os.Kill, grouper.Members{
+		{"initial-services", grouper.NewParallel(os.Kill, services)},
+		{"locket", maker.Locket()},
+	})
+}
@@ -0,0 +1,317 @@
+package world
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	bbsconfig "code.cloudfoundry.org/bbs/cmd/bbs/config"
+	locketconfig "code.cloudfoundry.org/locket/cmd/locket/config"
+	"github.com/go-sql-driver/mysql"
+	. "github.com/onsi/gomega"
+)
+
+// SQLError is the error an SQLFault answers a matching statement with.
+type SQLError int
+
+const (
+	NoSQLError SQLError = iota
+	// SQLDeadlock is a deadlock the server detected and broke by failing
+	// the statement.
+	SQLDeadlock
+	// SQLSerializationFailure fails the statement as if a concurrent
+	// transaction got in the way. MySQL reports these as deadlocks.
+	SQLSerializationFailure
+	// SQLLostConnection drops the connection instead of answering.
+	SQLLostConnection
+)
+
+// SQLFault changes how the SQLProxy handles the statements Statement
+// matches: it holds them for Latency, or until the fault is removed if Hang
+// is set, and then fails them with Error or, if there is none, passes them
+// on. A fault applies to the first Times statements it matches, or to
+// every one if Times is 0.
+type SQLFault struct {
+	Statement *regexp.Regexp
+	Error     SQLError
+	Latency   time.Duration
+	Hang      bool
+	Times     int
+}
+
+// MatchStatement matches statements against pattern, ignoring case.
+func MatchStatement(pattern string) *regexp.Regexp {
+	return regexp.MustCompile("(?i)" + pattern)
+}
+
+// MatchTable matches statements that read or write table.
+func MatchTable(table string) *regexp.Regexp {
+	return MatchStatement("\\b(FROM|INTO|UPDATE|JOIN|TABLE)\\s+[`\"]?" + regexp.QuoteMeta(table) + "\\b")
+}
+
+// InjectedSQLFault is an SQLFault in effect on an SQLProxy.
+type InjectedSQLFault struct {
+	fault SQLFault
+
+	lock    sync.Mutex
+	hits    int
+	removed chan struct{}
+}
+
+// Hits returns how many statements the fault has applied to.
+func (f *InjectedSQLFault) Hits() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.hits
+}
+
+// Remove stops the fault applying to further statements and lets the
+// statements it hangs carry on.
+func (f *InjectedSQLFault) Remove() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	select {
+	case <-f.removed:
+	default:
+		close(f.removed)
+	}
+}
+
+func (f *InjectedSQLFault) hit(statement string) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	select {
+	case <-f.removed:
+		return false
+	default:
+	}
+
+	if f.fault.Times > 0 && f.hits >= f.fault.Times {
+		return false
+	}
+	if !f.fault.Statement.MatchString(statement) {
+		return false
+	}
+
+	f.hits++
+	return true
+}
+
+func (f *InjectedSQLFault) delay() {
+	time.Sleep(f.fault.Latency)
+	if f.fault.Hang {
+		<-f.removed
+	}
+}
+
+// SQLProxy sits between a component and the world's database, speaking the
+// wire protocol of the world's driver so that it can fail, slow down or
+// hang individual statements. Point a BBS or locket at it with
+// BBSThroughSQLProxy or LocketThroughSQLProxy.
+//
+// Connections using TLS are passed through untouched, since their
+// statements can't be seen.
+type SQLProxy struct {
+	driver           string
+	address          string
+	backend          string
+	connectionString string
+
+	lock   sync.Mutex
+	faults []*InjectedSQLFault
+	conns  map[net.Conn]struct{}
+}
+
+// NewSQLProxy returns a proxy, on a port claimed from maker, for the
+// database maker's components use.
+func NewSQLProxy(maker ComponentMaker) *SQLProxy {
+	port, err := maker.PortAllocator().ClaimPorts(1)
+	Expect(err).NotTo(HaveOccurred())
+
+	p := &SQLProxy{
+		driver:  maker.DBDriverName(),
+		address: fmt.Sprintf("127.0.0.1:%d", port),
+		conns:   map[net.Conn]struct{}{},
+	}
+
+	switch p.driver {
+	case MySQLDriver:
+		cfg, err := mysql.ParseDSN(maker.Addresses().SQL)
+		Expect(err).NotTo(HaveOccurred())
+		p.backend = cfg.Addr
+		cfg.Addr = p.address
+		p.connectionString = cfg.FormatDSN()
+	case PostgresDriver:
+		u, err := url.Parse(maker.Addresses().SQL)
+		Expect(err).NotTo(HaveOccurred())
+		p.backend = u.Host
+		if u.Port() == "" {
+			p.backend = net.JoinHostPort(u.Hostname(), "5432")
+		}
+		u.Host = p.address
+		p.connectionString = u.String()
+	default:
+		Fail(fmt.Sprintf("no SQL proxy for database driver %q", p.driver))
+	}
+
+	return p
+}
+
+func (p *SQLProxy) Address() string {
+	return p.address
+}
+
+// ConnectionString is the world's SQL connection string, pointed at the
+// proxy.
+func (p *SQLProxy) ConnectionString() string {
+	return p.connectionString
+}
+
+// BBSThroughSQLProxy points the BBS at proxy instead of the database. The
+// proxy can't see into TLS connections, so TLS to the database is turned
+// off.
+func BBSThroughSQLProxy(proxy *SQLProxy) func(*bbsconfig.BBSConfig) {
+	return func(cfg *bbsconfig.BBSConfig) {
+		cfg.DatabaseConnectionString = proxy.ConnectionString()
+		cfg.SQLCACertFile = ""
+	}
+}
+
+// LocketThroughSQLProxy points locket at proxy, as BBSThroughSQLProxy does
+// the BBS.
+func LocketThroughSQLProxy(proxy *SQLProxy) func(*locketconfig.LocketConfig) {
+	return func(cfg *locketconfig.LocketConfig) {
+		cfg.DatabaseConnectionString = proxy.ConnectionString()
+		cfg.SQLCACertFile = ""
+	}
+}
+
+func (p *SQLProxy) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	listener, err := net.Listen("tcp", p.address)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go p.proxy(conn)
+		}
+	}()
+
+	close(ready)
+	<-signals
+
+	listener.Close()
+	p.Clear()
+	p.closeAll()
+	return nil
+}
+
+// Inject puts fault in effect for every connection, after the faults
+// already injected; a statement gets the first fault that applies to it.
+func (p *SQLProxy) Inject(fault SQLFault) *InjectedSQLFault {
+	Expect(fault.Statement).NotTo(BeNil(), "an SQL fault needs a statement to match")
+
+	injected := &InjectedSQLFault{
+		fault:   fault,
+		removed: make(chan struct{}),
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.faults = append(p.faults, injected)
+
+	return injected
+}
+
+// Clear removes every fault.
+func (p *SQLProxy) Clear() {
+	p.lock.Lock()
+	faults := p.faults
+	p.faults = nil
+	p.lock.Unlock()
+
+	for _, fault := range faults {
+		fault.Remove()
+	}
+}
+
+func (p *SQLProxy) match(statement string) *InjectedSQLFault {
+	p.lock.Lock()
+	faults := p.faults
+	p.lock.Unlock()
+
+	for _, fault := range faults {
+		if fault.hit(statement) {
+			return fault
+		}
+	}
+	return nil
+}
+
+func (p *SQLProxy) proxy(conn net.Conn) {
+	backendConn, err := net.DialTimeout("tcp", p.backend, tcpProxyDialTimeout)
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	p.track(conn, backendConn)
+	defer p.untrack(conn, backendConn)
+	defer conn.Close()
+	defer backendConn.Close()
+
+	switch p.driver {
+	case MySQLDriver:
+		p.proxyMySQL(conn, backendConn)
+	case PostgresDriver:
+		p.proxyPostgres(conn, backendConn)
+	}
+}
+
+func (p *SQLProxy) track(conns ...net.Conn) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for _, conn := range conns {
+		p.conns[conn] = struct{}{}
+	}
+}
+
+func (p *SQLProxy) untrack(conns ...net.Conn) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for _, conn := range conns {
+		delete(p.conns, conn)
+	}
+}
+
+func (p *SQLProxy) closeAll() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for conn := range p.conns {
+		conn.Close()
+	}
+}
+
+// pipeSQL copies between client and server, without looking, until either
+// side closes.
+func pipeSQL(client, server net.Conn) {
+	done := make(chan struct{}, 2)
+	pipe := func(dst, src net.Conn) {
+		io.Copy(dst, src)
+		done <- struct{}{}
+	}
+	go pipe(server, client)
+	go pipe(client, server)
+	<-done
+}
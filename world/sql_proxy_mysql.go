@@ -0,0 +1,182 @@
+package world
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+)
+
+const (
+	mysqlComQuery       = 0x03
+	mysqlComStmtPrepare = 0x16
+	mysqlComStmtExecute = 0x17
+	mysqlComStmtClose   = 0x19
+
+	mysqlClientSSL = 0x00000800
+)
+
+// mysqlConn is one client connection through the SQLProxy. Statements
+// prepared on it are remembered by ID so that executing them can be
+// matched against faults.
+type mysqlConn struct {
+	client, server net.Conn
+
+	writeLock sync.Mutex
+
+	lock       sync.Mutex
+	preparing  string
+	statements map[uint32]string
+}
+
+func (p *SQLProxy) proxyMySQL(client, server net.Conn) {
+	// the server greets, then the client answers, possibly asking for TLS
+	greeting, err := readMySQLPacket(server)
+	if err != nil {
+		return
+	}
+	if _, err := client.Write(greeting); err != nil {
+		return
+	}
+
+	handshake, err := readMySQLPacket(client)
+	if err != nil {
+		return
+	}
+	if _, err := server.Write(handshake); err != nil {
+		return
+	}
+
+	if len(handshake) >= 8 && binary.LittleEndian.Uint32(handshake[4:8])&mysqlClientSSL != 0 {
+		pipeSQL(client, server)
+		return
+	}
+
+	c := &mysqlConn{
+		client:     client,
+		server:     server,
+		statements: map[uint32]string{},
+	}
+	go c.forwardServer()
+	c.forwardClient(p)
+}
+
+func (c *mysqlConn) forwardServer() {
+	defer c.client.Close()
+
+	for {
+		packet, err := readMySQLPacket(c.server)
+		if err != nil {
+			return
+		}
+
+		// the first packet of the reply to a prepare carries the statement ID
+		c.lock.Lock()
+		if c.preparing != "" && packet[3] == 1 {
+			if len(packet) >= 9 && packet[4] == 0x00 {
+				c.statements[binary.LittleEndian.Uint32(packet[5:9])] = c.preparing
+			}
+			c.preparing = ""
+		}
+		c.lock.Unlock()
+
+		if err := c.writeClient(packet); err != nil {
+			return
+		}
+	}
+}
+
+func (c *mysqlConn) forwardClient(p *SQLProxy) {
+	defer c.server.Close()
+
+	for {
+		packet, err := readMySQLPacket(c.client)
+		if err != nil {
+			return
+		}
+
+		// commands start a new sequence; anything else is part of the
+		// handshake
+		if packet[3] == 0 && len(packet) > 4 {
+			if statement := c.statement(packet[4:]); statement != "" {
+				if fault := p.match(statement); fault != nil {
+					fault.delay()
+
+					switch fault.fault.Error {
+					case SQLLostConnection:
+						return
+					case SQLDeadlock, SQLSerializationFailure:
+						if err := c.writeClient(mysqlDeadlock(packet[3] + 1)); err != nil {
+							return
+						}
+						continue
+					}
+				}
+			}
+		}
+
+		if _, err := c.server.Write(packet); err != nil {
+			return
+		}
+	}
+}
+
+// statement returns the SQL the command runs, if any.
+func (c *mysqlConn) statement(command []byte) string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	switch command[0] {
+	case mysqlComQuery:
+		return string(command[1:])
+	case mysqlComStmtPrepare:
+		c.preparing = string(command[1:])
+	case mysqlComStmtExecute:
+		if len(command) >= 5 {
+			return c.statements[binary.LittleEndian.Uint32(command[1:5])]
+		}
+	case mysqlComStmtClose:
+		if len(command) >= 5 {
+			delete(c.statements, binary.LittleEndian.Uint32(command[1:5]))
+		}
+	}
+	return ""
+}
+
+func (c *mysqlConn) writeClient(packet []byte) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	_, err := c.client.Write(packet)
+	return err
+}
+
+// readMySQLPacket reads one packet, header included.
+func readMySQLPacket(r io.Reader) ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	length := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	packet := make([]byte, 4+length)
+	copy(packet, header)
+	if _, err := io.ReadFull(r, packet[4:]); err != nil {
+		return nil, err
+	}
+	return packet, nil
+}
+
+// mysqlDeadlock is the ERR packet MySQL sends when it fails a statement to
+// break a deadlock.
+func mysqlDeadlock(seq byte) []byte {
+	message := "Deadlock found when trying to get lock; try restarting transaction"
+
+	payload := []byte{0xff, 0, 0}
+	binary.LittleEndian.PutUint16(payload[1:], 1213)
+	payload = append(payload, '#')
+	payload = append(payload, "40001"...)
+	payload = append(payload, message...)
+
+	length := len(payload)
+	return append([]byte{byte(length), byte(length >> 8), byte(length >> 16), seq}, payload...)
+}
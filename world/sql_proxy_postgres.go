@@ -0,0 +1,288 @@
+package world
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+)
+
+const (
+	postgresCancelRequestCode = 80877102
+	postgresSSLRequestCode    = 80877103
+	postgresGSSENCRequestCode = 80877104
+)
+
+// postgresConn is one client connection through the SQLProxy. Named
+// statements are remembered so that binding them can be matched against
+// faults, and the server's transaction status so that injected errors
+// leave the client where a real one would.
+type postgresConn struct {
+	client, server net.Conn
+
+	writeLock sync.Mutex
+
+	lock       sync.Mutex
+	status     byte
+	statements map[string]string
+
+	// after failing an extended-protocol message, the server skips the
+	// client's messages up to the next Sync
+	skipping bool
+}
+
+func (p *SQLProxy) proxyPostgres(client, server net.Conn) {
+	// startup messages have no type; the client may ask for TLS or GSS
+	// encryption first, which the server answers with a single byte
+	for {
+		message, err := readPostgresStartupMessage(client)
+		if err != nil {
+			return
+		}
+		if _, err := server.Write(message); err != nil {
+			return
+		}
+
+		code := binary.BigEndian.Uint32(message[4:8])
+		if code == postgresCancelRequestCode {
+			pipeSQL(client, server)
+			return
+		}
+		if code != postgresSSLRequestCode && code != postgresGSSENCRequestCode {
+			break
+		}
+
+		answer := make([]byte, 1)
+		if _, err := io.ReadFull(server, answer); err != nil {
+			return
+		}
+		if _, err := client.Write(answer); err != nil {
+			return
+		}
+		if answer[0] != 'N' {
+			pipeSQL(client, server)
+			return
+		}
+	}
+
+	c := &postgresConn{
+		client:     client,
+		server:     server,
+		status:     'I',
+		statements: map[string]string{},
+	}
+	go c.forwardServer()
+	c.forwardClient(p)
+}
+
+func (c *postgresConn) forwardServer() {
+	defer c.client.Close()
+
+	for {
+		message, err := readPostgresMessage(c.server)
+		if err != nil {
+			return
+		}
+
+		if message[0] == 'Z' && len(message) >= 6 {
+			c.lock.Lock()
+			c.status = message[5]
+			c.lock.Unlock()
+		}
+
+		if err := c.writeClient(message); err != nil {
+			return
+		}
+	}
+}
+
+func (c *postgresConn) forwardClient(p *SQLProxy) {
+	defer c.server.Close()
+
+	for {
+		message, err := readPostgresMessage(c.client)
+		if err != nil {
+			return
+		}
+
+		if c.skipping {
+			if message[0] == 'S' {
+				c.skipping = false
+				if err := c.writeClient(postgresReadyForQuery(c.failedStatus())); err != nil {
+					return
+				}
+			}
+			continue
+		}
+
+		if statement := c.statement(message); statement != "" {
+			if fault := p.match(statement); fault != nil {
+				fault.delay()
+
+				switch fault.fault.Error {
+				case SQLLostConnection:
+					return
+				case SQLDeadlock, SQLSerializationFailure:
+					if err := c.fail(message[0], fault.fault.Error); err != nil {
+						return
+					}
+					continue
+				}
+			}
+		}
+
+		if _, err := c.server.Write(message); err != nil {
+			return
+		}
+		if message[0] == 'X' {
+			return
+		}
+	}
+}
+
+// statement returns the SQL the message runs, if any. Unnamed statements
+// are matched when parsed, named ones each time they are bound.
+func (c *postgresConn) statement(message []byte) string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	body := message[5:]
+	switch message[0] {
+	case 'Q':
+		query, _ := postgresString(body)
+		return query
+	case 'P':
+		name, rest := postgresString(body)
+		query, _ := postgresString(rest)
+		if name == "" {
+			return query
+		}
+		c.statements[name] = query
+	case 'B':
+		_, rest := postgresString(body)
+		name, _ := postgresString(rest)
+		if name != "" {
+			return c.statements[name]
+		}
+	case 'C':
+		if len(body) > 0 && body[0] == 'S' {
+			name, _ := postgresString(body[1:])
+			delete(c.statements, name)
+		}
+	}
+	return ""
+}
+
+// fail answers the message with err in place of the server.
+func (c *postgresConn) fail(messageType byte, err SQLError) error {
+	code, text := "40001", "could not serialize access due to concurrent update"
+	if err == SQLDeadlock {
+		code, text = "40P01", "deadlock detected"
+	}
+
+	if writeErr := c.writeClient(postgresErrorResponse(code, text)); writeErr != nil {
+		return writeErr
+	}
+
+	if messageType == 'Q' {
+		return c.writeClient(postgresReadyForQuery(c.failedStatus()))
+	}
+	c.skipping = true
+	return nil
+}
+
+// failedStatus is the transaction status after a statement fails: a
+// transaction in progress can only be rolled back.
+func (c *postgresConn) failedStatus() byte {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.status == 'I' {
+		return 'I'
+	}
+	return 'E'
+}
+
+func (c *postgresConn) writeClient(message []byte) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	_, err := c.client.Write(message)
+	return err
+}
+
+func readPostgresStartupMessage(r io.Reader) ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	length := int(binary.BigEndian.Uint32(header))
+	if length < 8 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	message := make([]byte, length)
+	copy(message, header)
+	if _, err := io.ReadFull(r, message[4:]); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// readPostgresMessage reads one typed message, type and length included.
+func readPostgresMessage(r io.Reader) ([]byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	length := int(binary.BigEndian.Uint32(header[1:]))
+	if length < 4 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	message := make([]byte, 1+length)
+	copy(message, header)
+	if _, err := io.ReadFull(r, message[5:]); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// postgresString splits a null-terminated string off the front of b.
+func postgresString(b []byte) (string, []byte) {
+	end := bytes.IndexByte(b, 0)
+	if end < 0 {
+		return string(b), nil
+	}
+	return string(b[:end]), b[end+1:]
+}
+
+func postgresErrorResponse(code, text string) []byte {
+	fields := []byte{}
+	for _, field := range []struct {
+		kind  byte
+		value string
+	}{
+		{'S', "ERROR"},
+		{'V', "ERROR"},
+		{'C', code},
+		{'M', text},
+	} {
+		fields = append(fields, field.kind)
+		fields = append(fields, field.value...)
+		fields = append(fields, 0)
+	}
+	fields = append(fields, 0)
+
+	return postgresMessage('E', fields)
+}
+
+func postgresReadyForQuery(status byte) []byte {
+	return postgresMessage('Z', []byte{status})
+}
+
+func postgresMessage(messageType byte, body []byte) []byte {
+	message := make([]byte, 5, 5+len(body))
+	message[0] = messageType
+	binary.BigEndian.PutUint32(message[1:], uint32(4+len(body)))
+	return append(message, body...)
+}
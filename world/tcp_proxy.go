@@ -0,0 +1,139 @@
+package world
+
+import (
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+const tcpProxyDialTimeout = time.Second
+
+// TCPProxy forwards every connection it accepts to the first of its
+// backends that takes it. It can be partitioned to cut a component off
+// from whatever it reaches through the proxy.
+//
+// Connections are not moved when a backend dies; clients reconnect through
+// the proxy and reach the next backend that is up.
+type TCPProxy struct {
+	address  string
+	backends []string
+
+	lock        sync.Mutex
+	partitioned bool
+	conns       map[net.Conn]struct{}
+}
+
+// NewTCPProxy returns a proxy listening on address once it is run.
+func NewTCPProxy(address string, backends ...string) *TCPProxy {
+	return &TCPProxy{
+		address:  address,
+		backends: backends,
+		conns:    map[net.Conn]struct{}{},
+	}
+}
+
+func (p *TCPProxy) Address() string {
+	return p.address
+}
+
+func (p *TCPProxy) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	listener, err := net.Listen("tcp", p.address)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go p.proxy(conn)
+		}
+	}()
+
+	close(ready)
+	<-signals
+
+	listener.Close()
+	p.closeAll()
+	return nil
+}
+
+// Partition drops every proxied connection and refuses new ones until Heal
+// is called.
+func (p *TCPProxy) Partition() {
+	p.lock.Lock()
+	p.partitioned = true
+	p.lock.Unlock()
+
+	p.closeAll()
+}
+
+func (p *TCPProxy) Heal() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.partitioned = false
+}
+
+func (p *TCPProxy) proxy(conn net.Conn) {
+	for _, backend := range p.backends {
+		backendConn, err := net.DialTimeout("tcp", backend, tcpProxyDialTimeout)
+		if err != nil {
+			continue
+		}
+
+		if !p.track(conn, backendConn) {
+			break
+		}
+		defer p.untrack(conn, backendConn)
+
+		done := make(chan struct{}, 2)
+		pipe := func(dst, src net.Conn) {
+			io.Copy(dst, src)
+			done <- struct{}{}
+		}
+		go pipe(backendConn, conn)
+		go pipe(conn, backendConn)
+		<-done
+
+		conn.Close()
+		backendConn.Close()
+		return
+	}
+
+	conn.Close()
+}
+
+// track records conns so that they can be dropped, unless the proxy is
+// partitioned, in which case it closes them.
+func (p *TCPProxy) track(conns ...net.Conn) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for _, conn := range conns {
+		if p.partitioned {
+			conn.Close()
+			continue
+		}
+		p.conns[conn] = struct{}{}
+	}
+	return !p.partitioned
+}
+
+func (p *TCPProxy) untrack(conns ...net.Conn) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for _, conn := range conns {
+		delete(p.conns, conn)
+	}
+}
+
+func (p *TCPProxy) closeAll() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for conn := range p.conns {
+		conn.Close()
+	}
+}
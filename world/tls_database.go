@@ -0,0 +1,250 @@
+package world
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	_ "github.com/lib/pq"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+)
+
+// TLSDatabase is a database server of its own, of the world's driver, that
+// only takes TLS connections and presents a certificate from the world's
+// CertAuthority. Run it, then point components at it with
+// WithTLSDatabase.
+//
+// It runs mysqld, or initdb and postgres, from the PATH. Postgres won't run
+// as root, so as root it is run as the postgres user.
+type TLSDatabase struct {
+	Address          string
+	ConnectionString string
+	CACertFile       string
+	CertFile         string
+	KeyFile          string
+
+	driver string
+	dbName string
+	dir    string
+}
+
+// a fresh server takes a while to come up, more so on a busy machine
+const tlsDatabaseStartTimeout = time.Minute
+
+// MakeTLSDatabase claims a port for a TLS database and generates its
+// certificate, valid for 127.0.0.1.
+func MakeTLSDatabase(maker ComponentMaker) TLSDatabase {
+	port, err := maker.PortAllocator().ClaimPorts(1)
+	Expect(err).NotTo(HaveOccurred())
+
+	db := TLSDatabase{
+		Address: fmt.Sprintf("127.0.0.1:%d", port),
+		driver:  maker.DBDriverName(),
+		dbName:  fmt.Sprintf("diego_%d", GinkgoParallelNode()),
+		dir:     TempDir("tls-database"),
+	}
+
+	_, db.CACertFile = maker.CertAuthority().CAAndKey()
+	keyFile, certFile, err := maker.CertAuthority().GenerateSelfSignedCertAndKey("sql_server", []string{"localhost"}, false)
+	Expect(err).NotTo(HaveOccurred())
+
+	// the server reads these as itself, and refuses a key others can read
+	db.CertFile = filepath.Join(db.dir, "server.crt")
+	db.KeyFile = filepath.Join(db.dir, "server.key")
+	copyFile(certFile, db.CertFile, 0644)
+	copyFile(keyFile, db.KeyFile, 0600)
+
+	switch db.driver {
+	case MySQLDriver:
+		db.ConnectionString = fmt.Sprintf("diego:diego_password@tcp(%s)/%s", db.Address, db.dbName)
+	case PostgresDriver:
+		db.ConnectionString = fmt.Sprintf("postgres://diego:diego_pw@%s/%s", db.Address, db.dbName)
+	default:
+		Fail(fmt.Sprintf("no TLS database for database driver %q", db.driver))
+	}
+
+	return db
+}
+
+// WithTLSDatabase returns a copy of maker whose BBS and locket use db,
+// verifying its certificate.
+func WithTLSDatabase(maker ComponentMaker, db TLSDatabase) ComponentMaker {
+	switch m := maker.(type) {
+	case v0ComponentMaker:
+		m.commonComponentMaker = m.withTLSDatabase(db)
+		return m
+	case v1ComponentMaker:
+		m.commonComponentMaker = m.withTLSDatabase(db)
+		return m
+	}
+
+	Fail(fmt.Sprintf("cannot change the database of %T", maker))
+	return nil
+}
+
+func (maker commonComponentMaker) withTLSDatabase(db TLSDatabase) commonComponentMaker {
+	maker.addresses.SQL = db.ConnectionString
+	maker.sqlCACertFile = db.CACertFile
+	return maker
+}
+
+// Run initializes a fresh data directory, starts the server, and creates
+// the world's database and user on it.
+func (db TLSDatabase) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	defer GinkgoRecover()
+	defer os.RemoveAll(db.dir)
+
+	var server ifrit.Runner
+	switch db.driver {
+	case MySQLDriver:
+		server = db.mysql()
+	case PostgresDriver:
+		server = db.postgres()
+	}
+
+	process := ifrit.Invoke(server)
+	db.createDatabase()
+
+	close(ready)
+
+	for {
+		select {
+		case signal := <-signals:
+			process.Signal(signal)
+		case err := <-process.Wait():
+			return err
+		}
+	}
+}
+
+func (db TLSDatabase) mysql() ifrit.Runner {
+	dataDir := filepath.Join(db.dir, "data")
+	initialize := exec.Command("mysqld", "--initialize-insecure", "--user=root", "--datadir="+dataDir)
+	output, err := initialize.CombinedOutput()
+	Expect(err).NotTo(HaveOccurred(), string(output))
+
+	_, port, err := net.SplitHostPort(db.Address)
+	Expect(err).NotTo(HaveOccurred())
+
+	return ginkgomon.New(ginkgomon.Config{
+		Name:              "tls-mysql",
+		AnsiColorCode:     "96m",
+		StartCheck:        "mysqld: ready for connections",
+		StartCheckTimeout: tlsDatabaseStartTimeout,
+		Command: exec.Command(
+			"mysqld",
+			"--user=root",
+			"--datadir="+dataDir,
+			"--bind-address=127.0.0.1",
+			"--port="+port,
+			"--socket="+filepath.Join(db.dir, "mysqld.sock"),
+			"--pid-file="+filepath.Join(db.dir, "mysqld.pid"),
+			"--ssl-ca="+db.CACertFile,
+			"--ssl-cert="+db.CertFile,
+			"--ssl-key="+db.KeyFile,
+			"--require-secure-transport=ON",
+		),
+	})
+}
+
+func (db TLSDatabase) postgres() ifrit.Runner {
+	dataDir := filepath.Join(db.dir, "data")
+	db.chownToPostgres(db.dir, db.CertFile, db.KeyFile)
+
+	initialize := db.postgresCommand("initdb", "--username=diego", "--auth=trust", "--pgdata="+dataDir)
+	output, err := initialize.CombinedOutput()
+	Expect(err).NotTo(HaveOccurred(), string(output))
+
+	// only TLS connections over TCP are let in
+	hba := "local all all trust\nhostssl all all 127.0.0.1/32 trust\n"
+	Expect(ioutil.WriteFile(filepath.Join(dataDir, "pg_hba.conf"), []byte(hba), 0600)).To(Succeed())
+	db.chownToPostgres(filepath.Join(dataDir, "pg_hba.conf"))
+
+	_, port, err := net.SplitHostPort(db.Address)
+	Expect(err).NotTo(HaveOccurred())
+
+	return ginkgomon.New(ginkgomon.Config{
+		Name:              "tls-postgres",
+		AnsiColorCode:     "96m",
+		StartCheck:        "database system is ready to accept connections",
+		StartCheckTimeout: tlsDatabaseStartTimeout,
+		Command: db.postgresCommand(
+			"postgres",
+			"-D", dataDir,
+			"-p", port,
+			"-k", db.dir,
+			"-c", "listen_addresses=127.0.0.1",
+			"-c", "ssl=on",
+			"-c", "ssl_ca_file="+db.CACertFile,
+			"-c", "ssl_cert_file="+db.CertFile,
+			"-c", "ssl_key_file="+db.KeyFile,
+		),
+	})
+}
+
+func (db TLSDatabase) createDatabase() {
+	var (
+		conn *sql.DB
+		err  error
+	)
+
+	switch db.driver {
+	case MySQLDriver:
+		conn, err = sql.Open("mysql", fmt.Sprintf("root@tcp(%s)/?tls=skip-verify", db.Address))
+	case PostgresDriver:
+		conn, err = sql.Open("postgres", fmt.Sprintf("postgres://diego@%s/postgres?sslmode=require", db.Address))
+	}
+	Expect(err).NotTo(HaveOccurred())
+	defer conn.Close()
+	Eventually(conn.Ping).Should(Succeed())
+
+	if db.driver == MySQLDriver {
+		_, err = conn.Exec("CREATE USER 'diego'@'%' IDENTIFIED BY 'diego_password'")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = conn.Exec("GRANT ALL PRIVILEGES ON *.* TO 'diego'@'%'")
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	_, err = conn.Exec(fmt.Sprintf("CREATE DATABASE %s", db.dbName))
+	Expect(err).NotTo(HaveOccurred())
+}
+
+func (db TLSDatabase) postgresCommand(name string, args ...string) *exec.Cmd {
+	if os.Geteuid() != 0 {
+		return exec.Command(name, args...)
+	}
+	return exec.Command("runuser", append([]string{"-u", "postgres", "--", name}, args...)...)
+}
+
+func (db TLSDatabase) chownToPostgres(paths ...string) {
+	if os.Geteuid() != 0 {
+		return
+	}
+
+	postgres, err := user.Lookup("postgres")
+	Expect(err).NotTo(HaveOccurred())
+	uid, err := strconv.Atoi(postgres.Uid)
+	Expect(err).NotTo(HaveOccurred())
+	gid, err := strconv.Atoi(postgres.Gid)
+	Expect(err).NotTo(HaveOccurred())
+
+	for _, path := range paths {
+		Expect(os.Chown(path, uid, gid)).To(Succeed())
+	}
+}
+
+func copyFile(src, dst string, mode os.FileMode) {
+	contents, err := ioutil.ReadFile(src)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(ioutil.WriteFile(dst, contents, mode)).To(Succeed())
+}
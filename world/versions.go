@@ -0,0 +1,32 @@
+package world
+
+import (
+	"code.cloudfoundry.org/inigo/helpers/certauthority"
+	"code.cloudfoundry.org/inigo/helpers/portauthority"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	// VersionV0 is the older Diego release under test, built from the
+	// GOPATH in GOPATH_V0.
+	VersionV0 = "v0"
+	// VersionCurrent is the Diego release in this tree.
+	VersionCurrent = "current"
+)
+
+// VersionedArtifacts holds the artifacts of each Diego release under test,
+// by version.
+type VersionedArtifacts map[string]BuiltArtifacts
+
+// ComponentMaker makes a ComponentMaker for the components of version. All
+// versions share the world's addresses and certificate authority, so that
+// one can take over from another on the same database.
+func (a VersionedArtifacts) ComponentMaker(version string, worldAddresses ComponentAddresses, allocator portauthority.PortAllocator, certAuthority certauthority.CertAuthority) ComponentMaker {
+	artifacts, ok := a[version]
+	Expect(ok).To(BeTrue(), "no artifacts for version %q", version)
+
+	if version == VersionV0 {
+		return MakeV0ComponentMaker(artifacts, worldAddresses, allocator, certAuthority)
+	}
+	return MakeComponentMaker(artifacts, worldAddresses, allocator, certAuthority)
+}
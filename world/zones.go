@@ -0,0 +1,63 @@
+package world
+
+import (
+	"fmt"
+
+	repconfig "code.cloudfoundry.org/rep/cmd/rep/config"
+	. "github.com/onsi/ginkgo"
+	"github.com/tedsuo/ifrit/ginkgomon"
+)
+
+// ZonedRep is one rep of a multi-zone world.
+type ZonedRep struct {
+	Zone   string
+	CellID string
+	Runner *ginkgomon.Runner
+}
+
+// RepZone places a rep in zone and gives it a cell ID naming the zone, so
+// that cells of different zones are told apart in logs. n must be the
+// index the rep is made with.
+func RepZone(zone string, n int) func(*repconfig.RepConfig) {
+	return func(cfg *repconfig.RepConfig) {
+		cfg.Zone = zone
+		cfg.CellID = ZonedCellID(zone, n)
+	}
+}
+
+func ZonedCellID(zone string, n int) string {
+	return fmt.Sprintf("cell_%s-%d-%d", zone, n, GinkgoParallelNode())
+}
+
+// ZonedReps makes cellsPerZone reps in each of zones, numbering them
+// consecutively so that each gets its own ports. Reps are ordered by zone,
+// then by cell within the zone.
+func ZonedReps(maker ComponentMaker, zones []string, cellsPerZone int, modifyConfigFuncs ...func(*repconfig.RepConfig)) []ZonedRep {
+	reps := []ZonedRep{}
+
+	n := 0
+	for _, zone := range zones {
+		for i := 0; i < cellsPerZone; i++ {
+			configs := append([]func(*repconfig.RepConfig){RepZone(zone, n)}, modifyConfigFuncs...)
+			reps = append(reps, ZonedRep{
+				Zone:   zone,
+				CellID: ZonedCellID(zone, n),
+				Runner: maker.RepN(n, configs...),
+			})
+			n++
+		}
+	}
+
+	return reps
+}
+
+// RepsInZone returns the reps of reps that are in zone.
+func RepsInZone(reps []ZonedRep, zone string) []ZonedRep {
+	inZone := []ZonedRep{}
+	for _, rep := range reps {
+		if rep.Zone == zone {
+			inZone = append(inZone, rep)
+		}
+	}
+	return inZone
+}